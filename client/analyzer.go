@@ -5,7 +5,6 @@ import (
 	"image"
 	"math"
 	"math/big"
-	"strconv"
 
 	"github.com/corona10/goimagehash"
 )
@@ -19,117 +18,292 @@ type ScreenType int
 const (
 	SelectScreen ScreenType = iota
 	ResultScreen
+	PauseScreen
+	SelectPopupScreen
+	UnknownScreen
 )
 
+func (s ScreenType) String() string {
+	switch s {
+	case SelectScreen:
+		return "select"
+	case ResultScreen:
+		return "result"
+	case PauseScreen:
+		return "pause"
+	case SelectPopupScreen:
+		return "select popup"
+	default:
+		return "unknown"
+	}
+}
+
+// ScreenError is returned when a screenshot is not a screen that can be analyzed.
+type ScreenError struct {
+	ScreenType ScreenType
+}
+
+// Error returns a message describing why the screen was rejected.
+func (e *ScreenError) Error() string {
+	switch e.ScreenType {
+	case PauseScreen:
+		return "pause screen cannot be analyzed, capture the select or result screen instead"
+	case SelectPopupScreen:
+		return "a popup is covering the select screen, close it and try again"
+	default:
+		return "screenshot is not a select or result screen"
+	}
+}
+
+// screenAnchor is a region whose pHash identifies a screen type.
+type screenAnchor struct {
+	screenType ScreenType
+	coords     []int
+	pHash      string
+}
+
 const pHashThreshold = 2
 
-func AnalyzeScreenshot(cache *Cache, config *Config) (AnalysisReport, error) {
+// AnalyzeOptions holds optional state shared between analyses.
+type AnalyzeOptions struct {
+	// Calibrations caches anchor calibrations per screen size. Calibration is skipped when nil.
+	Calibrations *CalibrationCache
+	// Debug records the ROIs, hashes and distances of the analysis when set.
+	Debug *DebugRecorder
+	// Digits reads the judge, score, patch and the other numbers. They are left out when nil.
+	Digits *DigitTemplates
+	// CacheError is called when a cache write fails. The analysis goes on regardless.
+	CacheError func(err error)
+}
+
+func AnalyzeScreenshot(cache *Cache, config *Config, opts AnalyzeOptions) (AnalysisReport, error) {
 	img, err := LoadImageFromClipboard()
 	if err != nil {
 		return AnalysisReport{}, fmt.Errorf("failed to load image from clipboard: %v", err)
 	}
+	return AnalyzeImage(img, cache, config, opts)
+}
 
-	// Determine which ROIConfig to use based on screen size
+// AnalyzeImage analyzes a screenshot that has already been loaded.
+func AnalyzeImage(img image.Image, cache *Cache, config *Config, opts AnalyzeOptions) (AnalysisReport, error) {
+	report, err := analyzeImage(img, cache, config, opts)
+	opts.Debug.finish(report, err)
+	return report, err
+}
 
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-	screenSize := ScreenSize{Width: width, Height: height}
+func analyzeImage(img image.Image, cache *Cache, config *Config, opts AnalyzeOptions) (AnalysisReport, error) {
+	opts.Debug.capture(img, config)
+	targetConfig, layout, screenType, screenDistance, err := locateScreen(img, config, opts)
+	opts.Debug.observe(targetConfig, layout)
+	if err != nil {
+		return AnalysisReport{}, err
+	}
+	if screenType != SelectScreen && screenType != ResultScreen {
+		return AnalysisReport{}, &ScreenError{ScreenType: screenType}
+	}
+	candidates := buildJacketCandidates(cache)
 
-	var targetConfig ROIConfig
-	doScale := false
-	found := false
-	for _, cfg := range config.Configs {
-		if cfg.ScreenSize == screenSize.String() {
-			targetConfig = cfg
-			found = true
-			break
+	// Extract jacket image
+	jacketImage, err := cropJacket(img, targetConfig, layout, screenType)
+	if err != nil {
+		return AnalysisReport{}, err
+	}
+	// Calculate hashes of jacket image
+	jacketFingerprint, err := computeJacketFingerprint(jacketImage)
+	if err != nil {
+		return AnalysisReport{}, fmt.Errorf("failed to calculate hashes of jacket image: %v", err)
+	}
+
+	// Find best match song
+	match, err := matchJacket(candidates, jacketFingerprint)
+	if err != nil {
+		return AnalysisReport{}, fmt.Errorf("failed to find best match song: %v", err)
+	}
+	opts.Debug.set("jacket", map[string]any{
+		"pHash":         formatHash(jacketFingerprint.pHash),
+		"dHash":         formatHash(jacketFingerprint.dHash),
+		"aHash":         formatHash(jacketFingerprint.aHash),
+		"colorHist":     encodeColorHist(jacketFingerprint.colorHist),
+		"matchSongID":   match.Song.ID,
+		"matchPlus":     match.Plus,
+		"score":         match.Score,
+		"pHashDistance": match.PHashDistance,
+		"margin":        match.Margin,
+	})
+	// Check if score is within threshold
+	if !match.Accepted() {
+		return AnalysisReport{}, fmt.Errorf("best match song score is too high: %.3f (pHash distance: %d, hash: %v)", match.Score, match.PHashDistance, formatHash(jacketFingerprint.pHash))
+	}
+
+	report := AnalysisReport{
+		ScreenType:    screenType,
+		SongObject:    match.Song,
+		PatternObject: Pattern{SongID: match.Song.ID},
+		JacketImage:   jacketImage,
+		Confidence: map[string]float64{
+			FieldScreen: distanceConfidence(float64(screenDistance), pHashThreshold),
+			FieldJacket: math.Min(distanceConfidence(match.Score, jacketScoreThreshold), marginConfidence(match.Margin, jacketScoreThreshold)),
+		},
+	}
+
+	if screenType == ResultScreen {
+		// An unreadable difficulty is kept with zero confidence, so the song match is not lost
+		difficulty, confidence, err := classifyDifficulty(img, targetConfig.Result.Difficulty, layout, config)
+		if err != nil {
+			confidence = 0
 		}
+		report.PatternObject.Difficulty = difficulty
+		report.Confidence[FieldDifficulty] = confidence
+
+		if config.FullComboPHash != "" && len(targetConfig.Result.FullCombo) == 4 {
+			if fullCombo, confidence, err := detectFullCombo(img, targetConfig.Result.FullCombo, layout, config); err == nil {
+				report.FullCombo = fullCombo
+				report.Confidence[FieldFullCombo] = confidence
+			}
+		}
+	}
+	if opts.Digits != nil {
+		readNumbers(img, targetConfig, layout, screenType, opts.Digits, &report)
 	}
+	report.Warnings, report.DisabledChecks = validateReport(report, config)
+
+	return report, nil
+}
 
-	if !found {
-		targetConfig = config.Configs[0] // fallback to default config (1920x1080, reference)
-		doScale = true
+// locateScreen picks the ROI config and layout of the screenshot and decides its screen type.
+// When the screen is unknown and opts.Calibrations is not nil, the anchors are searched for nearby
+// and the resulting calibration is cached.
+func locateScreen(img image.Image, config *Config, opts AnalyzeOptions) (ROIConfig, Layout, ScreenType, int, error) {
+	calibrations := opts.Calibrations
+	if len(config.Configs) == 0 {
+		return ROIConfig{}, Layout{}, UnknownScreen, 0, fmt.Errorf("no ROI config loaded")
+	}
+	// Determine which ROIConfig to use based on screen size
+	targetConfig, layout, exact := selectLayout(img, config)
+	screenSize := ScreenSize{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if !exact && calibrations != nil {
+		if calibration, ok := calibrations.Get(screenSize); ok {
+			layout = calibration.Layout(config.Reference)
+		}
 	}
 
-	screenType, err := decideScreenType(img, targetConfig, doScale, screenSize, config)
+	screenType, screenDistance, err := decideScreenType(img, targetConfig, layout, config)
 	if err != nil {
-		return AnalysisReport{}, fmt.Errorf("failed to decide screen type: %v", err)
+		return targetConfig, layout, UnknownScreen, 0, fmt.Errorf("failed to decide screen type: %v", err)
 	}
-	jacketMap := buildJacketMap(cache)
+	// The ROIs may be shifted by window borders, so look for an anchor nearby and retry
+	if screenType == UnknownScreen && !exact && calibrations != nil {
+		calibration, err := Calibrate(img, targetConfig, config, DetectLayout(img, config.Reference))
+		if err == nil {
+			layout = calibration.Layout(config.Reference)
+			if err := calibrations.Put(screenSize, calibration); err != nil && opts.CacheError != nil {
+				opts.CacheError(fmt.Errorf("failed to save calibration for %v: %v", screenSize, err))
+			}
+			screenType, screenDistance, err = decideScreenType(img, targetConfig, layout, config)
+			if err != nil {
+				return targetConfig, layout, UnknownScreen, 0, fmt.Errorf("failed to decide screen type: %v", err)
+			}
+		}
+	}
+	return targetConfig, layout, screenType, screenDistance, nil
+}
 
-	// Extract jacket image
+// cropJacket crops the jacket of a select or result screen.
+func cropJacket(img image.Image, targetConfig ROIConfig, layout Layout, screenType ScreenType) (image.Image, error) {
 	var baseJacketCoords []int
 	switch screenType {
 	case SelectScreen:
 		baseJacketCoords = targetConfig.Select.Jacket
 	case ResultScreen:
 		baseJacketCoords = targetConfig.Result.Jacket
+	default:
+		return nil, &ScreenError{ScreenType: screenType}
 	}
-	jacketRect := image.Rect(baseJacketCoords[0], baseJacketCoords[1], baseJacketCoords[2], baseJacketCoords[3])
-	if doScale {
-		x1, y1 := scaleCoordinate(baseJacketCoords[0], baseJacketCoords[1], config.Reference, screenSize)
-		x2, y2 := scaleCoordinate(baseJacketCoords[2], baseJacketCoords[3], config.Reference, screenSize)
-		jacketRect = image.Rect(x1, y1, x2, y2)
+	jacketRect, err := layout.Rect(baseJacketCoords)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jacket coordinates: %v", err)
 	}
 	jacketImage, err := cropImage(img, jacketRect)
 	if err != nil {
-		return AnalysisReport{}, fmt.Errorf("failed to crop jacket image: %v", err)
+		return nil, fmt.Errorf("failed to crop jacket image: %v", err)
 	}
-	// Calculate pHash of jacket image
-	jacketHash, err := goimagehash.PerceptionHash(jacketImage)
-	if err != nil {
-		return AnalysisReport{}, fmt.Errorf("failed to calculate pHash of jacket image: %v", err)
+	return jacketImage, nil
+}
+
+// selectLayout returns the ROIConfig for the screenshot's size and whether it is an exact match.
+// Sizes without their own entry use the reference config, mapped onto the detected viewport.
+func selectLayout(img image.Image, config *Config) (ROIConfig, Layout, bool) {
+	bounds := img.Bounds()
+	screenSize := ScreenSize{Width: bounds.Dx(), Height: bounds.Dy()}
+	for _, cfg := range config.Configs {
+		if cfg.ScreenSize == screenSize.String() {
+			return cfg, identityLayout(bounds), true
+		}
 	}
+	// fallback to default config (1920x1080, reference)
+	return config.Configs[0], DetectLayout(img, config.Reference), false
+}
 
-	// Find best match song
-	bestMatchSong, distance, err := bestMatchSong(jacketMap, *jacketHash)
-	if err != nil {
-		return AnalysisReport{}, fmt.Errorf("failed to find best match song: %v", err)
+// decideScreenType compares the screenshot against the anchor of every known screen.
+// Popups and the pause screen are checked first, as they are drawn over the other screens.
+// Anchors without a configured hash or coordinates are skipped, and UnknownScreen is returned when nothing matches.
+// Until a result anchor is configured, a screen the select anchor does not match is taken
+// as a result screen, as before result anchors existed.
+// The pHash distance of the matching anchor is returned along with the screen type.
+func decideScreenType(img image.Image, targetConfig ROIConfig, layout Layout, config *Config) (ScreenType, int, error) {
+	anchors := []screenAnchor{
+		{SelectPopupScreen, targetConfig.Select.PopupAnchor, config.SelectPopupAnchorPHash},
+		{PauseScreen, targetConfig.Pause.Anchor, config.PauseAnchorPHash},
+		{SelectScreen, targetConfig.Select.SpeedWidget, config.SpeedWidgetPHash},
+		{ResultScreen, targetConfig.Result.Anchor, config.ResultAnchorPHash},
 	}
-	// Check if distance is within threshold
-	if distance > pHashThreshold {
-		return AnalysisReport{}, fmt.Errorf("best match song distance is too high: %d (hash: %v)", distance, jacketHash.GetHash())
+
+	selectChecked, resultConfigured := false, false
+	for _, anchor := range anchors {
+		if anchor.pHash == "" || len(anchor.coords) == 0 {
+			continue
+		}
+		distance, err := anchorDistance(img, anchor, layout)
+		if err != nil {
+			return UnknownScreen, 0, err
+		}
+		if distance <= pHashThreshold {
+			return anchor.screenType, distance, nil
+		}
+		selectChecked = selectChecked || anchor.screenType == SelectScreen
+		resultConfigured = resultConfigured || anchor.screenType == ResultScreen
+	}
+	// The fallback is reported as the weakest match an anchor would be accepted with
+	if selectChecked && !resultConfigured {
+		return ResultScreen, pHashThreshold, nil
 	}
 
-	return AnalysisReport{SongObject: bestMatchSong, JacketImage: jacketImage}, nil
+	return UnknownScreen, 0, nil
 }
 
-func decideScreenType(img image.Image, targetConfig ROIConfig, doScale bool, screenSize ScreenSize, config *Config) (ScreenType, error) {
-	coords := targetConfig.Select.SpeedWidget
-	if len(coords) != 4 {
-		return -1, fmt.Errorf("invalid speed widget coordinates")
-	}
-
-	rect := image.Rect(coords[0], coords[1], coords[2], coords[3])
-	if doScale {
-		x1, y1 := scaleCoordinate(coords[0], coords[1], config.Reference, screenSize)
-		x2, y2 := scaleCoordinate(coords[2], coords[3], config.Reference, screenSize)
-		rect = image.Rect(x1, y1, x2, y2)
+func anchorDistance(img image.Image, anchor screenAnchor, layout Layout) (int, error) {
+	rect, err := layout.Rect(anchor.coords)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %v anchor coordinates: %v", anchor.screenType, err)
 	}
 	cropped, err := cropImage(img, rect)
 	if err != nil {
-		return -1, fmt.Errorf("failed to crop image: %v", err)
+		return 0, fmt.Errorf("failed to crop image: %v", err)
 	}
 
-	knownHashUInt := convertPythonHashToGoHash(config.SpeedWidgetPHash)
+	knownHashUInt := convertPythonHashToGoHash(anchor.pHash)
 	knownHash := goimagehash.NewImageHash(knownHashUInt, goimagehash.PHash)
 
 	calculatedHash, err := goimagehash.PerceptionHash(cropped)
 	if err != nil {
-		return -1, fmt.Errorf("failed to calculate pHash: %v", err)
+		return 0, fmt.Errorf("failed to calculate pHash: %v", err)
 	}
 
 	distance, err := calculatedHash.Distance(knownHash)
 	if err != nil {
-		return -1, fmt.Errorf("failed to calculate hamming distance: %v", err)
+		return 0, fmt.Errorf("failed to calculate hamming distance: %v", err)
 	}
-
-	if distance <= pHashThreshold {
-		return SelectScreen, nil
-	}
-
-	return ResultScreen, nil
+	return distance, nil
 }
 
 // Convert Python hash string (hex) to Go hash (uint64)
@@ -148,33 +322,3 @@ func cropImage(img image.Image, rect image.Rectangle) (image.Image, error) {
 	}
 	return subImager.SubImage(rect), nil
 }
-
-func scaleCoordinate(x int, y int, reference ScreenSize, userScreenSize ScreenSize) (int, int) {
-	return int(math.Round(float64(x) / float64(reference.Width) * float64(userScreenSize.Width))), int(math.Round(float64(y) / float64(reference.Height) * float64(userScreenSize.Height)))
-}
-
-func buildJacketMap(cache *Cache) map[string]Song {
-	jacketMap := make(map[string]Song)
-	for _, song := range cache.Songs {
-		jacketMap[strconv.FormatUint(convertPythonHashToGoHash(song.PHash), 10)] = song
-		jacketMap[strconv.FormatUint(convertPythonHashToGoHash(song.PlusPHash), 10)] = song
-	}
-
-	return jacketMap
-}
-
-func bestMatchSong(jacketMap map[string]Song, hash goimagehash.ImageHash) (Song, int, error) {
-	bestMatch := Song{}
-	bestDistance := math.MaxInt64
-	for compareHash, song := range jacketMap {
-		distance, err := hash.Distance(goimagehash.NewImageHash(convertPythonHashToGoHash(compareHash), goimagehash.PHash))
-		if err != nil {
-			return Song{}, 0, err
-		}
-		if distance < bestDistance {
-			bestDistance = distance
-			bestMatch = song
-		}
-	}
-	return bestMatch, bestDistance, nil
-}
@@ -4,7 +4,6 @@ import (
 	"image"
 	"image/color"
 	"os"
-	"strconv"
 	"testing"
 )
 
@@ -53,7 +52,8 @@ func TestDecideScreenTypeWithRealImages(t *testing.T) {
 
 	// Create config with real values from config.yaml
 	config := &Config{
-		SpeedWidgetPHash: "c0c73d38273ed2c3",
+		SpeedWidgetPHash:  "c0c73d38273ed2c3",
+		ResultAnchorPHash: "ffda2835444e83e8",
 		Reference: ScreenSize{
 			Width:  1920,
 			Height: 1080,
@@ -64,12 +64,15 @@ func TestDecideScreenTypeWithRealImages(t *testing.T) {
 				Select: SelectROIConfig{
 					SpeedWidget: []int{30, 908, 119, 932},
 				},
+				Result: ResultROIConfig{
+					Anchor: []int{628, 98, 822, 132},
+				},
 			},
 		},
 	}
 
 	// Test Select Screen
-	screenType, err := decideScreenType(selectImg, config.Configs[0], false, config.Reference, config)
+	screenType, _, err := decideScreenType(selectImg, config.Configs[0], identityLayout(selectImg.Bounds()), config)
 	if err != nil {
 		t.Errorf("decideScreenType failed for select.png: %v", err)
 	}
@@ -78,7 +81,7 @@ func TestDecideScreenTypeWithRealImages(t *testing.T) {
 	}
 
 	// Test Result Screen
-	screenType, err = decideScreenType(resultImg, config.Configs[0], false, config.Reference, config)
+	screenType, _, err = decideScreenType(resultImg, config.Configs[0], identityLayout(resultImg.Bounds()), config)
 	if err != nil {
 		// It might return error if it's not a select screen (which is expected behavior for now as we only detect SelectScreen)
 		// But if it returns an error, we should check if it's the expected "screen not recognized" error or something else.
@@ -94,74 +97,39 @@ func TestDecideScreenTypeWithRealImages(t *testing.T) {
 	if screenType != ResultScreen {
 		t.Errorf("expected ResultScreen, got %v", screenType)
 	}
-}
 
-func loadImage(path string) (image.Image, error) {
-	file, err := os.Open(path)
+	// Test Unknown Screen
+	blankImg := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	screenType, _, err = decideScreenType(blankImg, config.Configs[0], identityLayout(blankImg.Bounds()), config)
 	if err != nil {
-		return nil, err
+		t.Errorf("decideScreenType failed for blank image: %v", err)
 	}
-	defer file.Close()
-	img, _, err := image.Decode(file)
-	return img, err
-}
-
-func TestBuildJacketMap(t *testing.T) {
-	// Mock Cache with sample songs
-	cache := &Cache{
-		Songs: []Song{
-			{
-				ID:        1,
-				Title:     "Test Song 1",
-				PHash:     "c0c73d38273ed2c3", // Hex string
-				PlusPHash: "a1b2c3d4e5f60708", // Hex string
-			},
-			{
-				ID:        2,
-				Title:     "Test Song 2",
-				PHash:     "1234567890abcdef",
-				PlusPHash: "", // Empty hash
-			},
-		},
+	if screenType != UnknownScreen {
+		t.Errorf("expected UnknownScreen, got %v", screenType)
 	}
 
-	// Call buildJacketMap
-	jacketMap := buildJacketMap(cache)
-
-	// Verify map size
-	// Song 1 has 2 valid hashes, Song 2 has 1 valid hash (assuming empty string results in 0 which might be added or ignored depending on implementation, let's check convertPythonHashToGoHash behavior for empty string.
-	// convertPythonHashToGoHash("") -> new(big.Int).SetString("", 16) -> error -> returns 0.
-	// So empty hash becomes "0".
-	// If multiple songs have empty hash, they will overwrite each other at key "0".
-	// Let's assume for this test we expect 3 entries if "0" is included.
-
-	// Helper to convert hex to decimal string key
-	toKey := func(hex string) string {
-		val := convertPythonHashToGoHash(hex)
-		return strconv.FormatUint(val, 10)
+	// Without a result anchor, a screen that is not the select screen is a result screen
+	noResultAnchor := *config
+	noResultAnchor.ResultAnchorPHash = ""
+	screenType, _, err = decideScreenType(resultImg, config.Configs[0], identityLayout(resultImg.Bounds()), &noResultAnchor)
+	if err != nil {
+		t.Errorf("decideScreenType failed without a result anchor: %v", err)
 	}
-
-	// Check Song 1 PHash
-	key1 := toKey("c0c73d38273ed2c3")
-	if song, exists := jacketMap[key1]; !exists {
-		t.Errorf("Expected map to contain key %s for Song 1 PHash", key1)
-	} else if song.ID != 1 {
-		t.Errorf("Expected Song 1 for key %s, got ID %d", key1, song.ID)
+	if screenType != ResultScreen {
+		t.Errorf("expected ResultScreen without a result anchor, got %v", screenType)
 	}
-
-	// Check Song 1 PlusPHash
-	key2 := toKey("a1b2c3d4e5f60708")
-	if song, exists := jacketMap[key2]; !exists {
-		t.Errorf("Expected map to contain key %s for Song 1 PlusPHash", key2)
-	} else if song.ID != 1 {
-		t.Errorf("Expected Song 1 for key %s, got ID %d", key2, song.ID)
+	screenType, _, _ = decideScreenType(selectImg, config.Configs[0], identityLayout(selectImg.Bounds()), &noResultAnchor)
+	if screenType != SelectScreen {
+		t.Errorf("expected SelectScreen without a result anchor, got %v", screenType)
 	}
+}
 
-	// Check Song 2 PHash
-	key3 := toKey("1234567890abcdef")
-	if song, exists := jacketMap[key3]; !exists {
-		t.Errorf("Expected map to contain key %s for Song 2 PHash", key3)
-	} else if song.ID != 2 {
-		t.Errorf("Expected Song 2 for key %s, got ID %d", key3, song.ID)
+func loadImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	return img, err
 }
@@ -90,7 +90,7 @@ func TestFetchPatternsNeedsUpdate(t *testing.T) {
 }
 
 func TestFetchSongsNoNeedsUpdate(t *testing.T) {
-	testSong := Song{0, "example", "artist", "120", "someDLC", "pHash", "plusPHash"}
+	testSong := Song{ID: 0, Title: "example", Artist: "artist", BPM: "120", DLC: "someDLC", PHash: "pHash", PlusPHash: "plusPHash"}
 	cache := Cache{"2025-11-14", "2025-11-14", []Song{testSong}, []Pattern{}}
 	songs, isUpdated, err := FetchSongs(&cache)
 	if err != nil {
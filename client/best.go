@@ -0,0 +1,121 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Improvement compares a play with the personal best of its pattern from before the play.
+type Improvement struct {
+	Previous PatternBest
+	// HasPrevious is false for the first play of a pattern.
+	HasPrevious bool
+	JudgeDelta  float64
+	ScoreDelta  float64
+	PatchDelta  float64
+	NewJudge    bool
+	NewScore    bool
+	NewPatch    bool
+	// NewFullCombo and NewMaxPatch are true when the play is the first with the flag.
+	NewFullCombo bool
+	NewMaxPatch  bool
+}
+
+// ReportKey returns the pattern a report was read from.
+func ReportKey(r AnalysisReport) PatternKey {
+	return PatternKey{r.SongObject.ID, r.PatternObject.Line, r.PatternObject.Difficulty}
+}
+
+// PersonalBest returns the best of a pattern from the server archive and the local history,
+// keeping the best of every value. Either source may be nil.
+// The bool is false when neither holds the pattern.
+func PersonalBest(key PatternKey, archives []Archive, history *History) (PatternBest, bool, error) {
+	var best PatternBest
+	found := false
+	if history != nil {
+		var err error
+		if best, found, err = history.Best(key); err != nil {
+			return PatternBest{}, false, err
+		}
+	}
+	for _, a := range archives {
+		if ArchiveKey(a) != key {
+			continue
+		}
+		if !found {
+			best = PatternBest{PatternKey: key, Level: a.Level, LastPlayed: NewServerPlayRecord(a).PlayedAt}
+			found = true
+		}
+		best.Judge = max(best.Judge, a.Judge)
+		best.Score = max(best.Score, a.Score)
+		best.Patch = max(best.Patch, a.Patch)
+		best.FullCombo = best.FullCombo || a.FullCombo
+		best.MaxPatch = best.MaxPatch || a.MaxPatch
+	}
+	return best, found, nil
+}
+
+// CompareWithBest compares the values of a report with the previous best of its pattern.
+// Every value of the first play of a pattern is a new best.
+func CompareWithBest(report AnalysisReport, best PatternBest, found bool) Improvement {
+	i := Improvement{Previous: best, HasPrevious: found}
+	if found {
+		i.JudgeDelta = report.Judge - best.Judge
+		i.ScoreDelta = report.Score - best.Score
+		i.PatchDelta = report.Patch - best.Patch
+	}
+	i.NewJudge = !found || i.JudgeDelta > 0
+	i.NewScore = !found || i.ScoreDelta > 0
+	i.NewPatch = !found || i.PatchDelta > 0
+	i.NewFullCombo = report.FullCombo && !best.FullCombo
+	i.NewMaxPatch = report.MaxPatch && !best.MaxPatch
+	return i
+}
+
+// NewRecord reports whether the play beats the previous best in any value.
+func (i Improvement) NewRecord() bool {
+	return i.NewJudge || i.NewScore || i.NewPatch || i.NewFullCombo || i.NewMaxPatch
+}
+
+// Summary describes the change from the previous best, e.g. "+0.3200% judge, new best patch".
+func (i Improvement) Summary() string {
+	if !i.HasPrevious {
+		return "first record"
+	}
+	var parts []string
+	if i.JudgeDelta != 0 {
+		parts = append(parts, fmt.Sprintf("%+.4f%% judge", i.JudgeDelta))
+	}
+	if i.ScoreDelta != 0 {
+		parts = append(parts, fmt.Sprintf("%+.0f score", i.ScoreDelta))
+	}
+	if i.NewPatch {
+		parts = append(parts, fmt.Sprintf("new best patch (%+.2f)", i.PatchDelta))
+	} else if i.PatchDelta != 0 {
+		parts = append(parts, fmt.Sprintf("%+.2f patch", i.PatchDelta))
+	}
+	if i.NewFullCombo {
+		parts = append(parts, "first full combo")
+	}
+	if i.NewMaxPatch {
+		parts = append(parts, "first max patch")
+	}
+	if len(parts) == 0 {
+		return "same as best"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// UploadArchive returns the record of an improving play, with the play's own values.
+// The bool is false when the play improves nothing, or is below the previous best in another value:
+// uploading it would lower that value on the server.
+func (i Improvement) UploadArchive(report AnalysisReport, decoder string) (Archive, bool) {
+	if !i.NewRecord() {
+		return Archive{}, false
+	}
+	archive := report.ToArchive(decoder)
+	if i.HasPrevious && betterValues(i.Previous.ToArchive(decoder), archive) {
+		return Archive{}, false
+	}
+	return archive, true
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPersonalBest(t *testing.T) {
+	history := openTestHistory(t)
+	key := PatternKey{1, 4, "HARD"}
+	if _, ok, err := PersonalBest(key, nil, history); ok || err != nil {
+		t.Fatalf("expected no best, got %v, %v", ok, err)
+	}
+
+	if _, err := history.Add(testPlay(1, 99, 900000, 500, time.Now())); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	server := testArchive(1, 98, 950000, 490, "2025-11-01")
+	server.FullCombo = true
+	best, ok, err := PersonalBest(key, []Archive{server, testArchive(2, 100, 1000000, 999, "2025-11-01")}, history)
+	if err != nil || !ok {
+		t.Fatalf("expected a best, got %v, %v", ok, err)
+	}
+	if best.Judge != 99 || best.Score != 950000 || best.Patch != 500 || !best.FullCombo || best.Plays != 1 {
+		t.Errorf("unexpected best %+v", best)
+	}
+
+	best, ok, _ = PersonalBest(key, []Archive{server}, nil)
+	if !ok || best.Judge != 98 || best.Level != 12 {
+		t.Errorf("unexpected best from the archive alone %+v", best)
+	}
+}
+
+func TestCompareWithBest(t *testing.T) {
+	best := PatternBest{PatternKey: PatternKey{1, 4, "HARD"}, Level: 12, Judge: 99, Score: 950000, Patch: 500}
+	report := AnalysisReport{
+		SongObject:    Song{ID: 1},
+		PatternObject: Pattern{Line: 4, Difficulty: "HARD", Level: 12},
+		Judge:         99.32,
+		Score:         940000,
+		Patch:         505.5,
+		FullCombo:     true,
+	}
+
+	i := CompareWithBest(report, best, true)
+	if !i.NewRecord() || !i.NewJudge || i.NewScore || !i.NewPatch || !i.NewFullCombo {
+		t.Errorf("unexpected improvement %+v", i)
+	}
+	if summary := i.Summary(); summary != "+0.3200% judge, -10000 score, new best patch (+5.50), first full combo" {
+		t.Errorf("unexpected summary %q", summary)
+	}
+	// The score is below the best, so uploading the play would lower it on the server
+	if archive, ok := i.UploadArchive(report, "tester"); ok {
+		t.Errorf("expected no upload of a play below the best in a value, got %+v", archive)
+	}
+	report.Score = 960000
+	i = CompareWithBest(report, best, true)
+	archive, ok := i.UploadArchive(report, "tester")
+	if !ok || archive.Judge != 99.32 || archive.Score != 960000 || archive.Patch != 505.5 || !archive.FullCombo {
+		t.Errorf("unexpected upload %+v", archive)
+	}
+
+	report.Judge, report.Score, report.Patch, report.FullCombo = 98, 940000, 400, false
+	i = CompareWithBest(report, best, true)
+	if i.NewRecord() {
+		t.Errorf("expected no new record, got %+v", i)
+	}
+	if _, ok := i.UploadArchive(report, "tester"); ok {
+		t.Error("expected no upload without an improvement")
+	}
+
+	i = CompareWithBest(report, PatternBest{}, false)
+	if !i.NewRecord() || i.Summary() != "first record" {
+		t.Errorf("expected the first play to be a new record, got %+v", i)
+	}
+}
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/corona10/goimagehash"
+)
+
+// Search window of the anchor calibration, in reference pixels.
+const (
+	calibrationSearchRadius = 48
+	calibrationFineRadius   = 2
+)
+
+// calibrationStepDivisor sets the coarse search step to this fraction of the anchor size.
+// pHash only tolerates a shift of a few percent of the cropped size.
+const calibrationStepDivisor = 24
+
+// Scale steps tried around the detected viewport scale, as fractions of it.
+const (
+	calibrationScaleStep  = 0.005
+	calibrationScaleSteps = 10
+)
+
+// standardWidths are common 16:9 render widths for a windowed game.
+// Their scales are tried before any other, so an exact window size wins ties.
+var standardWidths = []int{1280, 1600, 1920, 2560, 3200, 3840}
+
+// Calibration places the reference layout inside a screenshot by translation and scale.
+type Calibration struct {
+	OffsetX  int        `json:"offsetX"`
+	OffsetY  int        `json:"offsetY"`
+	Scale    float64    `json:"scale"`
+	Anchor   ScreenType `json:"anchor"`
+	Distance int        `json:"distance"`
+}
+
+// Layout returns the layout described by the calibration.
+func (c Calibration) Layout(reference ScreenSize) Layout {
+	width := int(math.Round(float64(reference.Width) * c.Scale))
+	height := int(math.Round(float64(reference.Height) * c.Scale))
+	return Layout{
+		Viewport:  image.Rect(c.OffsetX, c.OffsetY, c.OffsetX+width, c.OffsetY+height),
+		Reference: reference,
+	}
+}
+
+// Calibrate searches for a screen anchor around its position in the initial layout,
+// and returns the translation and scale under which it matches best.
+// The speed widget and the result anchor are tried in turn.
+func Calibrate(img image.Image, targetConfig ROIConfig, config *Config, initial Layout) (Calibration, error) {
+	anchors := []screenAnchor{
+		{SelectScreen, targetConfig.Select.SpeedWidget, config.SpeedWidgetPHash},
+		{ResultScreen, targetConfig.Result.Anchor, config.ResultAnchorPHash},
+	}
+
+	best := Calibration{Distance: math.MaxInt}
+	for _, anchor := range anchors {
+		if anchor.pHash == "" || len(anchor.coords) != 4 {
+			continue
+		}
+		calibration, err := searchAnchor(img, anchor, initial)
+		if err != nil {
+			return Calibration{}, err
+		}
+		if calibration.Distance < best.Distance {
+			best = calibration
+		}
+	}
+	if best.Distance > pHashThreshold {
+		return Calibration{}, fmt.Errorf("no screen anchor found near its expected position")
+	}
+	return best, nil
+}
+
+// searchAnchor runs a coarse translation search at the initial scale,
+// then a fine translation search around the best position for every candidate scale.
+func searchAnchor(img image.Image, anchor screenAnchor, initial Layout) (Calibration, error) {
+	knownHash := goimagehash.NewImageHash(convertPythonHashToGoHash(anchor.pHash), goimagehash.PHash)
+	reference := initial.Reference
+	scale := float64(initial.Viewport.Dx()) / float64(reference.Width)
+	width := float64(anchor.coords[2] - anchor.coords[0])
+	height := float64(anchor.coords[3] - anchor.coords[1])
+
+	distanceAt := func(x int, y int, s float64) (int, error) {
+		rect := image.Rect(x, y, x+int(math.Round(width*s)), y+int(math.Round(height*s)))
+		if !rect.In(img.Bounds()) || rect.Empty() {
+			return math.MaxInt, nil
+		}
+		cropped, err := cropImage(img, rect)
+		if err != nil {
+			return 0, err
+		}
+		hash, err := goimagehash.PerceptionHash(cropped)
+		if err != nil {
+			return 0, fmt.Errorf("failed to calculate pHash: %v", err)
+		}
+		return hash.Distance(knownHash)
+	}
+
+	start := initial.Point(anchor.coords[0], anchor.coords[1])
+	bestX, bestY, bestScale := start.X, start.Y, scale
+	bestDistance := math.MaxInt
+	search := func(centerX int, centerY int, radius int, stepX int, stepY int, s float64) error {
+		for dy := -radius; dy <= radius; dy += stepY {
+			for dx := -radius; dx <= radius; dx += stepX {
+				distance, err := distanceAt(centerX+dx, centerY+dy, s)
+				if err != nil {
+					return err
+				}
+				if distance < bestDistance {
+					bestX, bestY, bestScale, bestDistance = centerX+dx, centerY+dy, s, distance
+				}
+			}
+		}
+		return nil
+	}
+
+	radius := int(math.Round(calibrationSearchRadius * scale))
+	stepX := max(1, int(width*scale)/calibrationStepDivisor)
+	stepY := max(1, int(height*scale)/calibrationStepDivisor)
+	if err := search(start.X, start.Y, radius, stepX, stepY, scale); err != nil {
+		return Calibration{}, err
+	}
+	centerX, centerY := bestX, bestY
+	bestDistance = math.MaxInt
+	for _, s := range candidateScales(img.Bounds(), reference, scale) {
+		if err := search(centerX, centerY, calibrationFineRadius, 1, 1, s); err != nil {
+			return Calibration{}, err
+		}
+	}
+
+	return Calibration{
+		OffsetX:  bestX - int(math.Round(float64(anchor.coords[0])*bestScale)),
+		OffsetY:  bestY - int(math.Round(float64(anchor.coords[1])*bestScale)),
+		Scale:    bestScale,
+		Anchor:   anchor.screenType,
+		Distance: bestDistance,
+	}, nil
+}
+
+// candidateScales lists the standard window scales that fit in bounds, closest to scale first,
+// followed by scale and the steps around it.
+func candidateScales(bounds image.Rectangle, reference ScreenSize, scale float64) []float64 {
+	var standard []float64
+	for _, width := range standardWidths {
+		s := float64(width) / float64(reference.Width)
+		if float64(reference.Width)*s > float64(bounds.Dx()) || float64(reference.Height)*s > float64(bounds.Dy()) {
+			continue
+		}
+		if math.Abs(s-scale) > calibrationScaleStep*calibrationScaleSteps*scale {
+			continue
+		}
+		standard = append(standard, s)
+	}
+	sort.Slice(standard, func(i, j int) bool {
+		return math.Abs(standard[i]-scale) < math.Abs(standard[j]-scale)
+	})
+
+	scales := append(standard, scale)
+	for i := 1; i <= calibrationScaleSteps; i++ {
+		scales = append(scales, scale*(1-float64(i)*calibrationScaleStep), scale*(1+float64(i)*calibrationScaleStep))
+	}
+	return scales
+}
+
+// CalibrationCache stores calibrations per screen size so the search only runs once per setup.
+type CalibrationCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]Calibration
+}
+
+// LoadCalibrationCache loads the calibration cache from the local file system.
+// A missing file results in an empty cache.
+func LoadCalibrationCache() (*CalibrationCache, error) {
+	os.MkdirAll(getCacheDirectory(), os.ModeDir)
+	return loadCalibrationCacheFrom(filepath.Join(getCacheDirectory(), "calibration.json"))
+}
+
+// NewCalibrationCache returns an empty cache that is only kept in memory.
+func NewCalibrationCache() *CalibrationCache {
+	return &CalibrationCache{entries: make(map[string]Calibration)}
+}
+
+func loadCalibrationCacheFrom(path string) (*CalibrationCache, error) {
+	c := &CalibrationCache{path: path, entries: make(map[string]Calibration)}
+	if !fileExists(path) {
+		return c, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return c, fmt.Errorf("error opening calibration file: %v", err)
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(&c.entries); err != nil {
+		return c, fmt.Errorf("error parsing JSON: %v", err)
+	}
+	return c, nil
+}
+
+// Get returns the calibration for the given screen size, if there is one.
+func (c *CalibrationCache) Get(size ScreenSize) (Calibration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	calibration, ok := c.entries[size.String()]
+	return calibration, ok
+}
+
+// Put stores the calibration for the given screen size and saves the cache.
+func (c *CalibrationCache) Put(size ScreenSize, calibration Calibration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[size.String()] = calibration
+	return c.save()
+}
+
+func (c *CalibrationCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+	file, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening calibration file: %v", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(c.entries); err != nil {
+		return fmt.Errorf("error writing JSON: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+var calibrationConfig = &Config{
+	Reference:         ScreenSize{Width: 1920, Height: 1080},
+	SpeedWidgetPHash:  "c0c73d38273ed2c3",
+	ResultAnchorPHash: "ffda2835444e83e8",
+	Configs: []ROIConfig{
+		{
+			ScreenSize: "1920x1080",
+			Select:     SelectROIConfig{SpeedWidget: []int{30, 908, 119, 932}, Jacket: []int{760, 66, 1160, 466}},
+			Result:     ResultROIConfig{Anchor: []int{628, 98, 822, 132}, Jacket: []int{122, 193, 522, 593}},
+		},
+	},
+}
+
+// windowedCapture draws img inside a window with 8px grey borders and a 31px title bar.
+func windowedCapture(img image.Image) *image.RGBA {
+	canvas := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx()+16, img.Bounds().Dy()+39))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.RGBA{96, 96, 96, 255}), image.Point{}, draw.Src)
+	draw.Draw(canvas, image.Rect(0, 0, canvas.Bounds().Dx(), 31), image.NewUniform(color.RGBA{230, 230, 230, 255}), image.Point{}, draw.Src)
+	draw.Draw(canvas, img.Bounds().Add(image.Pt(8, 31)), img, image.Point{}, draw.Src)
+	return canvas
+}
+
+func TestCalibrateWindowedCapture(t *testing.T) {
+	for _, tt := range []struct {
+		path   string
+		anchor ScreenType
+	}{
+		{"../testing/select.png", SelectScreen},
+		{"../testing/result.png", ResultScreen},
+	} {
+		t.Run(tt.path, func(t *testing.T) {
+			img, err := loadImage(tt.path)
+			if err != nil {
+				t.Fatalf("failed to load image: %v", err)
+			}
+			windowed := windowedCapture(img)
+
+			targetConfig, layout, _ := selectLayout(windowed, calibrationConfig)
+			calibration, err := Calibrate(windowed, targetConfig, calibrationConfig, layout)
+			if err != nil {
+				t.Fatalf("Calibrate failed: %v", err)
+			}
+			if calibration.Anchor != tt.anchor {
+				t.Errorf("expected anchor %v, got %v", tt.anchor, calibration.Anchor)
+			}
+			if math.Abs(calibration.Scale-1) > 0.02 {
+				t.Errorf("expected scale near 1, got %.3f", calibration.Scale)
+			}
+			if abs(calibration.OffsetX-8) > 3 || abs(calibration.OffsetY-31) > 3 {
+				t.Errorf("expected offset near (8, 31), got (%d, %d)", calibration.OffsetX, calibration.OffsetY)
+			}
+		})
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func TestAnalyzeImageCalibratesAndCaches(t *testing.T) {
+	resultImg, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+	jacket, err := cropImage(resultImg, image.Rect(122, 193, 522, 593))
+	if err != nil {
+		t.Fatalf("cropImage failed: %v", err)
+	}
+	cache := &Cache{Songs: []Song{songFromImage(t, 7, jacket)}}
+	windowed := windowedCapture(resultImg)
+
+	calibrations, err := loadCalibrationCacheFrom(filepath.Join(t.TempDir(), "calibration.json"))
+	if err != nil {
+		t.Fatalf("loadCalibrationCacheFrom failed: %v", err)
+	}
+
+	// Without calibration the title bar shifts every ROI off target
+	if _, err := AnalyzeImage(windowed, cache, calibrationConfig, AnalyzeOptions{}); err == nil {
+		t.Fatal("expected uncalibrated analysis to fail")
+	}
+
+	report, err := AnalyzeImage(windowed, cache, calibrationConfig, AnalyzeOptions{Calibrations: calibrations})
+	if err != nil {
+		t.Fatalf("AnalyzeImage failed: %v", err)
+	}
+	if report.SongObject.ID != 7 {
+		t.Errorf("expected song 7, got %d", report.SongObject.ID)
+	}
+
+	size := ScreenSize{Width: windowed.Bounds().Dx(), Height: windowed.Bounds().Dy()}
+	if _, ok := calibrations.Get(size); !ok {
+		t.Fatal("expected calibration to be cached")
+	}
+	reloaded, err := loadCalibrationCacheFrom(calibrations.path)
+	if err != nil {
+		t.Fatalf("failed to reload calibration cache: %v", err)
+	}
+	cached, ok := reloaded.Get(size)
+	if !ok {
+		t.Fatal("expected calibration to be saved")
+	}
+	if cached.Anchor != ResultScreen {
+		t.Errorf("expected result anchor, got %v", cached.Anchor)
+	}
+}
+
+func TestAnalyzeImageReportsCalibrationSaveError(t *testing.T) {
+	resultImg, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+	jacket, err := cropImage(resultImg, image.Rect(122, 193, 522, 593))
+	if err != nil {
+		t.Fatalf("cropImage failed: %v", err)
+	}
+	cache := &Cache{Songs: []Song{songFromImage(t, 7, jacket)}}
+
+	// The folder of the cache file does not exist, so saving fails
+	calibrations, err := loadCalibrationCacheFrom(filepath.Join(t.TempDir(), "missing", "calibration.json"))
+	if err != nil {
+		t.Fatalf("loadCalibrationCacheFrom failed: %v", err)
+	}
+	var cacheErrors []error
+	opts := AnalyzeOptions{Calibrations: calibrations, CacheError: func(err error) { cacheErrors = append(cacheErrors, err) }}
+	if _, err := AnalyzeImage(windowedCapture(resultImg), cache, calibrationConfig, opts); err != nil {
+		t.Fatalf("AnalyzeImage failed: %v", err)
+	}
+	if len(cacheErrors) != 1 {
+		t.Errorf("expected one cache error, got %v", cacheErrors)
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/image/font"
+)
+
+// Layout of the share card.
+const (
+	cardWidth   = 960
+	cardHeight  = 400
+	cardMargin  = 40
+	cardJacket  = cardHeight - 2*cardMargin
+	cardBorder  = 4
+	cardBadgeH  = 30
+	cardTextX   = cardMargin + cardJacket + cardMargin
+	cardTextMax = cardWidth - cardTextX - cardMargin
+)
+
+// Colors of the share card.
+var (
+	cardBackground = color.RGBA{20, 20, 26, 255}
+	cardPanel      = color.RGBA{34, 34, 42, 255}
+	cardMuted      = color.RGBA{160, 160, 172, 255}
+	cardAccent     = color.RGBA{90, 170, 255, 255}
+	cardRecord     = color.RGBA{110, 220, 130, 255}
+)
+
+// RenderShareCard draws a result card of an analysed play with its improvement over the previous best.
+func RenderShareCard(report AnalysisReport, improvement Improvement, decoder string, fonts *Fonts) *image.RGBA {
+	card := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	fillRect(card, card.Bounds(), cardBackground)
+
+	// The jacket is framed by the lamp of the play
+	frame := image.Rect(cardMargin, cardMargin, cardMargin+cardJacket, cardMargin+cardJacket)
+	fillRect(card, frame, ArchiveLamp(report.ToArchive(decoder)).Color())
+	if report.JacketImage != nil {
+		drawScaled(card, frame.Inset(cardBorder), report.JacketImage)
+	} else {
+		fillRect(card, frame.Inset(cardBorder), cardPanel)
+	}
+
+	y := cardMargin + 30
+	drawText(card, fonts.Face(30, true), cardTextX, y, report.SongObject.Title, color.White, cardTextMax)
+	y += 30
+	drawText(card, fonts.Face(18, false), cardTextX, y, report.SongObject.Artist, cardMuted, cardTextMax)
+	y += 34
+	pattern := fmt.Sprintf("%dL %s  Lv.%d", report.PatternObject.Line, report.PatternObject.Difficulty, report.PatternObject.Level)
+	drawText(card, fonts.Face(20, true), cardTextX, y, pattern, cardAccent, cardTextMax)
+
+	// Judge, score and patch side by side, with their labels above
+	y += 56
+	labelFace, valueFace := fonts.Face(14, false), fonts.Face(30, true)
+	x := cardTextX
+	for _, v := range []struct{ label, value string }{
+		{"JUDGE", fmt.Sprintf("%.4f%%", report.Judge)},
+		{"SCORE", fmt.Sprintf("%.0f", report.Score)},
+		{"PATCH", fmt.Sprintf("%.2f", report.Patch)},
+	} {
+		drawText(card, labelFace, x, y-34, v.label, cardMuted, 0)
+		x += drawText(card, valueFace, x, y, v.value, color.White, 0) + 28
+	}
+
+	// Rank and lamp badges
+	y += 20
+	x = cardTextX
+	badgeFace := fonts.Face(16, true)
+	if report.Rank != "" {
+		x += drawBadge(card, badgeFace, x, y, report.Rank, cardPanel, color.White) + 10
+	}
+	if report.FullCombo {
+		x += drawBadge(card, badgeFace, x, y, "FULL COMBO", LampFullCombo.Color(), cardBackground) + 10
+	}
+	if report.MaxPatch {
+		drawBadge(card, badgeFace, x, y, "MAX PATCH", LampMaxPatch.Color(), cardBackground)
+	}
+
+	y += cardBadgeH + 40
+	summary, summaryColor := improvement.Summary(), color.Color(cardMuted)
+	if improvement.NewRecord() {
+		summary, summaryColor = "NEW RECORD!  "+summary, cardRecord
+	}
+	drawText(card, fonts.Face(18, true), cardTextX, y, summary, summaryColor, cardTextMax)
+
+	footer := "PLATiNA-ARCHiVE"
+	if decoder != "" {
+		footer += "  ·  " + decoder
+	}
+	footerFace := fonts.Face(14, false)
+	width := font.MeasureString(footerFace, footer).Ceil()
+	drawText(card, footerFace, cardWidth-cardMargin-width, cardHeight-cardMargin+8, footer, cardMuted, 0)
+	return card
+}
+
+// drawBadge draws text on a filled box with its top at y. It returns the width of the box.
+func drawBadge(dst *image.RGBA, face font.Face, x int, y int, text string, fill color.Color, textColor color.Color) int {
+	width := font.MeasureString(face, text).Ceil() + 20
+	fillRect(dst, image.Rect(x, y, x+width, y+cardBadgeH), fill)
+	drawText(dst, face, x+10, y+cardBadgeH-9, text, textColor, 0)
+	return width
+}
+
+// ShareCardPath returns the file a share card of a play is saved to, in the cache directory.
+func ShareCardPath(report AnalysisReport, playedAt time.Time) string {
+	name := fmt.Sprintf("%s-%d-%d%s.png", playedAt.Format("20060102-150405"), report.SongObject.ID,
+		report.PatternObject.Line, strings.ToLower(report.PatternObject.Difficulty))
+	return filepath.Join(getCacheDirectory(), "cards", name)
+}
+
+// SaveShareCard writes a share card to path, creating its folder.
+func SaveShareCard(path string, card image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating card folder: %v", err)
+	}
+	return SavePNG(path, card)
+}
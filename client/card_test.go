@@ -0,0 +1,47 @@
+package client
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderShareCard(t *testing.T) {
+	jacket := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	fillRect(jacket, jacket.Bounds(), color.RGBA{0, 255, 0, 255})
+	report := AnalysisReport{
+		SongObject:    Song{ID: 1, Title: "Firework", Artist: "Someone"},
+		PatternObject: Pattern{SongID: 1, Line: 4, Difficulty: "HARD", Level: 12},
+		JacketImage:   jacket,
+		Judge:         99.1234, Score: 991234, Patch: 512.5, Rank: "S+", FullCombo: true,
+	}
+	improvement := CompareWithBest(report, PatternBest{Judge: 98, Score: 980000, Patch: 500}, true)
+	card := RenderShareCard(report, improvement, "tester", loadTestFonts(t))
+
+	if card.Bounds().Dx() != cardWidth || card.Bounds().Dy() != cardHeight {
+		t.Fatalf("unexpected card size %v", card.Bounds())
+	}
+	if c := card.RGBAAt(cardMargin+1, cardMargin+1); c != LampFullCombo.Color() {
+		t.Errorf("expected the full combo frame around the jacket, got %v", c)
+	}
+	if c := card.RGBAAt(cardMargin+cardJacket/2, cardMargin+cardJacket/2); c != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("expected the jacket, got %v", c)
+	}
+
+	report.JacketImage = nil
+	card = RenderShareCard(report, Improvement{}, "", loadTestFonts(t))
+	if c := card.RGBAAt(cardMargin+cardJacket/2, cardMargin+cardJacket/2); c != cardPanel {
+		t.Errorf("expected an empty panel without a jacket, got %v", c)
+	}
+}
+
+func TestShareCardPath(t *testing.T) {
+	report := AnalysisReport{SongObject: Song{ID: 7}, PatternObject: Pattern{Line: 6, Difficulty: "OVER"}}
+	path := ShareCardPath(report, time.Date(2025, 11, 20, 21, 5, 9, 0, time.Local))
+	if filepath.Base(path) != "20251120-210509-7-6over.png" || !strings.Contains(path, "cards") {
+		t.Errorf("unexpected path %s", path)
+	}
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+
+	"golang.org/x/image/font"
+)
+
+// Chart image formats.
+const (
+	ChartFormatPNG = "png"
+	ChartFormatSVG = "svg"
+)
+
+// Layout of a rendered chart. Charts are stacked top to bottom.
+const (
+	chartWidth     = 720
+	chartHeight    = 220
+	chartLeft      = 64
+	chartRight     = 20
+	chartTop       = 36
+	chartBottom    = 28
+	chartTicks     = 4
+	chartTitleSize = 15
+	chartLabelSize = 11
+	chartMarker    = 5
+)
+
+// Colors of a rendered chart.
+var (
+	chartBackground = color.RGBA{24, 24, 28, 255}
+	chartGrid       = color.RGBA{60, 60, 68, 255}
+	chartLabel      = color.RGBA{170, 170, 180, 255}
+	chartLine       = color.RGBA{90, 170, 255, 255}
+)
+
+// chartFrame maps the points of a chart to the plot area of its image.
+type chartFrame struct {
+	plot      image.Rectangle
+	first     int64
+	last      int64
+	low, high float64
+}
+
+func newChartFrame(c Chart, top int) chartFrame {
+	f := chartFrame{plot: image.Rect(chartLeft, top+chartTop, chartWidth-chartRight, top+chartHeight-chartBottom)}
+	for i, p := range c.Points {
+		if i == 0 {
+			f.first, f.last, f.low, f.high = p.Date.Unix(), p.Date.Unix(), p.Value, p.Value
+		}
+		f.first, f.last = min(f.first, p.Date.Unix()), max(f.last, p.Date.Unix())
+		f.low, f.high = min(f.low, p.Value), max(f.high, p.Value)
+	}
+	// Leave a margin around the values, and some range around a single one
+	if span := f.high - f.low; span > 0 {
+		f.low, f.high = f.low-span*0.05, f.high+span*0.05
+	} else {
+		f.low, f.high = f.low-1, f.high+1
+	}
+	return f
+}
+
+// point returns the position of a point in the image.
+func (f chartFrame) point(p ChartPoint) image.Point {
+	x := f.plot.Min.X + f.plot.Dx()/2
+	if f.last > f.first {
+		x = f.plot.Min.X + int(float64(p.Date.Unix()-f.first)/float64(f.last-f.first)*float64(f.plot.Dx()))
+	}
+	y := f.plot.Max.Y - int((p.Value-f.low)/(f.high-f.low)*float64(f.plot.Dy()))
+	return image.Pt(x, y)
+}
+
+// ticks returns the values of the horizontal grid lines with their labels, lowest first.
+func (f chartFrame) ticks() ([]int, []string) {
+	var ys []int
+	var labels []string
+	for i := 0; i <= chartTicks; i++ {
+		v := f.low + (f.high-f.low)*float64(i)/chartTicks
+		ys = append(ys, f.plot.Max.Y-f.plot.Dy()*i/chartTicks)
+		if f.high-f.low >= 20 {
+			labels = append(labels, fmt.Sprintf("%.0f", v))
+		} else {
+			labels = append(labels, fmt.Sprintf("%.2f", v))
+		}
+	}
+	return ys, labels
+}
+
+// dateLabels returns the labels of the first and last day under the plot.
+func dateLabels(c Chart) (string, string) {
+	if len(c.Points) == 0 {
+		return "", ""
+	}
+	return c.Points[0].Date.Format(archiveDateLayout), c.Points[len(c.Points)-1].Date.Format(archiveDateLayout)
+}
+
+// RenderCharts draws the charts stacked top to bottom.
+func RenderCharts(charts []Chart, fonts *Fonts) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, max(1, len(charts))*chartHeight))
+	fillRect(img, img.Bounds(), chartBackground)
+	titleFace := fonts.Face(chartTitleSize, true)
+	labelFace := fonts.Face(chartLabelSize, false)
+
+	for i, c := range charts {
+		top := i * chartHeight
+		f := newChartFrame(c, top)
+		drawText(img, titleFace, chartLeft, top+chartTop-14, c.Title, color.White, chartWidth-chartLeft-chartRight)
+		ys, labels := f.ticks()
+		for j, y := range ys {
+			fillRect(img, image.Rect(f.plot.Min.X, y, f.plot.Max.X, y+1), chartGrid)
+			width := font.MeasureString(labelFace, labels[j]).Ceil()
+			drawText(img, labelFace, chartLeft-8-width, y+chartLabelSize/2, labels[j], chartLabel, 0)
+		}
+		if len(c.Points) == 0 {
+			drawText(img, labelFace, f.plot.Min.X+8, f.plot.Min.Y+f.plot.Dy()/2, "No records", chartLabel, 0)
+			continue
+		}
+		from, to := dateLabels(c)
+		drawText(img, labelFace, f.plot.Min.X, f.plot.Max.Y+chartLabelSize+8, from, chartLabel, 0)
+		width := font.MeasureString(labelFace, to).Ceil()
+		drawText(img, labelFace, f.plot.Max.X-width, f.plot.Max.Y+chartLabelSize+8, to, chartLabel, 0)
+
+		points := make([]image.Point, len(c.Points))
+		for j, p := range c.Points {
+			points[j] = f.point(p)
+		}
+		drawPolyline(img, points, 2, chartLine)
+		for _, p := range points {
+			fillRect(img, image.Rect(p.X-chartMarker/2, p.Y-chartMarker/2, p.X+chartMarker/2+1, p.Y+chartMarker/2+1), chartLine)
+		}
+	}
+	return img
+}
+
+// WriteChartsSVG writes the charts as an SVG image laid out like RenderCharts.
+func WriteChartsSVG(w io.Writer, charts []Chart) error {
+	bw := bufio.NewWriter(w)
+	height := max(1, len(charts)) * chartHeight
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Noto Sans KR, sans-serif">`+"\n",
+		chartWidth, height, chartWidth, height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", svgColor(chartBackground))
+
+	for i, c := range charts {
+		top := i * chartHeight
+		f := newChartFrame(c, top)
+		fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="%d" font-weight="bold" fill="white">%s</text>`+"\n",
+			chartLeft, top+chartTop-14, chartTitleSize, svgEscape(c.Title))
+		ys, labels := f.ticks()
+		for j, y := range ys {
+			fmt.Fprintf(bw, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s"/>`+"\n", f.plot.Min.X, y, f.plot.Max.X, y, svgColor(chartGrid))
+			fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="%d" fill="%s" text-anchor="end">%s</text>`+"\n",
+				chartLeft-8, y+chartLabelSize/2, chartLabelSize, svgColor(chartLabel), labels[j])
+		}
+		if len(c.Points) == 0 {
+			fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="%d" fill="%s">No records</text>`+"\n",
+				f.plot.Min.X+8, f.plot.Min.Y+f.plot.Dy()/2, chartLabelSize, svgColor(chartLabel))
+			continue
+		}
+		from, to := dateLabels(c)
+		fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="%d" fill="%s">%s</text>`+"\n",
+			f.plot.Min.X, f.plot.Max.Y+chartLabelSize+8, chartLabelSize, svgColor(chartLabel), from)
+		fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="%d" fill="%s" text-anchor="end">%s</text>`+"\n",
+			f.plot.Max.X, f.plot.Max.Y+chartLabelSize+8, chartLabelSize, svgColor(chartLabel), to)
+
+		coords := make([]string, len(c.Points))
+		for j, p := range c.Points {
+			pt := f.point(p)
+			coords[j] = fmt.Sprintf("%d,%d", pt.X, pt.Y)
+		}
+		fmt.Fprintf(bw, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(coords, " "), svgColor(chartLine))
+		for _, p := range c.Points {
+			pt := f.point(p)
+			fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+				pt.X-chartMarker/2, pt.Y-chartMarker/2, chartMarker, chartMarker, svgColor(chartLine))
+		}
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// WriteCharts writes the charts as a PNG or SVG image. Fonts are only used for PNG.
+func WriteCharts(w io.Writer, format string, charts []Chart, fonts *Fonts) error {
+	switch format {
+	case ChartFormatPNG:
+		if err := png.Encode(w, RenderCharts(charts, fonts)); err != nil {
+			return fmt.Errorf("error writing PNG: %v", err)
+		}
+		return nil
+	case ChartFormatSVG:
+		return WriteChartsSVG(w, charts)
+	}
+	return fmt.Errorf("unknown chart format %q", format)
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func svgEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testChart() Chart {
+	day := time.Date(2025, 11, 20, 0, 0, 0, 0, time.Local)
+	return Chart{Title: "Judge - <별의 노래>", Points: []ChartPoint{
+		{day, 95},
+		{day.AddDate(0, 0, 1), 97},
+		{day.AddDate(0, 0, 3), 96},
+	}}
+}
+
+func TestChartFrame(t *testing.T) {
+	c := testChart()
+	f := newChartFrame(c, chartHeight)
+	first, last := f.point(c.Points[0]), f.point(c.Points[2])
+	if first.X != f.plot.Min.X || last.X != f.plot.Max.X {
+		t.Errorf("expected the first and last days at the edges, got %v %v in %v", first, last, f.plot)
+	}
+	if high := f.point(c.Points[1]); high.Y <= f.plot.Min.Y || high.Y >= first.Y {
+		t.Errorf("expected the highest value near the top, got %v", high)
+	}
+
+	single := newChartFrame(Chart{Points: c.Points[:1]}, 0)
+	if p := single.point(c.Points[0]); p != image.Pt(single.plot.Min.X+single.plot.Dx()/2, single.plot.Min.Y+single.plot.Dy()/2) {
+		t.Errorf("expected a single point in the middle, got %v", p)
+	}
+}
+
+func TestRenderCharts(t *testing.T) {
+	charts := []Chart{testChart(), {Title: "Empty"}}
+	img := RenderCharts(charts, loadTestFonts(t))
+	if img.Bounds().Dy() != 2*chartHeight {
+		t.Fatalf("expected 2 stacked charts, got %v", img.Bounds())
+	}
+	p := newChartFrame(charts[0], 0).point(charts[0].Points[1])
+	if c := img.RGBAAt(p.X, p.Y); c != chartLine {
+		t.Errorf("expected a marker at %v, got %v", p, c)
+	}
+}
+
+func TestWriteChartsSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCharts(&buf, ChartFormatSVG, []Chart{testChart()}, nil); err != nil {
+		t.Fatalf("WriteCharts failed: %v", err)
+	}
+	svg := buf.String()
+	for _, want := range []string{"<svg ", "Judge - &lt;별의 노래&gt;", "<polyline ", "2025-11-20", "2025-11-23", "</svg>"} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("expected %q in the SVG", want)
+		}
+	}
+	if err := WriteCharts(&buf, "gif", nil, nil); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// goldenTolerance is the largest difference accepted between numeric fields.
+const goldenTolerance = 1e-6
+
+// CorpusCase is a labelled screenshot of the regression corpus.
+// Each screenshot has a sidecar JSON file with the same base name holding the
+// expected report fields, keyed as in ReportFields. Fields left out are not checked.
+type CorpusCase struct {
+	Name      string
+	ImagePath string
+	Expected  map[string]any
+}
+
+// LoadCorpus returns every PNG or JPEG screenshot in dir that has a sidecar JSON file.
+func LoadCorpus(dir string) ([]CorpusCase, error) {
+	screenshots, err := LoadScreenshots(dir)
+	if err != nil {
+		return nil, err
+	}
+	var cases []CorpusCase
+	for _, c := range screenshots {
+		if c.Expected != nil {
+			cases = append(cases, c)
+		}
+	}
+	return cases, nil
+}
+
+// LoadScreenshots returns every PNG or JPEG screenshot in dir.
+// Expected is nil for screenshots without a sidecar JSON file.
+func LoadScreenshots(dir string) ([]CorpusCase, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading corpus directory: %v", err)
+	}
+
+	var cases []CorpusCase
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".png" && ext != ".jpg" && ext != ".jpeg") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		c := CorpusCase{Name: name, ImagePath: filepath.Join(dir, entry.Name())}
+		sidecarPath := filepath.Join(dir, name+".json")
+		if fileExists(sidecarPath) {
+			expected, err := loadSidecar(sidecarPath)
+			if err != nil {
+				return nil, err
+			}
+			c.Expected = expected
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+func loadSidecar(path string) (map[string]any, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening sidecar: %v", err)
+	}
+	defer file.Close()
+
+	var expected map[string]any
+	if err := json.NewDecoder(file).Decode(&expected); err != nil {
+		return nil, fmt.Errorf("error parsing JSON in %s: %v", path, err)
+	}
+	return expected, nil
+}
+
+// LoadImage decodes the screenshot of the case.
+func (c CorpusCase) LoadImage() (image.Image, error) {
+	img, err := ReadImageFile(c.ImagePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", c.ImagePath, err)
+	}
+	return img, nil
+}
+
+// ReportFields flattens the comparable fields of a report, keyed as in corpus sidecars.
+// Fields that were not extracted are left out.
+func ReportFields(report AnalysisReport) map[string]any {
+	fields := map[string]any{
+		"screenType": report.ScreenType.String(),
+		"title":      report.SongObject.Title,
+	}
+	if report.PatternObject.Difficulty != "" {
+		fields["difficulty"] = report.PatternObject.Difficulty
+	}
+	if report.PatternObject.Line > 0 {
+		fields["line"] = report.PatternObject.Line
+	}
+	if report.PatternObject.Level > 0 {
+		fields["level"] = report.PatternObject.Level
+	}
+	if _, ok := report.Confidence[FieldJudge]; ok {
+		fields["judge"] = report.Judge
+	}
+	if _, ok := report.Confidence[FieldScore]; ok {
+		fields["score"] = report.Score
+	}
+	if _, ok := report.Confidence[FieldPatch]; ok {
+		fields["patch"] = report.Patch
+	}
+	if _, ok := report.Confidence[FieldFullCombo]; ok {
+		fields["fullCombo"] = report.FullCombo
+	}
+	if _, ok := report.Confidence[FieldNotes]; ok {
+		n := report.Notes
+		for key, count := range map[string]int{"totalNotes": n.Total, "perfectHigh": n.PerfectHigh, "perfect": n.Perfect, "great": n.Great, "good": n.Good, "miss": n.Miss} {
+			fields[key] = count
+		}
+	}
+	return fields
+}
+
+// FieldMismatch describes an expected field that the analyzer got wrong or did not extract.
+type FieldMismatch struct {
+	Field    string
+	Expected any
+	Actual   any
+}
+
+// CompareFields checks every expected field against the actual fields.
+// It returns the names of the matching fields and the mismatches, both sorted by field.
+func CompareFields(expected map[string]any, actual map[string]any) ([]string, []FieldMismatch) {
+	var matched []string
+	var mismatches []FieldMismatch
+	for field, want := range expected {
+		got, ok := actual[field]
+		if ok && fieldEqual(want, got) {
+			matched = append(matched, field)
+			continue
+		}
+		mismatches = append(mismatches, FieldMismatch{Field: field, Expected: want, Actual: got})
+	}
+	sort.Strings(matched)
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Field < mismatches[j].Field })
+	return matched, mismatches
+}
+
+// fieldEqual compares a JSON-decoded expected value with an extracted one.
+func fieldEqual(expected any, actual any) bool {
+	want, wantNumber := toFloat(expected)
+	got, gotNumber := toFloat(actual)
+	if wantNumber && gotNumber {
+		return math.Abs(want-got) <= goldenTolerance
+	}
+	return expected == actual
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
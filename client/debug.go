@@ -0,0 +1,287 @@
+package client
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// debugPointRadius is the half size of the box drawn and cropped around point ROIs.
+const debugPointRadius = 6
+
+// Region is a named ROI mapped into a screenshot.
+type Region struct {
+	Name string
+	Rect image.Rectangle
+}
+
+// DebugRecorder collects what the analyzer saw during one analysis,
+// so it can be written as a bundle and attached to a bug report.
+type DebugRecorder struct {
+	img          image.Image
+	config       *Config
+	targetConfig ROIConfig
+	layout       Layout
+	values       map[string]any
+}
+
+// NewDebugRecorder returns an empty recorder to pass in AnalyzeOptions.
+func NewDebugRecorder() *DebugRecorder {
+	return &DebugRecorder{values: make(map[string]any)}
+}
+
+// capture records the screenshot before anything is analyzed,
+// so the bundle holds it even when the screen cannot be located.
+func (d *DebugRecorder) capture(img image.Image, config *Config) {
+	if d == nil {
+		return
+	}
+	d.img = img
+	d.config = config
+	d.values["screenSize"] = ScreenSize{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}.String()
+}
+
+// observe records the layout the ROIs are mapped with.
+func (d *DebugRecorder) observe(targetConfig ROIConfig, layout Layout) {
+	if d == nil {
+		return
+	}
+	d.targetConfig = targetConfig
+	d.layout = layout
+	d.values["roiConfig"] = targetConfig.ScreenSize
+	d.values["viewport"] = layout.Viewport.String()
+}
+
+// set records a value for the JSON summary.
+func (d *DebugRecorder) set(key string, value any) {
+	if d == nil {
+		return
+	}
+	d.values[key] = value
+}
+
+// finish records the outcome of the analysis.
+func (d *DebugRecorder) finish(report AnalysisReport, err error) {
+	if d == nil {
+		return
+	}
+	if err != nil {
+		d.values["error"] = err.Error()
+		return
+	}
+	d.values["screenType"] = report.ScreenType.String()
+	d.values["song"] = map[string]any{"id": report.SongObject.ID, "title": report.SongObject.Title}
+	d.values["pattern"] = report.PatternObject
+	d.values["confidence"] = report.Confidence
+	d.values["warnings"] = report.Warnings
+	d.values["disabledChecks"] = report.DisabledChecks
+}
+
+// anchorDistances returns the pHash distance of every configured screen anchor.
+func (d *DebugRecorder) anchorDistances() map[string]any {
+	anchors := []screenAnchor{
+		{SelectPopupScreen, d.targetConfig.Select.PopupAnchor, d.config.SelectPopupAnchorPHash},
+		{PauseScreen, d.targetConfig.Pause.Anchor, d.config.PauseAnchorPHash},
+		{SelectScreen, d.targetConfig.Select.SpeedWidget, d.config.SpeedWidgetPHash},
+		{ResultScreen, d.targetConfig.Result.Anchor, d.config.ResultAnchorPHash},
+	}
+	distances := make(map[string]any)
+	for _, anchor := range anchors {
+		if anchor.pHash == "" || len(anchor.coords) == 0 {
+			continue
+		}
+		distance, err := anchorDistance(d.img, anchor, d.layout)
+		if err != nil {
+			distances[anchor.screenType.String()] = err.Error()
+			continue
+		}
+		distances[anchor.screenType.String()] = distance
+	}
+	return distances
+}
+
+// WriteBundle writes a zip file with the screenshot annotated with every ROI,
+// a PNG of every cropped ROI and a JSON summary of hashes and distances.
+func (d *DebugRecorder) WriteBundle(path string) error {
+	if d.img == nil {
+		return fmt.Errorf("no screenshot recorded")
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating debug bundle: %v", err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	if err := d.writeBundle(archive); err != nil {
+		archive.Close()
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("error writing debug bundle: %v", err)
+	}
+	return nil
+}
+
+// writeBundle adds the files of the bundle to the archive.
+func (d *DebugRecorder) writeBundle(archive *zip.Writer) error {
+	regions := Regions(d.targetConfig, d.layout)
+
+	writer, err := archive.Create("overlay.png")
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(writer, drawRegions(d.img, regions)); err != nil {
+		return fmt.Errorf("error writing overlay: %v", err)
+	}
+
+	for _, region := range regions {
+		rect := region.Rect.Intersect(d.img.Bounds())
+		if rect.Empty() {
+			continue
+		}
+		cropped, err := cropImage(d.img, rect)
+		if err != nil {
+			return err
+		}
+		writer, err := archive.Create("rois/" + region.Name + ".png")
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(writer, cropped); err != nil {
+			return fmt.Errorf("error writing %s: %v", region.Name, err)
+		}
+	}
+
+	d.values["anchors"] = d.anchorDistances()
+	writer, err = archive.Create("analysis.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(d.values); err != nil {
+		return fmt.Errorf("error writing JSON: %v", err)
+	}
+	return nil
+}
+
+// DebugBundlePath returns a new path for a debug bundle in the cache directory.
+func DebugBundlePath() string {
+	dir := filepath.Join(getCacheDirectory(), "debug")
+	os.MkdirAll(dir, os.ModeDir)
+	return filepath.Join(dir, fmt.Sprintf("analysis-%s.zip", time.Now().Format("20060102-150405")))
+}
+
+// Regions maps every ROI of the select and result configs into the screenshot.
+// Note rows are combined with the X range of the notes area, and points become small boxes.
+func Regions(cfg ROIConfig, layout Layout) []Region {
+	var regions []Region
+	add := func(name string, coords []int) {
+		switch len(coords) {
+		case 4:
+			if rect, err := layout.Rect(coords); err == nil && !rect.Empty() {
+				regions = append(regions, Region{name, rect})
+			}
+		case 2:
+			p := layout.Point(coords[0], coords[1])
+			regions = append(regions, Region{name, image.Rect(p.X-debugPointRadius, p.Y-debugPointRadius, p.X+debugPointRadius, p.Y+debugPointRadius)})
+		}
+	}
+	addRow := func(name string, rows []int) {
+		if len(rows) != 2 || len(cfg.Result.NotesArea) != 4 {
+			return
+		}
+		add(name, []int{cfg.Result.NotesArea[0], rows[0], cfg.Result.NotesArea[2], rows[1]})
+	}
+
+	s := cfg.Select
+	add("select.speedWidget", s.SpeedWidget)
+	add("select.popupAnchor", s.PopupAnchor)
+	add("select.jacket", s.Jacket)
+	add("select.majorJudge", s.MajorJudge)
+	add("select.minorJudge", s.MinorJudge)
+	add("select.line", s.Line)
+	add("select.level", s.Level)
+	add("select.majorPatch", s.MajorPatch)
+	add("select.minorPatch", s.MinorPatch)
+	add("select.score", s.Score)
+	add("select.fullCombo", s.FullCombo)
+	add("select.maxPatch", s.MaxPatch)
+	add("select.rank", s.Rank)
+
+	r := cfg.Result
+	add("result.anchor", r.Anchor)
+	add("result.jacket", r.Jacket)
+	add("result.judge", r.Judge)
+	add("result.line", r.Line)
+	add("result.level", r.Level)
+	add("result.patch", r.Patch)
+	add("result.score", r.Score)
+	add("result.rank", r.Rank)
+	addRow("result.totalNotes", r.TotalNotes)
+	addRow("result.perfectHigh", r.PerfectHigh)
+	addRow("result.perfect", r.Perfect)
+	addRow("result.great", r.Great)
+	addRow("result.good", r.Good)
+	addRow("result.miss", r.Miss)
+	add("result.difficulty", r.Difficulty)
+	add("result.fullCombo", r.FullCombo)
+
+	add("pause.anchor", cfg.Pause.Anchor)
+	return regions
+}
+
+// drawRegions returns a copy of img with every region outlined and labelled.
+// Select ROIs are drawn in cyan and result ROIs in magenta.
+func drawRegions(img image.Image, regions []Region) *image.RGBA {
+	canvas := image.NewRGBA(img.Bounds())
+	draw.Draw(canvas, canvas.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	for _, region := range regions {
+		c := color.RGBA{255, 0, 255, 255}
+		if strings.HasPrefix(region.Name, "select.") {
+			c = color.RGBA{0, 255, 255, 255}
+		}
+		outline(canvas, region.Rect, c)
+		label(canvas, region.Rect.Min.X+3, region.Rect.Min.Y+13, region.Name, c)
+	}
+	return canvas
+}
+
+func outline(canvas *image.RGBA, rect image.Rectangle, c color.Color) {
+	const width = 2
+	src := image.NewUniform(c)
+	draw.Draw(canvas, image.Rect(rect.Min.X, rect.Min.Y, rect.Max.X, rect.Min.Y+width), src, image.Point{}, draw.Src)
+	draw.Draw(canvas, image.Rect(rect.Min.X, rect.Max.Y-width, rect.Max.X, rect.Max.Y), src, image.Point{}, draw.Src)
+	draw.Draw(canvas, image.Rect(rect.Min.X, rect.Min.Y, rect.Min.X+width, rect.Max.Y), src, image.Point{}, draw.Src)
+	draw.Draw(canvas, image.Rect(rect.Max.X-width, rect.Min.Y, rect.Max.X, rect.Max.Y), src, image.Point{}, draw.Src)
+}
+
+// label draws text on a black background so it stays readable on any screenshot.
+func label(canvas *image.RGBA, x int, y int, text string, c color.Color) {
+	face := basicfont.Face7x13
+	width := font.MeasureString(face, text).Ceil()
+	draw.Draw(canvas, image.Rect(x-1, y-11, x+width+1, y+3), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	drawer := &font.Drawer{
+		Dst:  canvas,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	drawer.DrawString(text)
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"image"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegions(t *testing.T) {
+	cfg := ROIConfig{
+		Select: SelectROIConfig{SpeedWidget: []int{30, 908, 119, 932}, MaxPatch: []int{1033, 726}},
+		Result: ResultROIConfig{
+			NotesArea: []int{874, 0, 950, 0},
+			Miss:      []int{800, 828},
+		},
+	}
+	regions := Regions(cfg, identityLayout(image.Rect(0, 0, 1920, 1080)))
+
+	expected := map[string]image.Rectangle{
+		"select.speedWidget": image.Rect(30, 908, 119, 932),
+		"select.maxPatch":    image.Rect(1033-debugPointRadius, 726-debugPointRadius, 1033+debugPointRadius, 726+debugPointRadius),
+		"result.miss":        image.Rect(874, 800, 950, 828),
+	}
+	if len(regions) != len(expected) {
+		t.Fatalf("expected %d regions, got %v", len(expected), regions)
+	}
+	for _, region := range regions {
+		if rect, ok := expected[region.Name]; !ok || rect != region.Rect {
+			t.Errorf("unexpected region %s %v", region.Name, region.Rect)
+		}
+	}
+}
+
+func TestDebugBundle(t *testing.T) {
+	resultImg, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+	// No song is known, so the analysis fails after the screen type is decided
+	recorder := NewDebugRecorder()
+	if _, err := AnalyzeImage(resultImg, &Cache{}, calibrationConfig, AnalyzeOptions{Debug: recorder}); err == nil {
+		t.Fatal("expected analysis without songs to fail")
+	}
+
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	if err := recorder.WriteBundle(path); err != nil {
+		t.Fatalf("WriteBundle failed: %v", err)
+	}
+
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	defer reader.Close()
+
+	files := make(map[string]*zip.File)
+	for _, f := range reader.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"overlay.png", "analysis.json", "rois/result.jacket.png", "rois/select.speedWidget.png"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected %s in bundle", name)
+		}
+	}
+
+	rc, err := files["analysis.json"].Open()
+	if err != nil {
+		t.Fatalf("failed to open analysis.json: %v", err)
+	}
+	defer rc.Close()
+	var values map[string]any
+	if err := json.NewDecoder(rc).Decode(&values); err != nil {
+		t.Fatalf("failed to parse analysis.json: %v", err)
+	}
+	if values["error"] == nil {
+		t.Error("expected the analysis error to be recorded")
+	}
+	anchors, ok := values["anchors"].(map[string]any)
+	if !ok || anchors["result"] != float64(0) {
+		t.Errorf("expected result anchor distance 0, got %v", values["anchors"])
+	}
+}
+
+func TestDebugBundleBeforeLocating(t *testing.T) {
+	img, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+	// Without ROI configs the screen cannot be located, but the screenshot is still recorded
+	recorder := NewDebugRecorder()
+	if _, err := AnalyzeImage(img, &Cache{}, &Config{}, AnalyzeOptions{Debug: recorder}); err == nil {
+		t.Fatal("expected analysis without ROI configs to fail")
+	}
+
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	if err := recorder.WriteBundle(path); err != nil {
+		t.Fatalf("WriteBundle failed: %v", err)
+	}
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	defer reader.Close()
+	names := make(map[string]bool)
+	for _, f := range reader.File {
+		names[f.Name] = true
+	}
+	if !names["overlay.png"] || !names["analysis.json"] {
+		t.Errorf("expected the screenshot and summary in bundle, got %v", names)
+	}
+}
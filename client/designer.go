@@ -0,0 +1,107 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LevelCount is the number of patterns of a level.
+type LevelCount struct {
+	Level int
+	Count int
+}
+
+// DesignerStats aggregates the patterns of a chart designer with the player's bests on them.
+type DesignerStats struct {
+	Designer string
+	Patterns int
+	// Levels is the level distribution of the patterns, lowest level first.
+	Levels []LevelCount
+	Played int
+	// AverageJudge and AveragePatch are over the played patterns.
+	AverageJudge float64
+	AveragePatch float64
+	// JudgeGap is the average difference between the judge on each played pattern and the player's
+	// average judge on all played patterns of its level. Negative means weaker than usual at the same level.
+	JudgeGap float64
+}
+
+// Distribution describes the level distribution, e.g. "Lv.9×2 Lv.11×1".
+func (d DesignerStats) Distribution() string {
+	parts := make([]string, len(d.Levels))
+	for i, l := range d.Levels {
+		parts[i] = fmt.Sprintf("Lv.%d×%d", l.Level, l.Count)
+	}
+	return strings.Join(parts, " ")
+}
+
+// DesignerStatistics groups the patterns of the cache passing the filter by designer.
+// Designers with played patterns come first, weakest JudgeGap first; the others follow by name.
+func DesignerStatistics(cache *Cache, archives []Archive, filter PatternFilter) []DesignerStats {
+	bests := make(map[PatternKey]Archive)
+	for _, a := range BestArchives(archives) {
+		bests[ArchiveKey(a)] = a
+	}
+
+	type played struct {
+		level int
+		judge float64
+	}
+	byDesigner := make(map[string]*DesignerStats)
+	levels := make(map[string]map[int]int)
+	plays := make(map[string][]played)
+	levelJudges := make(map[int][]float64)
+	for _, p := range cache.Patterns {
+		song, _ := cache.FindSong(p.SongID)
+		if !filter.Matches(song, p) {
+			continue
+		}
+		d, ok := byDesigner[p.Designer]
+		if !ok {
+			d = &DesignerStats{Designer: p.Designer}
+			byDesigner[p.Designer] = d
+			levels[p.Designer] = make(map[int]int)
+		}
+		d.Patterns++
+		levels[p.Designer][p.Level]++
+
+		best, ok := bests[PatternKey{p.SongID, p.Line, p.Difficulty}]
+		if !ok {
+			continue
+		}
+		d.Played++
+		d.AverageJudge += best.Judge
+		d.AveragePatch += best.Patch
+		plays[p.Designer] = append(plays[p.Designer], played{p.Level, best.Judge})
+		levelJudges[p.Level] = append(levelJudges[p.Level], best.Judge)
+	}
+
+	stats := make([]DesignerStats, 0, len(byDesigner))
+	for name, d := range byDesigner {
+		for level, count := range levels[name] {
+			d.Levels = append(d.Levels, LevelCount{level, count})
+		}
+		sort.Slice(d.Levels, func(i, j int) bool { return d.Levels[i].Level < d.Levels[j].Level })
+		if d.Played > 0 {
+			d.AverageJudge /= float64(d.Played)
+			d.AveragePatch /= float64(d.Played)
+			for _, p := range plays[name] {
+				d.JudgeGap += p.judge - average(levelJudges[p.level])
+			}
+			d.JudgeGap /= float64(d.Played)
+		}
+		stats = append(stats, *d)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if (a.Played > 0) != (b.Played > 0) {
+			return a.Played > 0
+		}
+		if a.Played > 0 && a.JudgeGap != b.JudgeGap {
+			return a.JudgeGap < b.JudgeGap
+		}
+		return a.Designer < b.Designer
+	})
+	return stats
+}
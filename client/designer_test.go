@@ -0,0 +1,48 @@
+package client
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDesignerStatistics(t *testing.T) {
+	cache := &Cache{
+		Songs: []Song{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}},
+		Patterns: []Pattern{
+			{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10, Designer: "Alpha"},
+			{SongID: 2, Line: 4, Difficulty: "HARD", Level: 10, Designer: "Beta"},
+			{SongID: 3, Line: 4, Difficulty: "HARD", Level: 12, Designer: "Alpha"},
+			{SongID: 3, Line: 6, Difficulty: "HARD", Level: 12, Designer: "Alpha"},
+			{SongID: 4, Line: 4, Difficulty: "HARD", Level: 8, Designer: "Gamma"},
+		},
+	}
+	archives := []Archive{
+		{SongID: 1, Line: 4, Difficulty: "HARD", Judge: 96, Patch: 400},
+		{SongID: 2, Line: 4, Difficulty: "HARD", Judge: 99, Patch: 450},
+		{SongID: 3, Line: 4, Difficulty: "HARD", Judge: 95, Patch: 420},
+	}
+
+	stats := DesignerStatistics(cache, archives, PatternFilter{})
+	if len(stats) != 3 || stats[0].Designer != "Alpha" || stats[1].Designer != "Beta" || stats[2].Designer != "Gamma" {
+		t.Fatalf("expected the weakest designer first and the unplayed one last, got %+v", stats)
+	}
+	alpha := stats[0]
+	if alpha.Patterns != 3 || alpha.Played != 2 || alpha.Distribution() != "Lv.10×1 Lv.12×2" {
+		t.Errorf("unexpected counts %+v", alpha)
+	}
+	if alpha.AverageJudge != 95.5 || alpha.AveragePatch != 410 {
+		t.Errorf("unexpected averages %+v", alpha)
+	}
+	// 96 against the level 10 average of 97.5, and 95 against the level 12 average of 95
+	if math.Abs(alpha.JudgeGap+0.75) > 1e-9 || math.Abs(stats[1].JudgeGap-1.5) > 1e-9 {
+		t.Errorf("unexpected judge gaps %v %v", alpha.JudgeGap, stats[1].JudgeGap)
+	}
+	if stats[2].Played != 0 || stats[2].AverageJudge != 0 {
+		t.Errorf("expected no averages for an unplayed designer, got %+v", stats[2])
+	}
+
+	stats = DesignerStatistics(cache, archives, PatternFilter{Lines: []int{6}})
+	if len(stats) != 1 || stats[0].Patterns != 1 || stats[0].Played != 0 {
+		t.Errorf("expected only the 6 line pattern, got %+v", stats)
+	}
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// difficultySampleRadius is the half size of the box averaged around the difficulty colour point.
+const difficultySampleRadius = 2
+
+// averageColor returns the mean 8-bit RGB colour of the box of the given radius around p.
+func averageColor(img image.Image, p image.Point, radius int) [3]float64 {
+	var sum [3]float64
+	count := 0
+	for y := p.Y - radius; y <= p.Y+radius; y++ {
+		for x := p.X - radius; x <= p.X+radius; x++ {
+			if !(image.Point{X: x, Y: y}).In(img.Bounds()) {
+				continue
+			}
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum[0] += float64(r >> 8)
+			sum[1] += float64(g >> 8)
+			sum[2] += float64(b >> 8)
+			count++
+		}
+	}
+	if count == 0 {
+		return sum
+	}
+	for i := range sum {
+		sum[i] /= float64(count)
+	}
+	return sum
+}
+
+func colorDistance(a [3]float64, b []int) float64 {
+	if len(b) != 3 {
+		return math.Inf(1)
+	}
+	dr := a[0] - float64(b[0])
+	dg := a[1] - float64(b[1])
+	db := a[2] - float64(b[2])
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+// classifyDifficulty reads the difficulty from the colour at the given reference point.
+// The returned confidence drops as the sample moves away from the nearest colour,
+// and as it gets as close to the second nearest colour as to the nearest one.
+func classifyDifficulty(img image.Image, coords []int, layout Layout, config *Config) (string, float64, error) {
+	if len(coords) != 2 {
+		return "", 0, fmt.Errorf("expected 2 difficulty coordinates, got %d", len(coords))
+	}
+	sample := averageColor(img, layout.Point(coords[0], coords[1]), difficultySampleRadius)
+
+	colors := []struct {
+		difficulty string
+		rgb        []int
+	}{
+		{"EASY", config.DifficultyColors.Easy},
+		{"HARD", config.DifficultyColors.Hard},
+		{"OVER", config.DifficultyColors.Over},
+		{"PLUS", config.DifficultyColors.Plus},
+	}
+	best, second := math.Inf(1), math.Inf(1)
+	difficulty := ""
+	for _, c := range colors {
+		distance := colorDistance(sample, c.rgb)
+		if distance < best {
+			best, second = distance, best
+			difficulty = c.difficulty
+		} else if distance < second {
+			second = distance
+		}
+	}
+	if difficulty == "" {
+		return "", 0, fmt.Errorf("no difficulty colors configured")
+	}
+
+	// ColorTolerance is a per-channel tolerance, so scale it to an RGB distance
+	tolerance := float64(max(config.ColorTolerance, 1)) * math.Sqrt(3)
+	if best > 2*tolerance {
+		return "", 0, fmt.Errorf("difficulty color (%.0f, %.0f, %.0f) does not match any difficulty", sample[0], sample[1], sample[2])
+	}
+	confidence := math.Min(distanceConfidence(best, tolerance), marginConfidence(second-best, second+best))
+	return difficulty, confidence, nil
+}
@@ -0,0 +1,490 @@
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Size every glyph is normalised to before it is compared with the templates.
+const (
+	glyphWidth  = 12
+	glyphHeight = 18
+)
+
+// glyphMinArea is the smallest number of pixels a connected component needs to be kept.
+const glyphMinArea = 3
+
+// glyphMergeOverlap is the horizontal overlap, as a fraction of the narrower component,
+// above which components are merged into one glyph. It joins the parts of "%".
+const glyphMergeOverlap = 0.5
+
+// Components wider than glyphSplitAspect times their height are touching glyphs.
+// They are split into parts about glyphDigitAspect times as wide as they are high.
+const (
+	glyphSplitAspect = 1.4
+	glyphDigitAspect = 0.8
+)
+
+// Weights of the shape features added to the pixel distance between a glyph and a template.
+const (
+	glyphAspectWeight = 0.25
+	glyphHeightWeight = 0.25
+)
+
+// glyphSizeStep is the relative height step by which training samples are grouped into separate templates.
+const glyphSizeStep = 0.1
+
+// glyphDistanceThreshold is the largest glyph distance read with more than LowConfidence.
+const glyphDistanceThreshold = 0.2
+
+// DigitTemplates is a trained set of glyph templates for the number fields of the game.
+type DigitTemplates struct {
+	// Version names the game version the templates were trained on.
+	Version string          `json:"version"`
+	Width   int             `json:"width"`
+	Height  int             `json:"height"`
+	Glyphs  []GlyphTemplate `json:"glyphs"`
+}
+
+// GlyphTemplate is the average of the training samples of one character in one field.
+// The digit templates of every field form one shared set that all fields are read with,
+// while other characters, such as the decimal point or "%", are only read in their own field.
+type GlyphTemplate struct {
+	Char  string `json:"char"`
+	Field string `json:"field"`
+	// Pixels holds one byte of coverage per pixel, row by row, hex-encoded.
+	Pixels  string  `json:"pixels"`
+	Aspect  float64 `json:"aspect"`
+	Height  float64 `json:"height"`
+	Samples int     `json:"samples"`
+
+	coverage []float64
+}
+
+// glyph is a character segmented from a ROI.
+type glyph struct {
+	rect     image.Rectangle
+	coverage []float64
+	// aspect is the width over the height of the glyph.
+	aspect float64
+	// height is relative to the tallest glyph of the ROI.
+	height float64
+}
+
+// segmentGlyphs splits the text of a ROI into glyphs ordered from left to right.
+// The ROI is binarised with Otsu's threshold, and the text is the class the border mostly does not belong to.
+// Components spanning the full height or width of the ROI are borders and underlines, not text, and are dropped.
+func segmentGlyphs(img image.Image, rect image.Rectangle) []glyph {
+	rect = rect.Intersect(img.Bounds())
+	if rect.Empty() {
+		return nil
+	}
+	width, height := rect.Dx(), rect.Dy()
+	gray := make([]uint8, width*height)
+	var histogram [256]int
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(rect.Min.X+x, rect.Min.Y+y).RGBA()
+			v := uint8((299*(r>>8) + 587*(g>>8) + 114*(b>>8)) / 1000)
+			gray[y*width+x] = v
+			histogram[v]++
+		}
+	}
+	threshold := otsuThreshold(histogram[:], len(gray))
+	// The background is the class most of the ROI border belongs to
+	dark, border := 0, 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x != 0 && y != 0 && x != width-1 && y != height-1 {
+				continue
+			}
+			if gray[y*width+x] <= threshold {
+				dark++
+			}
+			border++
+		}
+	}
+	darkText := dark*2 < border
+	foreground := make([]bool, len(gray))
+	for i, v := range gray {
+		foreground[i] = (v <= threshold) == darkText
+	}
+
+	components := connectedComponents(foreground, width, height)
+	var kept []component
+	for _, c := range components {
+		spansHeight := c.rect.Min.Y == 0 && c.rect.Max.Y == height
+		spansWidth := c.rect.Min.X == 0 && c.rect.Max.X == width
+		if len(c.pixels) < glyphMinArea || spansHeight || spansWidth {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	var merged []component
+	for _, c := range mergeComponents(kept) {
+		merged = append(merged, splitComponent(c)...)
+	}
+
+	tallest := 0
+	for _, c := range merged {
+		tallest = max(tallest, c.rect.Dy())
+	}
+	glyphs := make([]glyph, len(merged))
+	for i, c := range merged {
+		glyphs[i] = glyph{
+			rect:     c.rect.Add(rect.Min),
+			coverage: normaliseGlyph(c),
+			aspect:   float64(c.rect.Dx()) / float64(c.rect.Dy()),
+			height:   float64(c.rect.Dy()) / float64(tallest),
+		}
+	}
+	return glyphs
+}
+
+// otsuThreshold returns the grey level that best separates the histogram into two classes.
+func otsuThreshold(histogram []int, total int) uint8 {
+	sum := 0.0
+	for i, count := range histogram {
+		sum += float64(i * count)
+	}
+	var sumBackground, weightBackground float64
+	best, threshold := -1.0, 0
+	for i, count := range histogram {
+		weightBackground += float64(count)
+		if weightBackground == 0 {
+			continue
+		}
+		weightForeground := float64(total) - weightBackground
+		if weightForeground == 0 {
+			break
+		}
+		sumBackground += float64(i * count)
+		meanBackground := sumBackground / weightBackground
+		meanForeground := (sum - sumBackground) / weightForeground
+		between := weightBackground * weightForeground * (meanBackground - meanForeground) * (meanBackground - meanForeground)
+		if between > best {
+			best, threshold = between, i
+		}
+	}
+	return uint8(threshold)
+}
+
+type component struct {
+	rect   image.Rectangle
+	pixels []image.Point
+}
+
+// connectedComponents labels the 8-connected foreground regions of a mask.
+func connectedComponents(mask []bool, width int, height int) []component {
+	visited := make([]bool, len(mask))
+	var components []component
+	for start := range mask {
+		if !mask[start] || visited[start] {
+			continue
+		}
+		visited[start] = true
+		stack := []int{start}
+		var c component
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			p := image.Pt(i%width, i/width)
+			c.pixels = append(c.pixels, p)
+			c.rect = c.rect.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					x, y := p.X+dx, p.Y+dy
+					if x < 0 || y < 0 || x >= width || y >= height {
+						continue
+					}
+					j := y*width + x
+					if mask[j] && !visited[j] {
+						visited[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+		components = append(components, c)
+	}
+	return components
+}
+
+// mergeComponents joins components that overlap horizontally, and sorts them from left to right.
+func mergeComponents(components []component) []component {
+	sort.Slice(components, func(i, j int) bool { return components[i].rect.Min.X < components[j].rect.Min.X })
+	var merged []component
+	for _, c := range components {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			overlap := min(last.rect.Max.X, c.rect.Max.X) - max(last.rect.Min.X, c.rect.Min.X)
+			if float64(overlap) >= glyphMergeOverlap*float64(min(last.rect.Dx(), c.rect.Dx())) {
+				last.rect = last.rect.Union(c.rect)
+				last.pixels = append(last.pixels, c.pixels...)
+				continue
+			}
+		}
+		merged = append(merged, c)
+	}
+	return merged
+}
+
+// splitComponent cuts a component holding touching glyphs at the columns
+// with the fewest pixels near the evenly spaced cut positions.
+func splitComponent(c component) []component {
+	width, height := c.rect.Dx(), c.rect.Dy()
+	if float64(width) <= glyphSplitAspect*float64(height) {
+		return []component{c}
+	}
+	parts := int(math.Round(float64(width) / (glyphDigitAspect * float64(height))))
+	if parts < 2 {
+		return []component{c}
+	}
+	projection := make([]int, width)
+	for _, p := range c.pixels {
+		projection[p.X-c.rect.Min.X]++
+	}
+	cuts := []int{0}
+	window := width / (4 * parts)
+	for k := 1; k < parts; k++ {
+		target := k * width / parts
+		cut := target
+		for x := max(target-window, 1); x <= min(target+window, width-1); x++ {
+			if projection[x] < projection[cut] {
+				cut = x
+			}
+		}
+		cuts = append(cuts, cut)
+	}
+	cuts = append(cuts, width)
+
+	pieces := make([]component, parts)
+	for _, p := range c.pixels {
+		x := p.X - c.rect.Min.X
+		for k := 0; k < parts; k++ {
+			if x >= cuts[k] && x < cuts[k+1] {
+				pieces[k].pixels = append(pieces[k].pixels, p)
+				pieces[k].rect = pieces[k].rect.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+				break
+			}
+		}
+	}
+	var split []component
+	for _, piece := range pieces {
+		if len(piece.pixels) > 0 {
+			split = append(split, piece)
+		}
+	}
+	return split
+}
+
+// normaliseGlyph scales the pixels of a component to the glyph size.
+// Each cell holds the fraction of its source pixels that belong to the component.
+func normaliseGlyph(c component) []float64 {
+	width, height := c.rect.Dx(), c.rect.Dy()
+	mask := make([]bool, width*height)
+	for _, p := range c.pixels {
+		mask[(p.Y-c.rect.Min.Y)*width+p.X-c.rect.Min.X] = true
+	}
+	coverage := make([]float64, glyphWidth*glyphHeight)
+	for gy := 0; gy < glyphHeight; gy++ {
+		y0, y1 := gy*height/glyphHeight, max((gy+1)*height/glyphHeight, gy*height/glyphHeight+1)
+		for gx := 0; gx < glyphWidth; gx++ {
+			x0, x1 := gx*width/glyphWidth, max((gx+1)*width/glyphWidth, gx*width/glyphWidth+1)
+			filled, total := 0, 0
+			for y := y0; y < min(y1, height); y++ {
+				for x := x0; x < min(x1, width); x++ {
+					if mask[y*width+x] {
+						filled++
+					}
+					total++
+				}
+			}
+			if total > 0 {
+				coverage[gy*glyphWidth+gx] = float64(filled) / float64(total)
+			}
+		}
+	}
+	return coverage
+}
+
+// distance compares a glyph with a template. It is 0 for identical shapes.
+func (t *GlyphTemplate) distance(g glyph) float64 {
+	d := 0.0
+	for i, v := range g.coverage {
+		d += math.Abs(v - t.coverage[i])
+	}
+	d /= float64(len(g.coverage))
+	return d + glyphAspectWeight*math.Abs(g.aspect-t.Aspect) + glyphHeightWeight*math.Abs(g.height-t.Height)
+}
+
+// classify returns the character of the closest template and the confidence of the choice.
+// Digit templates are used for every field, and other templates only for their own field.
+// The confidence compares the best distance with the closest template of another character.
+func (d *DigitTemplates) classify(g glyph, field string) (string, float64) {
+	best := math.Inf(1)
+	char := ""
+	distances := make([]float64, len(d.Glyphs))
+	for i := range d.Glyphs {
+		t := &d.Glyphs[i]
+		distances[i] = math.Inf(1)
+		if !isDigit(t.Char) && t.Field != field {
+			continue
+		}
+		distances[i] = t.distance(g)
+		if distances[i] < best {
+			best, char = distances[i], t.Char
+		}
+	}
+	second := math.Inf(1)
+	for i, distance := range distances {
+		if d.Glyphs[i].Char != char {
+			second = math.Min(second, distance)
+		}
+	}
+	return char, math.Min(distanceConfidence(best, glyphDistanceThreshold), marginConfidence(second-best, glyphDistanceThreshold))
+}
+
+// isDigit reports whether char is a single decimal digit.
+func isDigit(char string) bool {
+	return len(char) == 1 && char[0] >= '0' && char[0] <= '9'
+}
+
+// ReadText reads the characters in the ROI of a field, named as in the templates.
+// The confidence is the lowest of any glyph.
+func (d *DigitTemplates) ReadText(img image.Image, rect image.Rectangle, field string) (string, float64, error) {
+	if len(d.Glyphs) == 0 {
+		return "", 0, fmt.Errorf("no digit templates loaded")
+	}
+	glyphs := segmentGlyphs(img, rect)
+	if len(glyphs) == 0 {
+		return "", 0, fmt.Errorf("no text found in %v", rect)
+	}
+	var text strings.Builder
+	confidence := 1.0
+	for _, g := range glyphs {
+		char, c := d.classify(g, field)
+		text.WriteString(char)
+		confidence = math.Min(confidence, c)
+	}
+	return text.String(), confidence, nil
+}
+
+// decode unpacks the pixels of every template after loading.
+func (d *DigitTemplates) decode() error {
+	if d.Width != glyphWidth || d.Height != glyphHeight {
+		return fmt.Errorf("templates are %dx%d, expected %dx%d", d.Width, d.Height, glyphWidth, glyphHeight)
+	}
+	for i := range d.Glyphs {
+		data, err := hex.DecodeString(d.Glyphs[i].Pixels)
+		if err != nil || len(data) != glyphWidth*glyphHeight {
+			return fmt.Errorf("invalid pixels for %q", d.Glyphs[i].Char)
+		}
+		d.Glyphs[i].coverage = make([]float64, len(data))
+		for j, b := range data {
+			d.Glyphs[i].coverage[j] = float64(b) / 255
+		}
+	}
+	return nil
+}
+
+// ReadDigitTemplates parses a template set.
+func ReadDigitTemplates(r io.Reader) (*DigitTemplates, error) {
+	var templates DigitTemplates
+	if err := json.NewDecoder(r).Decode(&templates); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %v", err)
+	}
+	if err := templates.decode(); err != nil {
+		return nil, err
+	}
+	return &templates, nil
+}
+
+// Write saves the template set as JSON.
+func (d *DigitTemplates) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+// LoadDigitTemplates loads the template set referenced by Config.DigitTemplates.
+// URLs are downloaded into the cache directory, and the cached copy is used when the download fails.
+// Relative paths are resolved against the cache directory.
+func LoadDigitTemplates(config *Config) (*DigitTemplates, error) {
+	source := config.DigitTemplates
+	if source == "" {
+		return nil, fmt.Errorf("no digit templates configured")
+	}
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(getCacheDirectory(), source)
+		}
+		return loadDigitTemplatesFrom(source)
+	}
+
+	os.MkdirAll(getCacheDirectory(), os.ModeDir)
+	cachePath := filepath.Join(getCacheDirectory(), "digits.json")
+	if err := downloadDigitTemplates(source, cachePath); err != nil && !fileExists(cachePath) {
+		return nil, fmt.Errorf("error downloading digit templates: %v", err)
+	}
+	return loadDigitTemplatesFrom(cachePath)
+}
+
+func loadDigitTemplatesFrom(path string) (*DigitTemplates, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening digit templates: %v", err)
+	}
+	defer file.Close()
+	return ReadDigitTemplates(file)
+}
+
+// downloadTimeout bounds a template download, so a stalled server does not block analysis.
+const downloadTimeout = 30 * time.Second
+
+// downloadDigitTemplates saves the template set at url to path.
+// The body is parsed before anything is written, and path is replaced by a rename,
+// so a failed or malformed download leaves the cached copy untouched.
+func downloadDigitTemplates(url string, path string) error {
+	client := &http.Client{Timeout: downloadTimeout}
+	res, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %s", res.Status)
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if _, err := ReadDigitTemplates(bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"bytes"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOtsuThreshold(t *testing.T) {
+	histogram := make([]int, 256)
+	histogram[40] = 100
+	histogram[200] = 300
+	threshold := otsuThreshold(histogram, 400)
+	if threshold < 40 || threshold >= 200 {
+		t.Errorf("expected a threshold between the two peaks, got %d", threshold)
+	}
+}
+
+func TestSegmentGlyphsSplitsTouchingDigits(t *testing.T) {
+	img, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+	// The four zeros of the miss count touch each other
+	glyphs := segmentGlyphs(img, image.Rect(874, 800, 950, 828))
+	if len(glyphs) != 4 {
+		t.Fatalf("expected 4 glyphs, got %d", len(glyphs))
+	}
+	for i := 1; i < len(glyphs); i++ {
+		if glyphs[i].rect.Min.X < glyphs[i-1].rect.Min.X {
+			t.Errorf("glyphs are not ordered from left to right: %v", glyphs)
+		}
+	}
+	// The judge ends with "%", whose three parts are one glyph
+	if glyphs := segmentGlyphs(img, image.Rect(959, 301, 1283, 367)); len(glyphs) != len("99.8829%") {
+		t.Errorf("expected %d judge glyphs, got %d", len("99.8829%"), len(glyphs))
+	}
+}
+
+// TestDigitTemplatesRoundTrip trains on the select screen and reads the result screen,
+// whose fields show no samples of their own.
+func TestDigitTemplatesRoundTrip(t *testing.T) {
+	train, err := loadImage("../testing/select.png")
+	if err != nil {
+		t.Fatalf("failed to load select.png: %v", err)
+	}
+	labels := map[string]any{"judge": 99.9158, "score": 209500.0, "patch": 881.25, "line": 4.0}
+	config, _ := loadGoldenInputs(t, "../testing")
+	samples, skipped, err := ExtractGlyphSamples(train, config, labels)
+	if err != nil {
+		t.Fatalf("ExtractGlyphSamples failed: %v", err)
+	}
+	if len(skipped) > 0 {
+		t.Fatalf("unexpected skipped fields: %v", skipped)
+	}
+
+	var buf bytes.Buffer
+	if err := TrainDigitTemplates(samples, "test").Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	templates, err := ReadDigitTemplates(&buf)
+	if err != nil {
+		t.Fatalf("ReadDigitTemplates failed: %v", err)
+	}
+	img, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+	// The line is drawn as on the select screen
+	text, confidence, err := templates.ReadText(img, image.Rect(37, 32, 75, 81), "result.line")
+	if err != nil {
+		t.Fatalf("ReadText failed: %v", err)
+	}
+	if text != "4" {
+		t.Errorf("expected 4, got %q", text)
+	}
+	if confidence < LowConfidence {
+		t.Errorf("expected a confident read, got %.2f", confidence)
+	}
+	// The level uses a font the select screen does not show, so the read must not be trusted
+	text, confidence, err = templates.ReadText(img, image.Rect(395, 700, 502, 762), "result.level")
+	if err != nil {
+		t.Fatalf("ReadText failed: %v", err)
+	}
+	if text != "18" && confidence >= LowConfidence {
+		t.Errorf("expected a misread level %q to have low confidence, got %.2f", text, confidence)
+	}
+}
+
+func TestDownloadDigitTemplatesKeepsCacheOnBadBody(t *testing.T) {
+	good, err := os.ReadFile("../testing/digits.json")
+	if err != nil {
+		t.Fatalf("failed to read digits.json: %v", err)
+	}
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "digits.json")
+	if err := os.WriteFile(path, good, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range [][]byte{[]byte("<html>502 Bad Gateway</html>"), good[:len(good)/2]} {
+		body = bad
+		if err := downloadDigitTemplates(server.URL, path); err == nil {
+			t.Errorf("expected a malformed body to be rejected")
+		}
+		if data, _ := os.ReadFile(path); !bytes.Equal(data, good) {
+			t.Fatalf("expected the cached templates to be kept")
+		}
+	}
+
+	body = good
+	os.WriteFile(path, []byte("stale"), 0644)
+	if err := downloadDigitTemplates(server.URL, path); err != nil {
+		t.Fatalf("downloadDigitTemplates failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, good) {
+		t.Errorf("expected the downloaded templates to replace the cache")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected no temporary files left, got %d entries", len(entries))
+	}
+}
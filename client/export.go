@@ -0,0 +1,310 @@
+package client
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Export formats.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+// ExportFormats lists the supported export formats.
+var ExportFormats = []string{FormatCSV, FormatJSONL, FormatXLSX}
+
+// ArchiveColumns are the columns of an archive export. New columns are only ever appended.
+var ArchiveColumns = []string{
+	"songID", "title", "artist", "BPM", "DLC", "line", "difficulty", "level",
+	"judge", "score", "patch", "fullCombo", "maxPatch", "decodedAt", "decoder",
+}
+
+// PlayColumns are the columns of a play history export. New columns are only ever appended.
+var PlayColumns = []string{
+	"id", "playedAt", "source", "screenType", "songID", "title", "artist", "BPM", "DLC",
+	"line", "difficulty", "level", "judge", "score", "patch", "rank", "fullCombo", "maxPatch",
+	"totalNotes", "perfectHigh", "perfect", "great", "good", "miss",
+	"minConfidence", "warnings", "screenshotPath",
+}
+
+// Exporter writes rows of values, one per column, to a file format.
+// Values are strings, ints, float64s, bools or nil for an empty cell.
+type Exporter interface {
+	Write(values []any) error
+	// Close finishes the file. It does not close the underlying writer.
+	Close() error
+}
+
+// NewExporter returns an exporter of the format writing to w. CSV and XLSX start with a header row,
+// while JSON Lines use the columns as the keys of every object.
+func NewExporter(w io.Writer, format string, columns []string) (Exporter, error) {
+	var e Exporter
+	switch format {
+	case FormatJSONL:
+		return &jsonlExporter{w: bufio.NewWriter(w), columns: columns}, nil
+	case FormatCSV:
+		e = &csvExporter{w: csv.NewWriter(w)}
+	case FormatXLSX:
+		x, err := newXLSXExporter(w)
+		if err != nil {
+			return nil, err
+		}
+		e = x
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	header := make([]any, len(columns))
+	for i, c := range columns {
+		header[i] = c
+	}
+	if err := e.Write(header); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ArchiveRow returns the values of an archive record in the order of ArchiveColumns.
+// The song and level are resolved from the cache, falling back to the record's level.
+func ArchiveRow(a Archive, cache *Cache) []any {
+	song, _ := cache.FindSong(a.SongID)
+	level := a.Level
+	if p, ok := cache.FindPattern(ArchiveKey(a)); ok {
+		level = p.Level
+	}
+	return []any{
+		a.SongID, song.Title, song.Artist, song.BPM, song.DLC, a.Line, a.Difficulty, level,
+		a.Judge, a.Score, a.Patch, a.FullCombo, a.MaxPatch, a.DecodedAt, a.Decoder,
+	}
+}
+
+// PlayRow returns the values of a play in the order of PlayColumns.
+func PlayRow(p PlayRecord, cache *Cache) []any {
+	song, _ := cache.FindSong(p.SongID)
+	title := song.Title
+	if title == "" {
+		title = p.Title
+	}
+	notes := make([]any, 6)
+	if n := p.Notes; n != nil {
+		notes = []any{n.Total, n.PerfectHigh, n.Perfect, n.Great, n.Good, n.Miss}
+	}
+	var minConfidence any
+	for _, c := range p.Confidence {
+		if minConfidence == nil || c < minConfidence.(float64) {
+			minConfidence = c
+		}
+	}
+	warnings := ""
+	for i, w := range p.Warnings {
+		if i > 0 {
+			warnings += "; "
+		}
+		warnings += w.Message
+	}
+	row := []any{
+		p.ID, p.PlayedAt.Format(time.RFC3339), p.Source, p.ScreenType, p.SongID, title, song.Artist, song.BPM, song.DLC,
+		p.Line, p.Difficulty, p.Level, p.Judge, p.Score, p.Patch, p.Rank, p.FullCombo, p.MaxPatch,
+	}
+	row = append(row, notes...)
+	return append(row, minConfidence, warnings, p.ScreenshotPath)
+}
+
+// ExportArchives writes archive records in the format.
+func ExportArchives(w io.Writer, format string, archives []Archive, cache *Cache) error {
+	e, err := NewExporter(w, format, ArchiveColumns)
+	if err != nil {
+		return err
+	}
+	for _, a := range archives {
+		if err := e.Write(ArchiveRow(a, cache)); err != nil {
+			return err
+		}
+	}
+	return e.Close()
+}
+
+// ExportPlays writes the plays of the history matching the query in the format, streaming them from the database.
+func ExportPlays(w io.Writer, format string, history *History, q HistoryQuery, cache *Cache) error {
+	e, err := NewExporter(w, format, PlayColumns)
+	if err != nil {
+		return err
+	}
+	if err := history.EachPlay(q, func(p PlayRecord) error { return e.Write(PlayRow(p, cache)) }); err != nil {
+		return err
+	}
+	return e.Close()
+}
+
+// formatValue formats a value for a text cell.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvExporter) Write(values []any) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = formatValue(v)
+	}
+	if err := e.w.Write(record); err != nil {
+		return fmt.Errorf("error writing CSV: %v", err)
+	}
+	return nil
+}
+
+func (e *csvExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonlExporter struct {
+	w       *bufio.Writer
+	columns []string
+}
+
+// Write writes the row as a JSON object with keys in column order.
+func (e *jsonlExporter) Write(values []any) error {
+	e.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		key, _ := json.Marshal(e.columns[i])
+		value, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("error writing JSON: %v", err)
+		}
+		e.w.Write(key)
+		e.w.WriteByte(':')
+		e.w.Write(value)
+	}
+	e.w.WriteString("}\n")
+	return nil
+}
+
+func (e *jsonlExporter) Close() error {
+	return e.w.Flush()
+}
+
+// Fixed parts of a workbook with a single sheet.
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxExporter writes a workbook with one sheet. The fixed parts are written first,
+// so rows are streamed into the sheet, the last entry of the zip archive.
+// Strings are written inline, so no shared string table has to be held in memory.
+type xlsxExporter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+func newXLSXExporter(w io.Writer) (*xlsxExporter, error) {
+	z := zip.NewWriter(w)
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("error writing XLSX: %v", err)
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, fmt.Errorf("error writing XLSX: %v", err)
+		}
+	}
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("error writing XLSX: %v", err)
+	}
+	sheet := bufio.NewWriter(f)
+	sheet.WriteString(xlsxSheetStart)
+	return &xlsxExporter{zip: z, sheet: sheet}, nil
+}
+
+func (e *xlsxExporter) Write(values []any) error {
+	e.rows++
+	fmt.Fprintf(e.sheet, `<row r="%d">`, e.rows)
+	for i, v := range values {
+		ref := columnName(i) + strconv.Itoa(e.rows)
+		switch v := v.(type) {
+		case nil:
+			continue
+		case bool:
+			b := 0
+			if v {
+				b = 1
+			}
+			fmt.Fprintf(e.sheet, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+		case int, uint64:
+			fmt.Fprintf(e.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			fmt.Fprintf(e.sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			fmt.Fprintf(e.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			if err := xml.EscapeText(e.sheet, []byte(formatValue(v))); err != nil {
+				return fmt.Errorf("error writing XLSX: %v", err)
+			}
+			e.sheet.WriteString(`</t></is></c>`)
+		}
+	}
+	if _, err := e.sheet.WriteString(`</row>`); err != nil {
+		return fmt.Errorf("error writing XLSX: %v", err)
+	}
+	return nil
+}
+
+func (e *xlsxExporter) Close() error {
+	e.sheet.WriteString(xlsxSheetEnd)
+	if err := e.sheet.Flush(); err != nil {
+		return fmt.Errorf("error writing XLSX: %v", err)
+	}
+	return e.zip.Close()
+}
+
+// columnName returns the spreadsheet name of a zero-based column index: A, B, ..., Z, AA, ...
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestColumnName(t *testing.T) {
+	for i, expected := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if name := columnName(i); name != expected {
+			t.Errorf("column %d: expected %s, got %s", i, expected, name)
+		}
+	}
+}
+
+func TestExportArchivesCSV(t *testing.T) {
+	cache := &Cache{
+		Songs:    []Song{{ID: 1, Title: "Firework", Artist: "Artist, \"quoted\"", BPM: "150", DLC: ""}},
+		Patterns: []Pattern{{SongID: 1, Line: 4, Difficulty: "HARD", Level: 13}},
+	}
+	var buf bytes.Buffer
+	archives := []Archive{{Decoder: "tester", SongID: 1, Line: 4, Difficulty: "HARD", Level: 12, Judge: 99.5, Score: 990000, Patch: 512.34, DecodedAt: "2025-11-20", FullCombo: true}}
+	if err := ExportArchives(&buf, FormatCSV, archives, cache); err != nil {
+		t.Fatalf("ExportArchives failed: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) != 2 || strings.Join(records[0], ",") != strings.Join(ArchiveColumns, ",") {
+		t.Fatalf("unexpected CSV %v", records)
+	}
+	expected := []string{"1", "Firework", "Artist, \"quoted\"", "150", "", "4", "HARD", "13", "99.5", "990000", "512.34", "true", "false", "2025-11-20", "tester"}
+	if strings.Join(records[1], "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %v, got %v", expected, records[1])
+	}
+}
+
+func TestExportPlaysJSONL(t *testing.T) {
+	cache := &Cache{Songs: []Song{{ID: 1, Title: "Firework"}}}
+	history := openTestHistory(t)
+	play := testPlay(1, 99, 990000, 500, time.Date(2025, 11, 20, 21, 0, 0, 0, time.UTC))
+	play.Notes = &NoteCounts{Total: 10, PerfectHigh: 10}
+	play.Confidence = map[string]float64{FieldJudge: 0.9, FieldScore: 0.7}
+	for _, p := range []PlayRecord{play, testPlay(2, 90, 0, 0, time.Now())} {
+		if _, err := history.Add(p); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ExportPlays(&buf, FormatJSONL, history, HistoryQuery{SongID: 1}, cache); err != nil {
+		t.Fatalf("ExportPlays failed: %v", err)
+	}
+	scanner := bufio.NewScanner(&buf)
+	var rows []map[string]any
+	for scanner.Scan() {
+		var row map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	row := rows[0]
+	if len(row) != len(PlayColumns) || row["title"] != "Firework" || row["level"] != 12.0 || row["perfectHigh"] != 10.0 || row["minConfidence"] != 0.7 || row["playedAt"] != "2025-11-20T21:00:00Z" {
+		t.Errorf("unexpected row %v", row)
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := NewExporter(&buf, FormatXLSX, []string{"name", "count", "ratio", "flag", "empty"})
+	if err != nil {
+		t.Fatalf("NewExporter failed: %v", err)
+	}
+	if err := e.Write([]any{"<a & b>", 3, 0.5, true, nil}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	var sheet []byte
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		// Every part must be well-formed XML
+		decoder := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed: %v", f.Name, err)
+			}
+		}
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			sheet = data
+		}
+	}
+	for _, cell := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">name</t></is></c>`,
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">&lt;a &amp; b&gt;</t></is></c>`,
+		`<c r="B2"><v>3</v></c>`,
+		`<c r="C2"><v>0.5</v></c>`,
+		`<c r="D2" t="b"><v>1</v></c>`,
+	} {
+		if !bytes.Contains(sheet, []byte(cell)) {
+			t.Errorf("sheet is missing %s", cell)
+		}
+	}
+	if bytes.Contains(sheet, []byte(`r="E2"`)) {
+		t.Error("expected no cell for a nil value")
+	}
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadConfigFile parses a config file, such as config.yaml of the repository.
+func ReadConfigFile(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var config Config
+	if err := yaml.NewDecoder(file).Decode(&config); err != nil {
+		return nil, fmt.Errorf("error parsing YAML: %v", err)
+	}
+	return &config, nil
+}
+
+// ReadImageFile decodes an image file in any registered format.
+func ReadImageFile(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+	return img, nil
+}
+
+// ReadCacheFile reads a client cache file, or a JSON array of songs as a cache holding only songs.
+func ReadCacheFile(path string) (Cache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Cache{}, err
+	}
+	var cache Cache
+	if err := json.Unmarshal(data, &cache.Songs); err == nil {
+		return cache, nil
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return Cache{}, fmt.Errorf("error parsing JSON: %v", err)
+	}
+	return cache, nil
+}
+
+// ReadArchivesFile reads a JSON array of archive records, such as the output of FetchArchive.
+func ReadArchivesFile(path string) ([]Archive, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var archives []Archive
+	if err := json.Unmarshal(data, &archives); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %v", err)
+	}
+	return archives, nil
+}
+
+// FetchSavedArchive fetches the archive records of the account whose API key is saved.
+func FetchSavedArchive() ([]Archive, error) {
+	apiKey := LoadAPIKey()
+	if apiKey == "" {
+		return nil, fmt.Errorf("no API key saved, log in with the client first")
+	}
+	return FetchArchive(base64.StdEncoding.EncodeToString([]byte(apiKey)))
+}
+
+// LoadArchives reads the archive records of a file, or fetches those of the saved account when path is empty.
+func LoadArchives(path string) ([]Archive, error) {
+	if path == "" {
+		return FetchSavedArchive()
+	}
+	return ReadArchivesFile(path)
+}
+
+// WriteOutput calls write with the file at path, or with stdout when path is empty.
+func WriteOutput(path string, write func(w io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCacheFile(t *testing.T) {
+	dir := t.TempDir()
+	songsPath := filepath.Join(dir, "songs.json")
+	if err := os.WriteFile(songsPath, []byte(`[{"songID": 1, "title": "CHEWiNG LOVE"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cachePath := filepath.Join(dir, "db.json")
+	if err := os.WriteFile(cachePath, []byte(`{"songs": [{"songID": 2, "title": "Firework"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]int{songsPath: 1, cachePath: 2} {
+		cache, err := ReadCacheFile(path)
+		if err != nil {
+			t.Fatalf("ReadCacheFile(%s) failed: %v", filepath.Base(path), err)
+		}
+		if len(cache.Songs) != 1 || cache.Songs[0].ID != expected {
+			t.Errorf("ReadCacheFile(%s): expected song %d, got %+v", filepath.Base(path), expected, cache.Songs)
+		}
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	config, err := ReadConfigFile("../config.yaml")
+	if err != nil {
+		t.Fatalf("ReadConfigFile failed: %v", err)
+	}
+	if len(config.Configs) == 0 || config.Reference.Width == 0 {
+		t.Errorf("expected ROI configs and a reference size, got %+v", config)
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	err := WriteOutput(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "ok")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WriteOutput failed: %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "ok" {
+		t.Errorf("expected the output to be written, got %q (%v)", data, err)
+	}
+}
+
+func TestLoadArchives(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archives.json")
+	if err := os.WriteFile(path, []byte(`[{"song_id": 1, "line": 4, "difficulty": "HARD", "judge": 99.5}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archives, err := LoadArchives(path)
+	if err != nil {
+		t.Fatalf("LoadArchives failed: %v", err)
+	}
+	if len(archives) != 1 || archives[0].SongID != 1 {
+		t.Errorf("expected the archive record of the file, got %+v", archives)
+	}
+}
@@ -0,0 +1,304 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Goal kinds.
+const (
+	GoalJudge     = "judge"
+	GoalScore     = "score"
+	GoalPatch     = "patch"
+	GoalFullCombo = "fullCombo"
+	GoalMaxPatch  = "maxPatch"
+)
+
+// GoalKinds lists the goal kinds.
+var GoalKinds = []string{GoalJudge, GoalScore, GoalPatch, GoalFullCombo, GoalMaxPatch}
+
+// Goal is a target on a single pattern, or on every pattern of a level passing the line and difficulty filter.
+type Goal struct {
+	ID   int    `json:"id"`
+	Kind string `json:"kind"`
+	// Target is the value to reach for the judge, score and patch kinds.
+	Target float64 `json:"target,omitempty"`
+	// Pattern is the pattern of a single pattern goal. Level and the filter are used when it is nil.
+	Pattern      *PatternKey `json:"pattern,omitempty"`
+	Level        int         `json:"level,omitempty"`
+	Lines        []int       `json:"lines,omitempty"`
+	Difficulties []string    `json:"difficulties,omitempty"`
+	Created      time.Time   `json:"created"`
+	// Achieved is set when the goal is checked as reached, and cleared when it no longer is.
+	Achieved time.Time `json:"achieved,omitzero"`
+}
+
+// Reached reports whether the goal has been achieved.
+func (g Goal) Reached() bool {
+	return !g.Achieved.IsZero()
+}
+
+// Validate checks that the goal has a known kind, a target when the kind needs one and a scope.
+func (g Goal) Validate() error {
+	switch g.Kind {
+	case GoalJudge, GoalScore, GoalPatch:
+		if g.Target <= 0 {
+			return fmt.Errorf("a %s goal needs a target", g.Kind)
+		}
+	case GoalFullCombo, GoalMaxPatch:
+	default:
+		return fmt.Errorf("unknown goal kind %q", g.Kind)
+	}
+	if g.Pattern == nil && g.Level <= 0 {
+		return fmt.Errorf("a goal needs a pattern or a level")
+	}
+	return nil
+}
+
+// meets reports whether an archive record fulfils the goal on its pattern.
+func (g Goal) meets(a Archive) bool {
+	switch g.Kind {
+	case GoalJudge:
+		return a.Judge >= g.Target
+	case GoalScore:
+		return a.Score >= g.Target
+	case GoalPatch:
+		return a.Patch >= g.Target
+	case GoalFullCombo:
+		return ArchiveLamp(a) >= LampFullCombo
+	case GoalMaxPatch:
+		return ArchiveLamp(a) >= LampMaxPatch
+	}
+	return false
+}
+
+// value returns what an archive record has of the goal's value, for progress towards a target.
+func (g Goal) value(a Archive) float64 {
+	switch g.Kind {
+	case GoalJudge:
+		return a.Judge
+	case GoalScore:
+		return a.Score
+	case GoalPatch:
+		return a.Patch
+	}
+	if g.meets(a) {
+		return 1
+	}
+	return 0
+}
+
+// Describe returns the goal in words, e.g. "judge ≥ 99.5 on Firework 6L OVER" or "FULL COMBO on all Lv.12 4L patterns".
+func (g Goal) Describe(cache *Cache) string {
+	var what string
+	switch g.Kind {
+	case GoalFullCombo:
+		what = LampFullCombo.String()
+	case GoalMaxPatch:
+		what = LampMaxPatch.String()
+	default:
+		what = fmt.Sprintf("%s ≥ %g", g.Kind, g.Target)
+	}
+	if g.Pattern != nil {
+		name := g.Pattern.String()
+		if song, ok := cache.FindSong(g.Pattern.SongID); ok {
+			name = fmt.Sprintf("%s %dL %s", song.Title, g.Pattern.Line, g.Pattern.Difficulty)
+		}
+		return fmt.Sprintf("%s on %s", what, name)
+	}
+	scope := fmt.Sprintf("Lv.%d", g.Level)
+	for _, line := range g.Lines {
+		scope += fmt.Sprintf(" %dL", line)
+	}
+	if len(g.Difficulties) > 0 {
+		scope += " " + strings.Join(g.Difficulties, "/")
+	}
+	return fmt.Sprintf("%s on all %s patterns", what, scope)
+}
+
+// patterns returns the patterns the goal covers.
+func (g Goal) patterns(cache *Cache) []PatternKey {
+	if g.Pattern != nil {
+		return []PatternKey{*g.Pattern}
+	}
+	filter := PatternFilter{Lines: g.Lines, Difficulties: g.Difficulties}
+	var keys []PatternKey
+	for _, p := range cache.Patterns {
+		song, _ := cache.FindSong(p.SongID)
+		if p.Level == g.Level && filter.Matches(song, p) {
+			keys = append(keys, PatternKey{p.SongID, p.Line, p.Difficulty})
+		}
+	}
+	return keys
+}
+
+// GoalProgress is how far a goal is from being reached.
+type GoalProgress struct {
+	Goal Goal
+	// Done and Total count the covered patterns that fulfil the goal.
+	Done  int
+	Total int
+	// Best is the best value on the pattern of a single pattern goal.
+	Best float64
+	// Fraction is in [0, 1]: Best over the target for a single pattern target, Done over Total otherwise.
+	Fraction float64
+}
+
+// Reached reports whether every covered pattern fulfils the goal.
+func (p GoalProgress) Reached() bool {
+	return p.Total > 0 && p.Done == p.Total
+}
+
+// String describes the progress, e.g. "98.7000 / 99.5" or "7 / 12".
+func (p GoalProgress) String() string {
+	if p.Goal.Pattern != nil && p.Goal.Target > 0 {
+		return fmt.Sprintf("%.4f / %g", p.Best, p.Goal.Target)
+	}
+	return fmt.Sprintf("%d / %d", p.Done, p.Total)
+}
+
+// GoalProgressOf computes the progress of a goal from the archive records.
+func GoalProgressOf(g Goal, archives []Archive, cache *Cache) GoalProgress {
+	bests := make(map[PatternKey]Archive)
+	for _, a := range BestArchives(archives) {
+		bests[ArchiveKey(a)] = a
+	}
+	progress := GoalProgress{Goal: g}
+	for _, key := range g.patterns(cache) {
+		progress.Total++
+		a, ok := bests[key]
+		if !ok {
+			continue
+		}
+		if g.meets(a) {
+			progress.Done++
+		}
+		progress.Best = max(progress.Best, g.value(a))
+	}
+	switch {
+	case progress.Total == 0:
+	case g.Pattern != nil && g.Target > 0:
+		progress.Fraction = min(1, progress.Best/g.Target)
+	default:
+		progress.Fraction = float64(progress.Done) / float64(progress.Total)
+	}
+	return progress
+}
+
+// GoalStore keeps the goals in a local file.
+type GoalStore struct {
+	mu    sync.Mutex
+	path  string
+	goals []Goal
+}
+
+// LoadGoals loads the goals from the local file system.
+// A missing file results in no goals.
+func LoadGoals() (*GoalStore, error) {
+	os.MkdirAll(getCacheDirectory(), os.ModeDir)
+	return loadGoalsFrom(filepath.Join(getCacheDirectory(), "goals.json"))
+}
+
+func loadGoalsFrom(path string) (*GoalStore, error) {
+	s := &GoalStore{path: path}
+	if !fileExists(path) {
+		return s, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return s, fmt.Errorf("error opening goals file: %v", err)
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(&s.goals); err != nil {
+		return s, fmt.Errorf("error parsing JSON: %v", err)
+	}
+	return s, nil
+}
+
+// Goals returns the goals in the order they were added.
+func (s *GoalStore) Goals() []Goal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Goal(nil), s.goals...)
+}
+
+// Add validates and stores a new goal, giving it an ID.
+func (s *GoalStore) Add(g Goal) (Goal, error) {
+	if err := g.Validate(); err != nil {
+		return Goal{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.goals {
+		g.ID = max(g.ID, existing.ID)
+	}
+	g.ID++
+	if g.Created.IsZero() {
+		g.Created = time.Now()
+	}
+	s.goals = append(s.goals, g)
+	return g, s.save()
+}
+
+// Remove deletes a goal.
+func (s *GoalStore) Remove(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, g := range s.goals {
+		if g.ID == id {
+			s.goals = append(s.goals[:i], s.goals[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("no goal %d", id)
+}
+
+// Check computes the progress of every goal and marks the goals newly reached, which it returns
+// so they are only announced once. Goals no longer reached, e.g. after a misread was removed, are unmarked.
+func (s *GoalStore) Check(archives []Archive, cache *Cache) ([]GoalProgress, []Goal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var progress []GoalProgress
+	var reached []Goal
+	changed := false
+	for i := range s.goals {
+		p := GoalProgressOf(s.goals[i], archives, cache)
+		switch {
+		case p.Reached() && !s.goals[i].Reached():
+			s.goals[i].Achieved = time.Now()
+			reached = append(reached, s.goals[i])
+			changed = true
+		case !p.Reached() && s.goals[i].Reached():
+			s.goals[i].Achieved = time.Time{}
+			changed = true
+		}
+		p.Goal = s.goals[i]
+		progress = append(progress, p)
+	}
+	if !changed {
+		return progress, nil, nil
+	}
+	return progress, reached, s.save()
+}
+
+func (s *GoalStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening goals file: %v", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(s.goals); err != nil {
+		return fmt.Errorf("error writing JSON: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGoalProgress(t *testing.T) {
+	cache := &Cache{
+		Songs: []Song{{ID: 1}, {ID: 2}},
+		Patterns: []Pattern{
+			{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10},
+			{SongID: 1, Line: 6, Difficulty: "HARD", Level: 11},
+			{SongID: 2, Line: 4, Difficulty: "HARD", Level: 10},
+		},
+	}
+	archives := []Archive{
+		{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10, Judge: 98.7},
+		{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10, Judge: 99.6, FullCombo: true},
+		{SongID: 2, Line: 4, Difficulty: "HARD", Level: 10, Judge: 97, MaxPatch: true},
+	}
+
+	pattern := &PatternKey{1, 4, "HARD"}
+	judge := GoalProgressOf(Goal{Kind: GoalJudge, Target: 99.5, Pattern: pattern}, archives, cache)
+	if !judge.Reached() || judge.Best != 99.6 || judge.Fraction != 1 {
+		t.Errorf("expected the judge goal reached by the best record, got %+v", judge)
+	}
+	higher := GoalProgressOf(Goal{Kind: GoalJudge, Target: 99.8, Pattern: pattern}, archives, cache)
+	if higher.Reached() || higher.String() != "99.6000 / 99.8" {
+		t.Errorf("unexpected progress %+v", higher)
+	}
+
+	level := GoalProgressOf(Goal{Kind: GoalFullCombo, Level: 10}, archives, cache)
+	if level.Done != 2 || level.Total != 2 || !level.Reached() {
+		t.Errorf("expected both level 10 patterns with a full combo or better, got %+v", level)
+	}
+	maxPatch := GoalProgressOf(Goal{Kind: GoalMaxPatch, Level: 10}, archives, cache)
+	if maxPatch.Done != 1 || maxPatch.Fraction != 0.5 || maxPatch.String() != "1 / 2" {
+		t.Errorf("expected one of two max patches, got %+v", maxPatch)
+	}
+}
+
+func TestGoalDescribe(t *testing.T) {
+	cache := &Cache{Songs: []Song{{ID: 1, Title: "Firework"}}}
+	g := Goal{Kind: GoalJudge, Target: 99.5, Pattern: &PatternKey{1, 6, "OVER"}}
+	if d := g.Describe(cache); d != "judge ≥ 99.5 on Firework 6L OVER" {
+		t.Errorf("unexpected description %q", d)
+	}
+	g = Goal{Kind: GoalFullCombo, Level: 12, Lines: []int{4}}
+	if d := g.Describe(cache); d != "FULL COMBO on all Lv.12 4L patterns" {
+		t.Errorf("unexpected description %q", d)
+	}
+}
+
+func TestGoalStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goals.json")
+	store, err := loadGoalsFrom(path)
+	if err != nil {
+		t.Fatalf("loadGoalsFrom failed: %v", err)
+	}
+	if _, err := store.Add(Goal{Kind: GoalJudge, Level: 10}); err == nil {
+		t.Errorf("expected a judge goal without a target to be refused")
+	}
+	first, err := store.Add(Goal{Kind: GoalFullCombo, Level: 10})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	second, _ := store.Add(Goal{Kind: GoalPatch, Target: 500, Pattern: &PatternKey{1, 4, "HARD"}})
+	if first.ID != 1 || second.ID != 2 || first.Created.IsZero() {
+		t.Errorf("unexpected goals %+v %+v", first, second)
+	}
+
+	cache := &Cache{
+		Songs: []Song{{ID: 1}, {ID: 2}},
+		Patterns: []Pattern{
+			{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10},
+			{SongID: 1, Line: 6, Difficulty: "HARD", Level: 11},
+			{SongID: 2, Line: 4, Difficulty: "HARD", Level: 10},
+		},
+	}
+	archives := []Archive{
+		{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10, Patch: 510, FullCombo: true},
+		{SongID: 2, Line: 4, Difficulty: "HARD", Level: 10, FullCombo: true},
+	}
+	progress, reached, err := store.Check(archives, cache)
+	if err != nil || len(progress) != 2 || len(reached) != 2 {
+		t.Fatalf("expected both goals reached, got %+v %+v %v", progress, reached, err)
+	}
+	if _, reached, _ = store.Check(archives, cache); len(reached) != 0 {
+		t.Errorf("expected reached goals to be announced once, got %+v", reached)
+	}
+
+	// A goal no longer reached is unmarked, and announced again once reached
+	lowered := []Archive{{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10, Patch: 490, FullCombo: true}, archives[1]}
+	if _, reached, _ = store.Check(lowered, cache); len(reached) != 0 || store.Goals()[1].Reached() || !store.Goals()[0].Reached() {
+		t.Errorf("expected only the patch goal to be unmarked, got %+v", store.Goals())
+	}
+	if _, reached, _ = store.Check(archives, cache); len(reached) != 1 || reached[0].ID != second.ID {
+		t.Errorf("expected the patch goal to be announced again, got %+v", reached)
+	}
+
+	if err := store.Remove(first.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	reloaded, err := loadGoalsFrom(path)
+	if err != nil {
+		t.Fatalf("loadGoalsFrom failed: %v", err)
+	}
+	goals := reloaded.Goals()
+	if len(goals) != 1 || goals[0].ID != 2 || !goals[0].Reached() || goals[0].Pattern == nil {
+		t.Errorf("expected the reached patch goal to be kept, got %+v", goals)
+	}
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var goldenDir = flag.String("golden.dir", "../testing", "directory of the screenshot corpus")
+
+var goldenDigits = flag.String("golden.digits", "", "digit templates trained on captures outside the corpus")
+
+// goldenMinAccuracy is the accuracy each field must keep across the corpus.
+// Fields not listed here are reported but do not fail the test.
+var goldenMinAccuracy = map[string]float64{
+	"screenType": 1,
+	"title":      1,
+	"difficulty": 1,
+}
+
+// goldenMinDigitAccuracy is the accuracy each number field must keep. Numbers are
+// only held to it with -golden.digits, since templates left out of one screenshot
+// are cut from a handful of others and may miss the glyphs it shows.
+var goldenMinDigitAccuracy = map[string]float64{
+	"level":       1,
+	"line":        1,
+	"judge":       1,
+	"score":       1,
+	"patch":       1,
+	"totalNotes":  1,
+	"perfectHigh": 1,
+	"perfect":     1,
+	"great":       1,
+	"good":        1,
+	"miss":        1,
+}
+
+// loadGoldenInputs loads config.yaml and songs.json from the corpus directory,
+// falling back to the repository config and the bundled song fixtures.
+func loadGoldenInputs(t *testing.T, dir string) (*Config, *Cache) {
+	t.Helper()
+	configPath := filepath.Join(dir, "config.yaml")
+	if !fileExists(configPath) {
+		configPath = "../config.yaml"
+	}
+	config, err := ReadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	songsPath := filepath.Join(dir, "songs.json")
+	if !fileExists(songsPath) {
+		songsPath = "../testing/songs.json"
+	}
+	cache, err := ReadCacheFile(songsPath)
+	if err != nil {
+		t.Fatalf("failed to load songs: %v", err)
+	}
+	return config, &cache
+}
+
+// heldOutDigitTemplates returns the templates each case is read with: those given by
+// -golden.digits, or templates trained on every other case of the corpus.
+func heldOutDigitTemplates(t *testing.T, cases []CorpusCase, config *Config) []*DigitTemplates {
+	t.Helper()
+	templates := make([]*DigitTemplates, len(cases))
+	if *goldenDigits != "" {
+		given, err := loadDigitTemplatesFrom(*goldenDigits)
+		if err != nil {
+			t.Fatalf("failed to load digit templates: %v", err)
+		}
+		for i := range templates {
+			templates[i] = given
+		}
+		return templates
+	}
+
+	samples := make([][]GlyphSample, len(cases))
+	for i, c := range cases {
+		img, err := c.LoadImage()
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		cut, skipped, err := ExtractGlyphSamples(img, config, c.Expected)
+		if err != nil {
+			t.Logf("%s: no glyphs for training: %v", c.Name, err)
+			continue
+		}
+		for _, s := range skipped {
+			t.Logf("%s: %s", c.Name, s)
+		}
+		samples[i] = cut
+	}
+	for i := range cases {
+		var others []GlyphSample
+		for j, cut := range samples {
+			if j != i {
+				others = append(others, cut...)
+			}
+		}
+		templates[i] = TrainDigitTemplates(others, "golden")
+	}
+	return templates
+}
+
+// TestGoldenCorpus analyzes every labelled screenshot of the corpus and reports
+// the accuracy of every field. Point -golden.dir at another directory to run
+// the analyzer against captures that are not committed. Numbers are read with
+// templates not cut from the screenshot itself, see heldOutDigitTemplates.
+func TestGoldenCorpus(t *testing.T) {
+	cases, err := LoadCorpus(*goldenDir)
+	if err != nil {
+		t.Fatalf("LoadCorpus failed: %v", err)
+	}
+	if len(cases) == 0 {
+		t.Skipf("no labelled screenshots in %s", *goldenDir)
+	}
+	config, cache := loadGoldenInputs(t, *goldenDir)
+	templates := heldOutDigitTemplates(t, cases, config)
+	minAccuracy := goldenMinAccuracy
+	if *goldenDigits != "" {
+		minAccuracy = make(map[string]float64)
+		for _, floors := range []map[string]float64{goldenMinAccuracy, goldenMinDigitAccuracy} {
+			for field, minimum := range floors {
+				minAccuracy[field] = minimum
+			}
+		}
+	} else {
+		t.Log("number fields are reported without their floors, pass -golden.digits to check them")
+	}
+
+	correct := make(map[string]int)
+	total := make(map[string]int)
+	for i, c := range cases {
+		img, err := c.LoadImage()
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		actual := map[string]any{}
+		report, err := AnalyzeImage(img, cache, config, AnalyzeOptions{Digits: templates[i]})
+		if err != nil {
+			t.Logf("%s: analysis failed: %v", c.Name, err)
+		} else {
+			actual = ReportFields(report)
+		}
+
+		matched, mismatches := CompareFields(c.Expected, actual)
+		for _, field := range matched {
+			correct[field]++
+			total[field]++
+		}
+		for _, m := range mismatches {
+			total[m.Field]++
+			t.Logf("%s: %s expected %v, got %v", c.Name, m.Field, m.Expected, m.Actual)
+		}
+	}
+
+	fields := make([]string, 0, len(total))
+	for field := range total {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var summary strings.Builder
+	for _, field := range fields {
+		accuracy := float64(correct[field]) / float64(total[field])
+		fmt.Fprintf(&summary, "\n  %-12s %3d/%-3d %6.1f%%", field, correct[field], total[field], accuracy*100)
+		if minimum, ok := minAccuracy[field]; ok && accuracy < minimum {
+			t.Errorf("%s accuracy %.1f%% is below %.1f%%", field, accuracy*100, minimum*100)
+		}
+	}
+	t.Logf("per-field accuracy over %d screenshots:%s", len(cases), summary.String())
+}
@@ -0,0 +1,512 @@
+package client
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of the history database.
+var (
+	playsBucket = []byte("plays")
+	bestsBucket = []byte("bests")
+)
+
+// PlaySourceServer marks plays imported from the server archive.
+// Plays of analysed screenshots have no source.
+const PlaySourceServer = "server"
+
+// PlayRecord is an analysed screenshot as kept in the local history.
+// It holds everything the report extracted, including what the server does not accept.
+type PlayRecord struct {
+	ID             uint64              `json:"id"`
+	PlayedAt       time.Time           `json:"playedAt"`
+	Source         string              `json:"source,omitempty"`
+	ScreenType     string              `json:"screenType"`
+	SongID         int                 `json:"songID"`
+	Title          string              `json:"title"`
+	Line           int                 `json:"line"`
+	Difficulty     string              `json:"difficulty"`
+	Level          int                 `json:"level"`
+	Judge          float64             `json:"judge"`
+	Score          float64             `json:"score"`
+	Patch          float64             `json:"patch"`
+	Rank           string              `json:"rank,omitempty"`
+	FullCombo      bool                `json:"fullCombo"`
+	MaxPatch       bool                `json:"maxPatch"`
+	Notes          *NoteCounts         `json:"notes,omitempty"`
+	Confidence     map[string]float64  `json:"confidence"`
+	Warnings       []ValidationWarning `json:"warnings,omitempty"`
+	ScreenshotPath string              `json:"screenshotPath,omitempty"`
+	// Record is false when the report lacks a value the archive needs.
+	// Such plays are kept, but left out of the bests.
+	Record bool `json:"record"`
+	// Confirmed is set once the values were accepted by the player or uploaded.
+	// Only confirmed records count towards the bests.
+	Confirmed bool `json:"confirmed"`
+}
+
+// NewPlayRecord converts a report into a play record.
+func NewPlayRecord(report AnalysisReport, playedAt time.Time, screenshotPath string) PlayRecord {
+	record := PlayRecord{
+		PlayedAt:       playedAt,
+		ScreenType:     report.ScreenType.String(),
+		SongID:         report.SongObject.ID,
+		Title:          report.SongObject.Title,
+		Line:           report.PatternObject.Line,
+		Difficulty:     report.PatternObject.Difficulty,
+		Level:          report.PatternObject.Level,
+		Judge:          report.Judge,
+		Score:          report.Score,
+		Patch:          report.Patch,
+		Rank:           report.Rank,
+		FullCombo:      report.FullCombo,
+		MaxPatch:       report.MaxPatch,
+		Confidence:     report.Confidence,
+		Warnings:       report.Warnings,
+		ScreenshotPath: screenshotPath,
+		Record:         report.HasRecord(),
+	}
+	if _, ok := report.Confidence[FieldNotes]; ok {
+		notes := report.Notes
+		record.Notes = &notes
+	}
+	return record
+}
+
+// PatternKey identifies a pattern of a song.
+type PatternKey struct {
+	SongID     int    `json:"songID"`
+	Line       int    `json:"line"`
+	Difficulty string `json:"difficulty"`
+}
+
+func (k PatternKey) String() string {
+	return fmt.Sprintf("%d/%d/%s", k.SongID, k.Line, k.Difficulty)
+}
+
+// Key returns the pattern the record was played on.
+func (r PlayRecord) Key() PatternKey {
+	return PatternKey{r.SongID, r.Line, r.Difficulty}
+}
+
+// PatternBest holds the best values ever recorded on a pattern.
+// Every value is the best on its own, so they can come from different plays.
+type PatternBest struct {
+	PatternKey
+	Level      int       `json:"level"`
+	Judge      float64   `json:"judge"`
+	Score      float64   `json:"score"`
+	Patch      float64   `json:"patch"`
+	FullCombo  bool      `json:"fullCombo"`
+	MaxPatch   bool      `json:"maxPatch"`
+	Plays      int       `json:"plays"`
+	LastPlayed time.Time `json:"lastPlayed"`
+}
+
+// add merges a play into the best.
+func (b *PatternBest) add(r PlayRecord) {
+	b.PatternKey = r.Key()
+	b.Level = r.Level
+	b.Judge = max(b.Judge, r.Judge)
+	b.Score = max(b.Score, r.Score)
+	b.Patch = max(b.Patch, r.Patch)
+	b.FullCombo = b.FullCombo || r.FullCombo
+	b.MaxPatch = b.MaxPatch || r.MaxPatch
+	b.Plays++
+	if r.PlayedAt.After(b.LastPlayed) {
+		b.LastPlayed = r.PlayedAt
+	}
+}
+
+// DefaultScreenshotLimit is how many play screenshots are kept by default.
+const DefaultScreenshotLimit = 500
+
+// History is the local database of analysed plays.
+type History struct {
+	db   *bolt.DB
+	path string
+	// ScreenshotLimit is how many screenshots are kept; the oldest are deleted when a new one is saved.
+	// Zero keeps them all.
+	ScreenshotLimit int
+}
+
+// OpenHistory opens the history database at path, creating it if needed.
+func OpenHistory(path string) (*History, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating history folder: %v", err)
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening history: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{playsBucket, bestsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating history buckets: %v", err)
+	}
+	return &History{db: db, path: path, ScreenshotLimit: DefaultScreenshotLimit}, nil
+}
+
+// HistoryPath returns the path of the history database in the cache directory.
+func HistoryPath() string {
+	return filepath.Join(getCacheDirectory(), "history", "history.db")
+}
+
+// Close closes the database.
+func (h *History) Close() error {
+	return h.db.Close()
+}
+
+// SaveScreenshot writes the screenshot of a play next to the database and returns its path.
+// Screenshots beyond the limit are deleted, oldest first.
+func (h *History) SaveScreenshot(img image.Image, playedAt time.Time) (string, error) {
+	dir := filepath.Join(filepath.Dir(h.path), "screenshots")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating screenshot folder: %v", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("play-%s.png", playedAt.Format("20060102-150405.000")))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("error creating screenshot file: %v", err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		return "", fmt.Errorf("error writing screenshot: %v", err)
+	}
+	if err := pruneScreenshots(dir, h.ScreenshotLimit); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// pruneScreenshots deletes the oldest screenshots of dir so at most limit are left.
+// Names hold the play time, so they sort from the oldest.
+func pruneScreenshots(dir string, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "play-*.png"))
+	if err != nil {
+		return fmt.Errorf("error listing screenshots: %v", err)
+	}
+	sort.Strings(paths)
+	for len(paths) > limit {
+		if err := os.Remove(paths[0]); err != nil {
+			return fmt.Errorf("error deleting screenshot: %v", err)
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
+
+// Add stores a play. The best of its pattern is only updated when the play is a confirmed record;
+// other plays are confirmed later with Confirm. It returns the record with its ID set.
+func (h *History) Add(record PlayRecord) (PlayRecord, error) {
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		plays := tx.Bucket(playsBucket)
+		id, err := plays.NextSequence()
+		if err != nil {
+			return err
+		}
+		record.ID = id
+		if err := putPlay(tx, record); err != nil {
+			return err
+		}
+		if !record.Record || !record.Confirmed {
+			return nil
+		}
+		return addBest(tx, record)
+	})
+	if err != nil {
+		return PlayRecord{}, fmt.Errorf("error adding play: %v", err)
+	}
+	return record, nil
+}
+
+// Confirm marks a play as accepted by the player or uploaded, and adds it to the best of its pattern.
+// Confirming a play twice does nothing.
+func (h *History) Confirm(id uint64) (PlayRecord, error) {
+	var record PlayRecord
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if record, err = getPlay(tx, id); err != nil {
+			return err
+		}
+		if record.Confirmed {
+			return nil
+		}
+		record.Confirmed = true
+		if err := putPlay(tx, record); err != nil {
+			return err
+		}
+		if !record.Record {
+			return nil
+		}
+		return addBest(tx, record)
+	})
+	if err != nil {
+		return PlayRecord{}, fmt.Errorf("error confirming play: %v", err)
+	}
+	return record, nil
+}
+
+// Remove deletes a play with its screenshot, and recomputes the best of its pattern without it.
+func (h *History) Remove(id uint64) error {
+	var record PlayRecord
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if record, err = getPlay(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Bucket(playsBucket).Delete(itob(id)); err != nil {
+			return err
+		}
+		return rebuildBests(tx, func(key PatternKey) bool { return key == record.Key() })
+	})
+	if err != nil {
+		return fmt.Errorf("error removing play: %v", err)
+	}
+	if record.ScreenshotPath != "" {
+		if err := os.Remove(record.ScreenshotPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error deleting screenshot: %v", err)
+		}
+	}
+	return nil
+}
+
+// RebuildBests recomputes the bests of every pattern from the confirmed records.
+func (h *History) RebuildBests() error {
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		return rebuildBests(tx, func(PatternKey) bool { return true })
+	})
+	if err != nil {
+		return fmt.Errorf("error rebuilding bests: %v", err)
+	}
+	return nil
+}
+
+// rebuildBests recomputes the bests of the patterns selected by keep from the confirmed records.
+func rebuildBests(tx *bolt.Tx, keep func(PatternKey) bool) error {
+	rebuilt := make(map[PatternKey]*PatternBest)
+	err := tx.Bucket(playsBucket).ForEach(func(_, data []byte) error {
+		var record PlayRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return err
+		}
+		if !record.Record || !record.Confirmed || !keep(record.Key()) {
+			return nil
+		}
+		best, ok := rebuilt[record.Key()]
+		if !ok {
+			best = &PatternBest{}
+			rebuilt[record.Key()] = best
+		}
+		best.add(record)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	bests := tx.Bucket(bestsBucket)
+	var stale [][]byte
+	err = bests.ForEach(func(key, data []byte) error {
+		var best PatternBest
+		if err := json.Unmarshal(data, &best); err != nil {
+			return err
+		}
+		if keep(best.PatternKey) {
+			stale = append(stale, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range stale {
+		if err := bests.Delete(key); err != nil {
+			return err
+		}
+	}
+	for key, best := range rebuilt {
+		data, err := json.Marshal(best)
+		if err != nil {
+			return err
+		}
+		if err := bests.Put([]byte(key.String()), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getPlay(tx *bolt.Tx, id uint64) (PlayRecord, error) {
+	var record PlayRecord
+	data := tx.Bucket(playsBucket).Get(itob(id))
+	if data == nil {
+		return record, fmt.Errorf("no play %d", id)
+	}
+	return record, json.Unmarshal(data, &record)
+}
+
+func putPlay(tx *bolt.Tx, record PlayRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(playsBucket).Put(itob(record.ID), data)
+}
+
+// addBest merges a record into the best of its pattern.
+func addBest(tx *bolt.Tx, record PlayRecord) error {
+	bests := tx.Bucket(bestsBucket)
+	key := []byte(record.Key().String())
+	var best PatternBest
+	if data := bests.Get(key); data != nil {
+		if err := json.Unmarshal(data, &best); err != nil {
+			return err
+		}
+	}
+	best.add(record)
+	data, err := json.Marshal(best)
+	if err != nil {
+		return err
+	}
+	return bests.Put(key, data)
+}
+
+// Best returns the best of a pattern. The bool is false when the pattern was never recorded.
+func (h *History) Best(key PatternKey) (PatternBest, bool, error) {
+	var best PatternBest
+	var found bool
+	err := h.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bestsBucket).Get([]byte(key.String()))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &best)
+	})
+	if err != nil {
+		return PatternBest{}, false, fmt.Errorf("error reading best: %v", err)
+	}
+	return best, found, nil
+}
+
+// Bests returns the best of every recorded pattern, sorted by song, line and difficulty.
+func (h *History) Bests() ([]PatternBest, error) {
+	var bests []PatternBest
+	err := h.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bestsBucket).ForEach(func(_, data []byte) error {
+			var best PatternBest
+			if err := json.Unmarshal(data, &best); err != nil {
+				return err
+			}
+			bests = append(bests, best)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading bests: %v", err)
+	}
+	sort.Slice(bests, func(i, j int) bool {
+		a, b := bests[i], bests[j]
+		if a.SongID != b.SongID {
+			return a.SongID < b.SongID
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Difficulty < b.Difficulty
+	})
+	return bests, nil
+}
+
+// HistoryQuery selects plays. Zero fields match every play.
+type HistoryQuery struct {
+	SongID     int
+	Line       int
+	Difficulty string
+	Source     string
+	Since      time.Time
+	Until      time.Time
+	// RecordsOnly leaves out plays that lack a value the archive needs.
+	RecordsOnly bool
+	// ConfirmedOnly leaves out plays that were neither accepted by the player nor uploaded.
+	ConfirmedOnly bool
+	// Limit keeps only the latest plays when positive.
+	Limit int
+}
+
+func (q HistoryQuery) matches(r PlayRecord) bool {
+	switch {
+	case q.SongID != 0 && r.SongID != q.SongID,
+		q.Line != 0 && r.Line != q.Line,
+		q.Difficulty != "" && r.Difficulty != q.Difficulty,
+		q.Source != "" && r.Source != q.Source,
+		!q.Since.IsZero() && r.PlayedAt.Before(q.Since),
+		!q.Until.IsZero() && !r.PlayedAt.Before(q.Until),
+		q.RecordsOnly && !r.Record,
+		q.ConfirmedOnly && !r.Confirmed:
+		return false
+	}
+	return true
+}
+
+// EachPlay calls fn with every play matching the query, in the order they were added,
+// without holding them all in memory. The limit of the query is ignored.
+func (h *History) EachPlay(q HistoryQuery, fn func(PlayRecord) error) error {
+	return h.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(playsBucket).ForEach(func(_, data []byte) error {
+			var record PlayRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return fmt.Errorf("error reading plays: %v", err)
+			}
+			if !q.matches(record) {
+				return nil
+			}
+			return fn(record)
+		})
+	})
+}
+
+// Plays returns the plays matching the query, oldest first.
+func (h *History) Plays(q HistoryQuery) ([]PlayRecord, error) {
+	var plays []PlayRecord
+	err := h.EachPlay(q, func(record PlayRecord) error {
+		plays = append(plays, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// IDs follow insertion order, which may differ from play time for imported plays
+	sort.SliceStable(plays, func(i, j int) bool { return plays[i].PlayedAt.Before(plays[j].PlayedAt) })
+	if q.Limit > 0 && len(plays) > q.Limit {
+		plays = plays[len(plays)-q.Limit:]
+	}
+	return plays, nil
+}
+
+// itob encodes an ID as a big-endian key, so keys sort by ID.
+func itob(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
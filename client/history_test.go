@@ -0,0 +1,263 @@
+package client
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestHistory(t *testing.T) *History {
+	t.Helper()
+	history, err := OpenHistory(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("OpenHistory failed: %v", err)
+	}
+	t.Cleanup(func() { history.Close() })
+	return history
+}
+
+func testPlay(songID int, judge, score, patch float64, playedAt time.Time) PlayRecord {
+	return PlayRecord{
+		PlayedAt:   playedAt,
+		ScreenType: ResultScreen.String(),
+		SongID:     songID,
+		Line:       4,
+		Difficulty: "HARD",
+		Level:      12,
+		Judge:      judge,
+		Score:      score,
+		Patch:      patch,
+		Record:     true,
+		Confirmed:  true,
+	}
+}
+
+func TestNewPlayRecord(t *testing.T) {
+	report := consistentReport()
+	report.ScreenType = ResultScreen
+	report.Score = 1000000
+	report.PatternObject.Line = 4
+	report.Confidence[FieldScore] = 1
+	record := NewPlayRecord(report, time.Now(), "shot.png")
+	if !record.Record || record.Notes == nil || *record.Notes != report.Notes {
+		t.Errorf("expected a full record with notes, got %+v", record)
+	}
+
+	delete(report.Confidence, FieldNotes)
+	delete(report.Confidence, FieldScore)
+	record = NewPlayRecord(report, time.Now(), "")
+	if record.Record || record.Notes != nil {
+		t.Errorf("expected a partial record without notes, got %+v", record)
+	}
+}
+
+func TestHistoryBests(t *testing.T) {
+	history := openTestHistory(t)
+	start := time.Date(2025, 11, 1, 20, 0, 0, 0, time.UTC)
+	plays := []PlayRecord{
+		testPlay(1, 98.5, 950000, 500, start),
+		testPlay(1, 99.1, 940000, 510, start.Add(time.Hour)),
+		testPlay(2, 90, 800000, 300, start.Add(2*time.Hour)),
+	}
+	partial := testPlay(1, 100, 1000000, 600, start.Add(3*time.Hour))
+	partial.Record = false
+	for _, p := range append(plays, partial) {
+		if _, err := history.Add(p); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	best, ok, err := history.Best(plays[0].Key())
+	if err != nil || !ok {
+		t.Fatalf("expected a best, got %v, %v", ok, err)
+	}
+	if best.Judge != 99.1 || best.Score != 950000 || best.Patch != 510 || best.Plays != 2 || !best.LastPlayed.Equal(plays[1].PlayedAt) {
+		t.Errorf("unexpected best %+v", best)
+	}
+	if _, ok, _ := history.Best(PatternKey{3, 4, "HARD"}); ok {
+		t.Error("expected no best for an unplayed pattern")
+	}
+
+	bests, err := history.Bests()
+	if err != nil || len(bests) != 2 || bests[0].SongID != 1 || bests[1].SongID != 2 {
+		t.Errorf("unexpected bests %+v, %v", bests, err)
+	}
+}
+
+func TestHistoryConfirm(t *testing.T) {
+	history := openTestHistory(t)
+	start := time.Date(2025, 11, 1, 20, 0, 0, 0, time.UTC)
+	if _, err := history.Add(testPlay(1, 98, 950000, 500, start)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	// A misread the player has not accepted yet
+	misread := testPlay(1, 100, 1000000, 600, start.Add(time.Hour))
+	misread.Confirmed = false
+	misread, err := history.Add(misread)
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if best, _, _ := history.Best(misread.Key()); best.Judge != 98 || best.Plays != 1 {
+		t.Errorf("expected the unconfirmed play to be left out of the best, got %+v", best)
+	}
+	plays, _ := history.Plays(HistoryQuery{ConfirmedOnly: true})
+	if len(plays) != 1 {
+		t.Errorf("expected 1 confirmed play, got %d", len(plays))
+	}
+
+	for range 2 {
+		if _, err := history.Confirm(misread.ID); err != nil {
+			t.Fatalf("Confirm failed: %v", err)
+		}
+	}
+	if best, _, _ := history.Best(misread.Key()); best.Judge != 100 || best.Plays != 2 {
+		t.Errorf("expected the confirmed play in the best once, got %+v", best)
+	}
+	if _, err := history.Confirm(99); err == nil {
+		t.Error("expected an error confirming a missing play")
+	}
+}
+
+func TestHistoryRemove(t *testing.T) {
+	history := openTestHistory(t)
+	start := time.Date(2025, 11, 1, 20, 0, 0, 0, time.UTC)
+	var added []PlayRecord
+	for _, p := range []PlayRecord{
+		testPlay(1, 98, 950000, 500, start),
+		testPlay(1, 100, 1000000, 600, start.Add(time.Hour)),
+		testPlay(2, 90, 800000, 300, start),
+	} {
+		record, err := history.Add(p)
+		if err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		added = append(added, record)
+	}
+
+	if err := history.Remove(added[1].ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if best, _, _ := history.Best(added[0].Key()); best.Judge != 98 || best.Patch != 500 || best.Plays != 1 {
+		t.Errorf("expected the best without the removed play, got %+v", best)
+	}
+	if err := history.Remove(added[0].ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, ok, _ := history.Best(added[0].Key()); ok {
+		t.Error("expected no best once every play is removed")
+	}
+	if best, ok, _ := history.Best(added[2].Key()); !ok || best.Judge != 90 {
+		t.Errorf("expected the best of another pattern to be kept, got %+v", best)
+	}
+	if err := history.Remove(added[0].ID); err == nil {
+		t.Error("expected an error removing a missing play")
+	}
+}
+
+func TestHistoryRebuildBests(t *testing.T) {
+	history := openTestHistory(t)
+	start := time.Date(2025, 11, 1, 20, 0, 0, 0, time.UTC)
+	unconfirmed := testPlay(1, 100, 1000000, 600, start.Add(time.Hour))
+	unconfirmed.Confirmed = false
+	for _, p := range []PlayRecord{testPlay(1, 98, 950000, 500, start), unconfirmed, testPlay(2, 90, 800000, 300, start)} {
+		if _, err := history.Add(p); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := history.RebuildBests(); err != nil {
+		t.Fatalf("RebuildBests failed: %v", err)
+	}
+	bests, err := history.Bests()
+	if err != nil || len(bests) != 2 || bests[0].Judge != 98 || bests[0].Plays != 1 || bests[1].Judge != 90 {
+		t.Errorf("unexpected bests %+v, %v", bests, err)
+	}
+}
+
+func TestHistoryPlays(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.db")
+	history, err := OpenHistory(path)
+	if err != nil {
+		t.Fatalf("OpenHistory failed: %v", err)
+	}
+	start := time.Date(2025, 11, 1, 20, 0, 0, 0, time.UTC)
+	// Added out of order, as an import would
+	for _, p := range []PlayRecord{
+		testPlay(1, 99, 0, 0, start.Add(2*time.Hour)),
+		testPlay(1, 97, 0, 0, start),
+		testPlay(2, 98, 0, 0, start.Add(time.Hour)),
+	} {
+		if _, err := history.Add(p); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	history.Close()
+
+	// Plays persist across opening the database
+	history, err = OpenHistory(path)
+	if err != nil {
+		t.Fatalf("OpenHistory failed: %v", err)
+	}
+	defer history.Close()
+
+	for _, tt := range []struct {
+		name     string
+		query    HistoryQuery
+		expected []float64
+	}{
+		{"all", HistoryQuery{}, []float64{97, 98, 99}},
+		{"song", HistoryQuery{SongID: 1}, []float64{97, 99}},
+		{"since", HistoryQuery{Since: start.Add(time.Hour)}, []float64{98, 99}},
+		{"until", HistoryQuery{Until: start.Add(time.Hour)}, []float64{97}},
+		{"limit", HistoryQuery{Limit: 2}, []float64{98, 99}},
+		{"difficulty", HistoryQuery{Difficulty: "EASY"}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			plays, err := history.Plays(tt.query)
+			if err != nil {
+				t.Fatalf("Plays failed: %v", err)
+			}
+			var judges []float64
+			for _, p := range plays {
+				judges = append(judges, p.Judge)
+			}
+			if len(judges) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, judges)
+			}
+			for i := range judges {
+				if judges[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, judges)
+				}
+			}
+		})
+	}
+}
+
+func TestHistorySaveScreenshot(t *testing.T) {
+	history := openTestHistory(t)
+	path, err := history.SaveScreenshot(image.NewRGBA(image.Rect(0, 0, 4, 4)), time.Now())
+	if err != nil {
+		t.Fatalf("SaveScreenshot failed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("screenshot not written: %v", err)
+	}
+
+	// Only the latest screenshots are kept
+	history.ScreenshotLimit = 2
+	start := time.Now().Add(time.Hour)
+	var paths []string
+	for i := range 3 {
+		path, err := history.SaveScreenshot(image.NewRGBA(image.Rect(0, 0, 4, 4)), start.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatalf("SaveScreenshot failed: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	kept, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.png"))
+	if len(kept) != 2 || kept[0] != paths[1] || kept[1] != paths[2] {
+		t.Errorf("expected the 2 latest screenshots, got %v", kept)
+	}
+}
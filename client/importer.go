@@ -0,0 +1,456 @@
+package client
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Archive fields an import column can be mapped to.
+const (
+	ImportTitle      = "title"
+	ImportSongID     = "songID"
+	ImportLine       = "line"
+	ImportDifficulty = "difficulty"
+	ImportLevel      = "level"
+	ImportJudge      = "judge"
+	ImportScore      = "score"
+	ImportPatch      = "patch"
+	ImportDecodedAt  = "decodedAt"
+	ImportFullCombo  = "fullCombo"
+	ImportMaxPatch   = "maxPatch"
+)
+
+// importAliases are the column names recognised for every field, compared after normaliseTitle.
+// They include the export columns, the archive JSON keys and common spreadsheet headings.
+var importAliases = map[string][]string{
+	ImportTitle:      {"title", "song", "songtitle", "곡", "곡명", "제목"},
+	ImportSongID:     {"songid", "song_id"},
+	ImportLine:       {"line", "lines", "라인"},
+	ImportDifficulty: {"difficulty", "diff", "난이도"},
+	ImportLevel:      {"level", "lv", "레벨"},
+	ImportJudge:      {"judge", "accuracy", "정확도", "판정"},
+	ImportScore:      {"score", "점수"},
+	ImportPatch:      {"patch", "패치"},
+	ImportDecodedAt:  {"decodedat", "decoded_at", "date", "playedat", "날짜"},
+	ImportFullCombo:  {"fullcombo", "is_full_combo", "fc", "풀콤보"},
+	ImportMaxPatch:   {"maxpatch", "is_max_patch", "mp"},
+}
+
+// titleMatchThreshold is the lowest similarity at which a title is matched to a song.
+const titleMatchThreshold = 0.8
+
+// ImportMapping maps archive fields to the columns of the imported file.
+type ImportMapping map[string]string
+
+// GuessImportMapping maps every field to the first column of the header named like it.
+func GuessImportMapping(header []string) ImportMapping {
+	mapping := make(ImportMapping)
+	for field, aliases := range importAliases {
+		for _, column := range header {
+			name := normaliseTitle(column)
+			for _, alias := range aliases {
+				if name == normaliseTitle(alias) {
+					mapping[field] = column
+					break
+				}
+			}
+			if _, ok := mapping[field]; ok {
+				break
+			}
+		}
+	}
+	return mapping
+}
+
+// ParseImportMapping parses field=column pairs separated by commas, as given on the command line.
+func ParseImportMapping(s string) (ImportMapping, error) {
+	mapping := make(ImportMapping)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("mapping %q is not field=column", pair)
+		}
+		field = strings.TrimSpace(field)
+		if _, known := importAliases[field]; !known {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		mapping[field] = strings.TrimSpace(column)
+	}
+	return mapping, nil
+}
+
+// ImportTable is the header and rows of an imported file.
+type ImportTable struct {
+	Header []string
+	Rows   []map[string]string
+}
+
+// ReadImportTable reads a CSV file with a header row, or JSON holding an array of
+// objects or one object per line. The format is detected from the first character.
+func ReadImportTable(r io.Reader) (ImportTable, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ImportTable{}, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return readJSONTable(trimmed)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return ImportTable{}, fmt.Errorf("error parsing CSV: %v", err)
+	}
+	if len(records) == 0 {
+		return ImportTable{}, fmt.Errorf("empty file")
+	}
+	table := ImportTable{Header: records[0]}
+	for _, record := range records[1:] {
+		row := make(map[string]string)
+		for i, value := range record {
+			if i < len(table.Header) {
+				row[table.Header[i]] = value
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+func readJSONTable(data []byte) (ImportTable, error) {
+	var objects []map[string]any
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &objects); err != nil {
+			return ImportTable{}, fmt.Errorf("error parsing JSON: %v", err)
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for decoder.More() {
+			var object map[string]any
+			if err := decoder.Decode(&object); err != nil {
+				return ImportTable{}, fmt.Errorf("error parsing JSON: %v", err)
+			}
+			objects = append(objects, object)
+		}
+	}
+
+	var table ImportTable
+	columns := make(map[string]bool)
+	for _, object := range objects {
+		row := make(map[string]string)
+		for key, value := range object {
+			if !columns[key] {
+				columns[key] = true
+				table.Header = append(table.Header, key)
+			}
+			if value != nil {
+				row[key] = formatValue(value)
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	sort.Strings(table.Header)
+	return table, nil
+}
+
+// Statuses of an import entry.
+const (
+	ImportNew      = "new"
+	ImportImproved = "improved"
+	// ImportConflict is a record better than the archive in a value but worse in another.
+	// It is not uploaded, as that would lower the other value on the server.
+	ImportConflict  = "conflict"
+	ImportUnchanged = "unchanged"
+	ImportInvalid   = "invalid"
+)
+
+// ImportEntry is a row of the imported file with the archive record it becomes.
+type ImportEntry struct {
+	// Row is the 1-based row number, not counting the header.
+	Row     int
+	Archive Archive
+	Song    Song
+	// TitleMatch is the similarity of the title to the matched song, 1 for an exact or ID match.
+	TitleMatch float64
+	// Existing is the record of the pattern already in the archive, if any.
+	Existing *Archive
+	Status   string
+	Err      error
+}
+
+// ImportPlan is the dry run of an import.
+type ImportPlan struct {
+	Entries []ImportEntry
+}
+
+// PlanImport converts the rows into archive records of the decoder and compares them with the existing archive.
+// Songs are matched by ID, or by a fuzzy match of the title, and patterns are checked against the cache.
+// Of the rows of the same pattern only the best is kept, by patch, then judge, then score;
+// values of different rows are never combined.
+func PlanImport(table ImportTable, mapping ImportMapping, cache *Cache, existing []Archive, decoder string) ImportPlan {
+	current := make(map[PatternKey]Archive)
+	for _, a := range BestArchives(existing) {
+		current[ArchiveKey(a)] = a
+	}
+
+	var plan ImportPlan
+	index := make(map[PatternKey]int)
+	for i, row := range table.Rows {
+		entry := parseImportRow(row, mapping, cache, decoder)
+		entry.Row = i + 1
+		if entry.Err != nil {
+			entry.Status = ImportInvalid
+			plan.Entries = append(plan.Entries, entry)
+			continue
+		}
+		key := ArchiveKey(entry.Archive)
+		if j, ok := index[key]; ok {
+			if rankAbove(entry.Archive, plan.Entries[j].Archive) {
+				plan.Entries[j] = entry
+			}
+			continue
+		}
+		index[key] = len(plan.Entries)
+		plan.Entries = append(plan.Entries, entry)
+	}
+
+	for i := range plan.Entries {
+		e := &plan.Entries[i]
+		if e.Status == ImportInvalid {
+			continue
+		}
+		a, ok := current[ArchiveKey(e.Archive)]
+		switch {
+		case !ok:
+			e.Status = ImportNew
+		case beats(e.Archive, a):
+			e.Existing = &a
+			e.Status = ImportImproved
+		case betterValues(e.Archive, a):
+			e.Existing = &a
+			e.Status = ImportConflict
+		default:
+			e.Existing = &a
+			e.Status = ImportUnchanged
+		}
+	}
+	return plan
+}
+
+// parseImportRow converts a row into an archive record, validated against the cache.
+func parseImportRow(row map[string]string, mapping ImportMapping, cache *Cache, decoder string) ImportEntry {
+	value := func(field string) string {
+		return strings.TrimSpace(row[mapping[field]])
+	}
+	fail := func(format string, args ...any) ImportEntry {
+		return ImportEntry{Err: fmt.Errorf(format, args...)}
+	}
+	entry := ImportEntry{Archive: Archive{Decoder: decoder}}
+
+	if id := value(ImportSongID); id != "" {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return fail("invalid song ID %q", id)
+		}
+		song, ok := cache.FindSong(n)
+		if !ok {
+			return fail("unknown song ID %d", n)
+		}
+		entry.Song, entry.TitleMatch = song, 1
+	} else if title := value(ImportTitle); title != "" {
+		song, similarity, ok := MatchSongTitle(title, cache.Songs)
+		if !ok {
+			return fail("no song matches %q", title)
+		}
+		entry.Song, entry.TitleMatch = song, similarity
+	} else {
+		return fail("no title or song ID")
+	}
+
+	a := &entry.Archive
+	a.SongID = entry.Song.ID
+	line, err := strconv.Atoi(strings.TrimSuffix(strings.ToUpper(value(ImportLine)), "LINES"))
+	if err != nil {
+		return fail("invalid line %q", value(ImportLine))
+	}
+	a.Line = line
+	a.Difficulty = strings.ToUpper(value(ImportDifficulty))
+	pattern, ok := cache.FindPattern(ArchiveKey(*a))
+	if !ok {
+		return fail("%s has no %dL %s pattern", entry.Song.Title, a.Line, a.Difficulty)
+	}
+	a.Level = pattern.Level
+	if level := value(ImportLevel); level != "" {
+		// "14", "Lv14", "Lv.14" or "Lv. 14"
+		number := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(level), "lv"), "."))
+		if n, err := strconv.Atoi(number); err != nil || n != pattern.Level {
+			return fail("level %q does not match level %d of %s %dL %s", level, pattern.Level, entry.Song.Title, a.Line, a.Difficulty)
+		}
+	}
+
+	for _, f := range []struct {
+		field string
+		value *float64
+	}{{ImportJudge, &a.Judge}, {ImportScore, &a.Score}, {ImportPatch, &a.Patch}} {
+		s := strings.NewReplacer(",", "", "%", "").Replace(value(f.field))
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fail("invalid %s %q", f.field, value(f.field))
+		}
+		*f.value = v
+	}
+	if a.Judge < 0 || a.Judge > 100 {
+		return fail("judge %.4f is out of range", a.Judge)
+	}
+	a.FullCombo = parseImportBool(value(ImportFullCombo))
+	a.MaxPatch = parseImportBool(value(ImportMaxPatch))
+
+	// Rows without a date are left undated rather than dated by the import
+	if date := value(ImportDecodedAt); date != "" {
+		parsed, ok := parseImportDate(date)
+		if !ok {
+			return fail("invalid date %q", date)
+		}
+		a.DecodedAt = parsed
+	}
+	return entry
+}
+
+func parseImportBool(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "1", "yes", "y", "o", "fc", "mp":
+		return true
+	}
+	return false
+}
+
+// parseImportDate normalises the date layouts of spreadsheets to the archive layout.
+func parseImportDate(s string) (string, bool) {
+	for _, layout := range []string{time.RFC3339, archiveDateLayout, "2006/01/02", "2006.01.02", "2006-01-02 15:04:05", "2006/1/2", "2006. 1. 2."} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(archiveDateLayout), true
+		}
+	}
+	return "", false
+}
+
+// Counts returns the number of entries of every status.
+func (p ImportPlan) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, e := range p.Entries {
+		counts[e.Status]++
+	}
+	return counts
+}
+
+// Diff describes every entry of the plan, one line each.
+func (p ImportPlan) Diff() []string {
+	lines := make([]string, 0, len(p.Entries))
+	for _, e := range p.Entries {
+		a := e.Archive
+		switch e.Status {
+		case ImportInvalid:
+			lines = append(lines, fmt.Sprintf("! row %d: %v", e.Row, e.Err))
+			continue
+		case ImportNew:
+			lines = append(lines, fmt.Sprintf("+ %s %dL %s: %.4f%% / %.0f / %.2f", e.Song.Title, a.Line, a.Difficulty, a.Judge, a.Score, a.Patch))
+		case ImportImproved:
+			x := e.Existing
+			lines = append(lines, fmt.Sprintf("~ %s %dL %s: %.4f%% / %.0f / %.2f -> %.4f%% / %.0f / %.2f", e.Song.Title, a.Line, a.Difficulty,
+				x.Judge, x.Score, x.Patch, a.Judge, a.Score, a.Patch))
+		case ImportConflict:
+			x := e.Existing
+			lines = append(lines, fmt.Sprintf("? %s %dL %s: %.4f%% / %.0f / %.2f vs %.4f%% / %.0f / %.2f, not uploaded", e.Song.Title, a.Line, a.Difficulty,
+				x.Judge, x.Score, x.Patch, a.Judge, a.Score, a.Patch))
+		case ImportUnchanged:
+			lines = append(lines, fmt.Sprintf("= %s %dL %s", e.Song.Title, a.Line, a.Difficulty))
+		}
+		if e.TitleMatch < 1 {
+			lines[len(lines)-1] += fmt.Sprintf(" (title %.0f%% match)", e.TitleMatch*100)
+		}
+		if a.DecodedAt == "" {
+			lines[len(lines)-1] += " (undated)"
+		}
+	}
+	return lines
+}
+
+// Upload sends the new and improved entries as they were read. Improved records are not worse
+// than the existing ones in any value, so none goes down on the server.
+// Refused uploads are returned and do not stop the import.
+func (p ImportPlan) Upload(server ArchiveServer) ([]Archive, []SyncFailure) {
+	var result SyncResult
+	for _, e := range p.Entries {
+		if e.Status == ImportNew || e.Status == ImportImproved {
+			result.push(server, e.Archive)
+		}
+	}
+	return result.Pushed, result.Failed
+}
+
+// MatchSongTitle finds the song whose title is most similar to title, ignoring case, spaces and punctuation.
+// The bool is false when no title reaches the match threshold.
+func MatchSongTitle(title string, songs []Song) (Song, float64, bool) {
+	query := normaliseTitle(title)
+	var best Song
+	bestSimilarity := 0.0
+	for _, s := range songs {
+		similarity := titleSimilarity(query, normaliseTitle(s.Title))
+		if similarity > bestSimilarity {
+			best, bestSimilarity = s, similarity
+		}
+	}
+	return best, bestSimilarity, bestSimilarity >= titleMatchThreshold
+}
+
+// normaliseTitle lowercases the letters and digits of s and drops everything else.
+func normaliseTitle(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// titleSimilarity is 1 minus the edit distance of a and b over the length of the longer.
+func titleSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(ra, rb))/float64(max(len(ra), len(rb)))
+}
+
+// editDistance is the Levenshtein distance of a and b.
+func editDistance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMatchSongTitle(t *testing.T) {
+	songs := []Song{{ID: 1, Title: "CHEWiNG LOVE"}, {ID: 2, Title: "Firework"}, {ID: 3, Title: "별의 노래"}}
+	for _, tt := range []struct {
+		title    string
+		expected int
+	}{
+		{"chewing love", 1},
+		{"Chewing Lov", 1},
+		{"FIREWORKS", 2},
+		{"별의노래", 3},
+	} {
+		song, similarity, ok := MatchSongTitle(tt.title, songs)
+		if !ok || song.ID != tt.expected {
+			t.Errorf("%q: expected song %d, got %d (%.2f, %v)", tt.title, tt.expected, song.ID, similarity, ok)
+		}
+	}
+	if song, _, ok := MatchSongTitle("Something Else", songs); ok {
+		t.Errorf("expected no match, got %q", song.Title)
+	}
+}
+
+func TestGuessImportMapping(t *testing.T) {
+	mapping := GuessImportMapping([]string{"곡명", "Line", "난이도", "Lv", "정확도", "Score", "Patch", "FC", "날짜"})
+	expected := ImportMapping{
+		ImportTitle: "곡명", ImportLine: "Line", ImportDifficulty: "난이도", ImportLevel: "Lv", ImportJudge: "정확도",
+		ImportScore: "Score", ImportPatch: "Patch", ImportFullCombo: "FC", ImportDecodedAt: "날짜",
+	}
+	if len(mapping) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, mapping)
+	}
+	for field, column := range expected {
+		if mapping[field] != column {
+			t.Errorf("%s: expected %q, got %q", field, column, mapping[field])
+		}
+	}
+
+	// An export of the history maps back, using the song ID and not the play ID
+	mapping = GuessImportMapping(PlayColumns)
+	if mapping[ImportSongID] != "songID" || mapping[ImportDecodedAt] != "playedAt" {
+		t.Errorf("unexpected mapping of the history columns %v", mapping)
+	}
+
+	if _, err := ParseImportMapping("title=곡, judge=정확도"); err != nil {
+		t.Errorf("ParseImportMapping failed: %v", err)
+	}
+	if _, err := ParseImportMapping("rank=랭크"); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestReadImportTable(t *testing.T) {
+	for name, input := range map[string]string{
+		"csv":        "\xef\xbb\xbftitle,judge\nFirework,99.5\n",
+		"json array": `[{"title": "Firework", "judge": 99.5}]`,
+		"json lines": "{\"title\": \"Firework\", \"judge\": 99.5}\n",
+	} {
+		table, err := ReadImportTable(strings.NewReader(input))
+		if err != nil {
+			t.Errorf("%s: ReadImportTable failed: %v", name, err)
+			continue
+		}
+		if len(table.Rows) != 1 || table.Rows[0]["title"] != "Firework" || table.Rows[0]["judge"] != "99.5" {
+			t.Errorf("%s: unexpected table %+v", name, table)
+		}
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	csv := `곡명,라인,난이도,레벨,정확도,점수,패치,FC,날짜
+Chewing Love,4,hard,10,99.5%,"990,000",512.34,O,2024/03/01
+Chewing Love,4,HARD,Lv.10,98.0%,"995,000",500,,2024/03/02
+Firework,6,OVER,14,97.1234,900000,600,,2024.03.03
+별의 노래,4,EASY,3,90,800000,100,,2024-03-04
+Firework,4,HARD,10,90,800000,100,,2024-03-05
+Unknown Song,4,HARD,10,90,800000,100,,2024-03-05
+별의 노래,4,EASY,5,90,800000,100,,2024-03-04
+별의 노래,4,EASY,Lv. 3,96,950000,210,,
+`
+	table, err := ReadImportTable(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("ReadImportTable failed: %v", err)
+	}
+	cache := &Cache{
+		Songs: []Song{{ID: 1, Title: "CHEWiNG LOVE"}, {ID: 2, Title: "Firework"}, {ID: 3, Title: "별의 노래"}},
+		Patterns: []Pattern{
+			{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10},
+			{SongID: 2, Line: 6, Difficulty: "OVER", Level: 14},
+			{SongID: 3, Line: 4, Difficulty: "EASY", Level: 3},
+		},
+	}
+	existing := []Archive{
+		{SongID: 2, Line: 6, Difficulty: "OVER", Level: 14, Judge: 98, Score: 950000, Patch: 610},
+		{SongID: 3, Line: 4, Difficulty: "EASY", Level: 3, Judge: 95, Score: 900000, Patch: 200},
+	}
+	plan := PlanImport(table, GuessImportMapping(table.Header), cache, existing, "tester")
+
+	statuses := make([]string, len(plan.Entries))
+	for i, e := range plan.Entries {
+		statuses[i] = e.Status
+	}
+	expected := []string{ImportNew, ImportUnchanged, ImportImproved, ImportInvalid, ImportInvalid, ImportInvalid}
+	if strings.Join(statuses, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected statuses %v, got %v", expected, statuses)
+	}
+	// Rows of a pattern are not combined: the best row is kept as it is
+	best := plan.Entries[0].Archive
+	if best.Judge != 99.5 || best.Score != 990000 || best.Patch != 512.34 || !best.FullCombo || best.Level != 10 || best.DecodedAt != "2024-03-01" || best.Decoder != "tester" {
+		t.Errorf("unexpected best row %+v", best)
+	}
+	if undated := plan.Entries[2]; undated.Row != 8 || undated.Archive.DecodedAt != "" {
+		t.Errorf("expected the undated row to be kept without a date, got %+v", undated)
+	}
+	if plan.Entries[1].Archive.Judge != 97.1234 || plan.Entries[1].Existing == nil {
+		t.Errorf("unexpected entry %+v", plan.Entries[1])
+	}
+	if plan.Entries[5].Row != 7 || !strings.Contains(plan.Entries[5].Err.Error(), "level") {
+		t.Errorf("expected a level mismatch on row 7, got %+v", plan.Entries[5])
+	}
+	if counts := plan.Counts(); counts[ImportInvalid] != 3 || counts[ImportNew] != 1 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+	if diff := plan.Diff(); len(diff) != len(plan.Entries) || !strings.HasPrefix(diff[0], "+ CHEWiNG LOVE 4L HARD") || !strings.HasSuffix(diff[2], "(undated)") {
+		t.Errorf("unexpected diff %q", diff)
+	}
+}
+
+func TestImportUpload(t *testing.T) {
+	cache := &Cache{
+		Songs: []Song{{ID: 1, Title: "CHEWiNG LOVE"}, {ID: 2, Title: "Firework"}, {ID: 3, Title: "별의 노래"}},
+		Patterns: []Pattern{
+			{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10},
+			{SongID: 2, Line: 6, Difficulty: "OVER", Level: 14},
+			{SongID: 3, Line: 4, Difficulty: "EASY", Level: 3},
+		},
+	}
+	existing := []Archive{
+		{SongID: 2, Line: 6, Difficulty: "OVER", Level: 14, Judge: 98, Score: 950000, Patch: 610, DecodedAt: "2025-01-01"},
+		{SongID: 3, Line: 4, Difficulty: "EASY", Level: 3, Judge: 95, Score: 900000, Patch: 200, DecodedAt: "2025-01-01"},
+	}
+	table := ImportTable{Rows: []map[string]string{
+		{"title": "Firework", "line": "6", "difficulty": "OVER", "judge": "99", "score": "960000", "patch": "615", "date": "2024-01-01"},
+		{"title": "CHEWiNG LOVE", "line": "4", "difficulty": "HARD", "judge": "90", "score": "800000", "patch": "100", "date": "2024-01-01"},
+		// Better judge, worse patch
+		{"title": "별의 노래", "line": "4", "difficulty": "EASY", "judge": "96", "score": "900000", "patch": "190", "date": "2024-01-01"},
+	}}
+	mapping := GuessImportMapping([]string{"title", "line", "difficulty", "judge", "score", "patch", "date"})
+	plan := PlanImport(table, mapping, cache, existing, "tester")
+	if plan.Entries[2].Status != ImportConflict {
+		t.Errorf("expected a conflict, got %+v", plan.Entries[2])
+	}
+
+	server := newFakeArchiveServer(existing...)
+	pushed, failed := plan.Upload(server)
+	if len(pushed) != 2 || len(failed) != 0 {
+		t.Fatalf("expected 2 uploads, got %d (%v)", len(pushed), failed)
+	}
+	improved := server.archives[PatternKey{2, 6, "OVER"}]
+	if improved.Judge != 99 || improved.Score != 960000 || improved.Patch != 615 || improved.DecodedAt != "2024-01-01" {
+		t.Errorf("expected the improved record as it was read, got %+v", improved)
+	}
+	if server.archives[PatternKey{3, 4, "EASY"}].Patch != 200 {
+		t.Error("expected the conflicting record not to be uploaded")
+	}
+
+	var buf bytes.Buffer
+	if err := ExportArchives(&buf, FormatCSV, []Archive{improved}, cache); err != nil {
+		t.Fatalf("ExportArchives failed: %v", err)
+	}
+	// An export imports back unchanged
+	table, err := ReadImportTable(&buf)
+	if err != nil {
+		t.Fatalf("ReadImportTable failed: %v", err)
+	}
+	plan = PlanImport(table, GuessImportMapping(table.Header), cache, []Archive{improved}, "tester")
+	if len(plan.Entries) != 1 || plan.Entries[0].Status != ImportUnchanged {
+		t.Errorf("expected the export to import unchanged, got %+v", plan.Entries)
+	}
+}
@@ -0,0 +1,275 @@
+package client
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/corona10/goimagehash"
+)
+
+// colorHistBins is the number of bins per RGB channel in a jacket colour histogram.
+// The histogram is joint, so it has colorHistBins^3 entries.
+const colorHistBins = 4
+
+// jacketScoreThreshold is the highest combined score accepted as a jacket match.
+// Matches scored on the pHash alone keep the pHashThreshold limit instead, as it is looser here.
+const jacketScoreThreshold = 0.05
+
+// Weights of each hash in the combined jacket score.
+// Hashes missing from either side are left out and the remaining weights are renormalised.
+const (
+	pHashWeight     = 0.4
+	dHashWeight     = 0.25
+	aHashWeight     = 0.15
+	colorHistWeight = 0.2
+)
+
+// JacketHashes holds every hash of a jacket image in the format used by Song.
+type JacketHashes struct {
+	PHash     string `json:"pHash"`
+	DHash     string `json:"dHash"`
+	AHash     string `json:"aHash"`
+	ColorHist string `json:"colorHist"`
+}
+
+// JacketMatch is the result of matching a jacket image against the song list.
+// Margin is the score gap to the best candidate of a different song.
+type JacketMatch struct {
+	Song          Song
+	Plus          bool
+	Score         float64
+	PHashDistance int
+	Margin        float64
+	// PHashOnly is set when no hash but the pHash was present on both sides.
+	PHashOnly bool
+}
+
+// Accepted reports whether the match is close enough to identify the song.
+func (m JacketMatch) Accepted() bool {
+	if m.PHashOnly {
+		return m.PHashDistance <= pHashThreshold
+	}
+	return m.Score <= jacketScoreThreshold
+}
+
+type jacketFingerprint struct {
+	pHash     *goimagehash.ImageHash
+	dHash     *goimagehash.ImageHash
+	aHash     *goimagehash.ImageHash
+	colorHist []float64
+}
+
+type jacketCandidate struct {
+	song        Song
+	plus        bool
+	fingerprint jacketFingerprint
+}
+
+// ComputeJacketHashes calculates the perception, difference and average hashes
+// and the coarse colour histogram of a jacket image.
+func ComputeJacketHashes(img image.Image) (JacketHashes, error) {
+	fp, err := computeJacketFingerprint(img)
+	if err != nil {
+		return JacketHashes{}, err
+	}
+	return JacketHashes{
+		PHash:     formatHash(fp.pHash),
+		DHash:     formatHash(fp.dHash),
+		AHash:     formatHash(fp.aHash),
+		ColorHist: encodeColorHist(fp.colorHist),
+	}, nil
+}
+
+func computeJacketFingerprint(img image.Image) (jacketFingerprint, error) {
+	pHash, err := goimagehash.PerceptionHash(img)
+	if err != nil {
+		return jacketFingerprint{}, fmt.Errorf("failed to calculate pHash: %v", err)
+	}
+	dHash, err := goimagehash.DifferenceHash(img)
+	if err != nil {
+		return jacketFingerprint{}, fmt.Errorf("failed to calculate dHash: %v", err)
+	}
+	aHash, err := goimagehash.AverageHash(img)
+	if err != nil {
+		return jacketFingerprint{}, fmt.Errorf("failed to calculate aHash: %v", err)
+	}
+	return jacketFingerprint{
+		pHash:     pHash,
+		dHash:     dHash,
+		aHash:     aHash,
+		colorHist: colorHistogram(img),
+	}, nil
+}
+
+func formatHash(hash *goimagehash.ImageHash) string {
+	return fmt.Sprintf("%016x", hash.GetHash())
+}
+
+// parseHash converts a hex hash string into an ImageHash of the given kind.
+// It returns nil for an empty or malformed string.
+func parseHash(hash string, kind goimagehash.Kind) *goimagehash.ImageHash {
+	if hash == "" {
+		return nil
+	}
+	value := convertPythonHashToGoHash(hash)
+	if value == 0 {
+		return nil
+	}
+	return goimagehash.NewImageHash(value, kind)
+}
+
+// colorHistogram returns a normalised joint RGB histogram of img.
+func colorHistogram(img image.Image) []float64 {
+	hist := make([]float64, colorHistBins*colorHistBins*colorHistBins)
+	bounds := img.Bounds()
+	total := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			ri := int(r>>8) * colorHistBins / 256
+			gi := int(g>>8) * colorHistBins / 256
+			bi := int(b>>8) * colorHistBins / 256
+			hist[(ri*colorHistBins+gi)*colorHistBins+bi]++
+			total++
+		}
+	}
+	if total == 0 {
+		return hist
+	}
+	for i := range hist {
+		hist[i] /= float64(total)
+	}
+	return hist
+}
+
+// encodeColorHist quantises each bin to a byte and hex-encodes the result.
+func encodeColorHist(hist []float64) string {
+	data := make([]byte, len(hist))
+	for i, v := range hist {
+		data[i] = byte(math.Round(math.Min(math.Max(v, 0), 1) * 255))
+	}
+	return hex.EncodeToString(data)
+}
+
+func decodeColorHist(hist string) []float64 {
+	if hist == "" {
+		return nil
+	}
+	data, err := hex.DecodeString(hist)
+	if err != nil || len(data) != colorHistBins*colorHistBins*colorHistBins {
+		return nil
+	}
+	values := make([]float64, len(data))
+	sum := 0.0
+	for i, b := range data {
+		values[i] = float64(b)
+		sum += values[i]
+	}
+	if sum == 0 {
+		return nil
+	}
+	for i := range values {
+		values[i] /= sum
+	}
+	return values
+}
+
+// colorHistDistance returns the total variation distance between two histograms, in [0, 1].
+func colorHistDistance(a []float64, b []float64) float64 {
+	distance := 0.0
+	for i := range a {
+		distance += math.Abs(a[i] - b[i])
+	}
+	return distance / 2
+}
+
+func buildJacketCandidates(cache *Cache) []jacketCandidate {
+	candidates := make([]jacketCandidate, 0, len(cache.Songs)*2)
+	for _, song := range cache.Songs {
+		normal := jacketFingerprint{
+			pHash:     parseHash(song.PHash, goimagehash.PHash),
+			dHash:     parseHash(song.DHash, goimagehash.DHash),
+			aHash:     parseHash(song.AHash, goimagehash.AHash),
+			colorHist: decodeColorHist(song.ColorHist),
+		}
+		if normal.pHash != nil {
+			candidates = append(candidates, jacketCandidate{song: song, fingerprint: normal})
+		}
+		plus := jacketFingerprint{
+			pHash:     parseHash(song.PlusPHash, goimagehash.PHash),
+			dHash:     parseHash(song.PlusDHash, goimagehash.DHash),
+			aHash:     parseHash(song.PlusAHash, goimagehash.AHash),
+			colorHist: decodeColorHist(song.PlusColorHist),
+		}
+		if plus.pHash != nil {
+			candidates = append(candidates, jacketCandidate{song: song, plus: true, fingerprint: plus})
+		}
+	}
+	return candidates
+}
+
+// jacketScore combines every hash present on both fingerprints into a weighted score in [0, 1].
+// Lower is better.
+func jacketScore(query jacketFingerprint, known jacketFingerprint) (float64, int, error) {
+	pDistance, err := query.pHash.Distance(known.pHash)
+	if err != nil {
+		return 0, 0, err
+	}
+	score := pHashWeight * float64(pDistance) / 64
+	weight := pHashWeight
+
+	if query.dHash != nil && known.dHash != nil {
+		distance, err := query.dHash.Distance(known.dHash)
+		if err != nil {
+			return 0, 0, err
+		}
+		score += dHashWeight * float64(distance) / 64
+		weight += dHashWeight
+	}
+	if query.aHash != nil && known.aHash != nil {
+		distance, err := query.aHash.Distance(known.aHash)
+		if err != nil {
+			return 0, 0, err
+		}
+		score += aHashWeight * float64(distance) / 64
+		weight += aHashWeight
+	}
+	if query.colorHist != nil && known.colorHist != nil {
+		score += colorHistWeight * colorHistDistance(query.colorHist, known.colorHist)
+		weight += colorHistWeight
+	}
+	return score / weight, pDistance, nil
+}
+
+// sharesMoreThanPHash reports whether both fingerprints hold any hash besides the pHash.
+func (f jacketFingerprint) sharesMoreThanPHash(other jacketFingerprint) bool {
+	return (f.dHash != nil && other.dHash != nil) || (f.aHash != nil && other.aHash != nil) ||
+		(f.colorHist != nil && other.colorHist != nil)
+}
+
+func matchJacket(candidates []jacketCandidate, query jacketFingerprint) (JacketMatch, error) {
+	if len(candidates) == 0 {
+		return JacketMatch{}, fmt.Errorf("no jacket hashes available")
+	}
+	best := JacketMatch{Score: math.Inf(1)}
+	runnerUp := math.Inf(1)
+	for _, candidate := range candidates {
+		score, pDistance, err := jacketScore(query, candidate.fingerprint)
+		if err != nil {
+			return JacketMatch{}, err
+		}
+		if score < best.Score {
+			if candidate.song.ID != best.Song.ID {
+				runnerUp = best.Score
+			}
+			best = JacketMatch{Song: candidate.song, Plus: candidate.plus, Score: score, PHashDistance: pDistance,
+				PHashOnly: !query.sharesMoreThanPHash(candidate.fingerprint)}
+		} else if score < runnerUp && candidate.song.ID != best.Song.ID {
+			runnerUp = score
+		}
+	}
+	best.Margin = runnerUp - best.Score
+	return best, nil
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"math"
+	"testing"
+)
+
+func TestBuildJacketCandidates(t *testing.T) {
+	cache := &Cache{
+		Songs: []Song{
+			{
+				ID:        1,
+				Title:     "Test Song 1",
+				PHash:     "c0c73d38273ed2c3",
+				PlusPHash: "a1b2c3d4e5f60708",
+				DHash:     "0f0f0f0f0f0f0f0f",
+			},
+			{
+				ID:        2,
+				Title:     "Test Song 2",
+				PHash:     "1234567890abcdef",
+				PlusPHash: "", // Empty hash
+			},
+		},
+	}
+
+	candidates := buildJacketCandidates(cache)
+
+	// Song 2 has no plus jacket, so only 3 candidates are expected.
+	if len(candidates) != 3 {
+		t.Fatalf("expected 3 candidates, got %d", len(candidates))
+	}
+
+	expected := []struct {
+		id    int
+		plus  bool
+		pHash string
+	}{
+		{1, false, "c0c73d38273ed2c3"},
+		{1, true, "a1b2c3d4e5f60708"},
+		{2, false, "1234567890abcdef"},
+	}
+	for i, e := range expected {
+		c := candidates[i]
+		if c.song.ID != e.id || c.plus != e.plus {
+			t.Errorf("candidate %d: expected song %d (plus=%v), got song %d (plus=%v)", i, e.id, e.plus, c.song.ID, c.plus)
+		}
+		if c.fingerprint.pHash.GetHash() != convertPythonHashToGoHash(e.pHash) {
+			t.Errorf("candidate %d: unexpected pHash %x", i, c.fingerprint.pHash.GetHash())
+		}
+	}
+
+	if candidates[0].fingerprint.dHash == nil {
+		t.Error("expected dHash on song 1 normal jacket")
+	}
+	if candidates[1].fingerprint.dHash != nil {
+		t.Error("expected no dHash on song 1 plus jacket")
+	}
+}
+
+func TestColorHistRoundTrip(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			if x < 5 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+
+	hist := colorHistogram(img)
+	decoded := decodeColorHist(encodeColorHist(hist))
+	if decoded == nil {
+		t.Fatal("failed to decode encoded histogram")
+	}
+	for i := range hist {
+		if math.Abs(hist[i]-decoded[i]) > 0.01 {
+			t.Errorf("bin %d: expected %.3f, got %.3f", i, hist[i], decoded[i])
+		}
+	}
+	if d := colorHistDistance(hist, decoded); d > 0.01 {
+		t.Errorf("expected near-zero distance after round trip, got %.3f", d)
+	}
+}
+
+// checkerImage draws a checkerboard of c on black.
+func checkerImage(c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			if (x/16+y/16)%2 == 0 {
+				img.Set(x, y, c)
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 0, 255})
+			}
+		}
+	}
+	return img
+}
+
+func songFromImage(t *testing.T, id int, img image.Image) Song {
+	t.Helper()
+	hashes, err := ComputeJacketHashes(img)
+	if err != nil {
+		t.Fatalf("ComputeJacketHashes failed: %v", err)
+	}
+	return Song{ID: id, PHash: hashes.PHash, DHash: hashes.DHash, AHash: hashes.AHash, ColorHist: hashes.ColorHist}
+}
+
+func TestMatchJacketSeparatesColourOnlyDifference(t *testing.T) {
+	// Both colours have the same luminance, so every grayscale hash is identical.
+	red := checkerImage(color.RGBA{200, 100, 100, 255})
+	green := checkerImage(color.RGBA{100, 151, 100, 255})
+
+	cache := &Cache{Songs: []Song{songFromImage(t, 1, red), songFromImage(t, 2, green)}}
+	if cache.Songs[0].PHash != cache.Songs[1].PHash {
+		t.Fatalf("expected identical pHash, got %s and %s", cache.Songs[0].PHash, cache.Songs[1].PHash)
+	}
+
+	query, err := computeJacketFingerprint(green)
+	if err != nil {
+		t.Fatalf("computeJacketFingerprint failed: %v", err)
+	}
+	match, err := matchJacket(buildJacketCandidates(cache), query)
+	if err != nil {
+		t.Fatalf("matchJacket failed: %v", err)
+	}
+	if match.Song.ID != 2 {
+		t.Errorf("expected song 2, got song %d (score %.3f)", match.Song.ID, match.Score)
+	}
+	if match.Score != 0 {
+		t.Errorf("expected score 0, got %.3f", match.Score)
+	}
+}
+
+func TestMatchJacketWithCompressionArtefacts(t *testing.T) {
+	resultImg, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+	jacket, err := cropImage(resultImg, image.Rect(122, 193, 522, 593))
+	if err != nil {
+		t.Fatalf("cropImage failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, jacket, &jpeg.Options{Quality: 40}); err != nil {
+		t.Fatalf("jpeg.Encode failed: %v", err)
+	}
+	compressed, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatalf("jpeg.Decode failed: %v", err)
+	}
+
+	cache := &Cache{Songs: []Song{
+		songFromImage(t, 1, jacket),
+		songFromImage(t, 2, checkerImage(color.RGBA{200, 100, 100, 255})),
+	}}
+	query, err := computeJacketFingerprint(compressed)
+	if err != nil {
+		t.Fatalf("computeJacketFingerprint failed: %v", err)
+	}
+	match, err := matchJacket(buildJacketCandidates(cache), query)
+	if err != nil {
+		t.Fatalf("matchJacket failed: %v", err)
+	}
+	if match.Song.ID != 1 {
+		t.Errorf("expected song 1, got song %d", match.Song.ID)
+	}
+	if !match.Accepted() {
+		t.Errorf("expected score within threshold, got %.3f", match.Score)
+	}
+}
+
+func TestJacketMatchAccepted(t *testing.T) {
+	tests := []struct {
+		match    JacketMatch
+		accepted bool
+	}{
+		{JacketMatch{Score: 2.0 / 64, PHashDistance: 2, PHashOnly: true}, true},
+		// A pHash-only match keeps the limit of the pHash matcher, though its score is below the threshold
+		{JacketMatch{Score: 3.0 / 64, PHashDistance: 3, PHashOnly: true}, false},
+		{JacketMatch{Score: 0.04, PHashDistance: 3}, true},
+		{JacketMatch{Score: 0.06, PHashDistance: 0}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.match.Accepted(); got != tt.accepted {
+			t.Errorf("Accepted() for %+v = %v, expected %v", tt.match, got, tt.accepted)
+		}
+	}
+}
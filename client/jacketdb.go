@@ -0,0 +1,81 @@
+package client
+
+import (
+	"image"
+	"sort"
+)
+
+// CollisionThreshold is the default score below which two jackets of different songs
+// are reported as a near-collision. It is twice the match threshold, so a collision
+// warns about jackets that noise on a screenshot could make ambiguous.
+const CollisionThreshold = 2 * jacketScoreThreshold
+
+// JacketCollision is a pair of jackets of different songs whose hashes are too close.
+type JacketCollision struct {
+	Song          Song
+	Plus          bool
+	Other         Song
+	OtherPlus     bool
+	Score         float64
+	PHashDistance int
+}
+
+// ExtractJacket crops the jacket from a select or result screenshot.
+// The anchors are searched for when the ROIs do not line up with the screenshot.
+func ExtractJacket(img image.Image, config *Config) (image.Image, ScreenType, error) {
+	targetConfig, layout, screenType, _, err := locateScreen(img, config, AnalyzeOptions{Calibrations: NewCalibrationCache()})
+	if err != nil {
+		return nil, UnknownScreen, err
+	}
+	jacketImage, err := cropJacket(img, targetConfig, layout, screenType)
+	if err != nil {
+		return nil, screenType, err
+	}
+	return jacketImage, screenType, nil
+}
+
+// SetJacketHashes stores the hashes on the normal or plus jacket fields of the song.
+func (s *Song) SetJacketHashes(hashes JacketHashes, plus bool) {
+	if plus {
+		s.PlusPHash = hashes.PHash
+		s.PlusDHash = hashes.DHash
+		s.PlusAHash = hashes.AHash
+		s.PlusColorHist = hashes.ColorHist
+		return
+	}
+	s.PHash = hashes.PHash
+	s.DHash = hashes.DHash
+	s.AHash = hashes.AHash
+	s.ColorHist = hashes.ColorHist
+}
+
+// FindJacketCollisions compares the jackets of every pair of different songs
+// and returns the pairs scoring at or below threshold, closest first.
+func FindJacketCollisions(songs []Song, threshold float64) ([]JacketCollision, error) {
+	candidates := buildJacketCandidates(&Cache{Songs: songs})
+	var collisions []JacketCollision
+	for i, a := range candidates {
+		for _, b := range candidates[i+1:] {
+			if a.song.ID == b.song.ID {
+				continue
+			}
+			score, pDistance, err := jacketScore(a.fingerprint, b.fingerprint)
+			if err != nil {
+				return nil, err
+			}
+			if score > threshold {
+				continue
+			}
+			collisions = append(collisions, JacketCollision{
+				Song:          a.song,
+				Plus:          a.plus,
+				Other:         b.song,
+				OtherPlus:     b.plus,
+				Score:         score,
+				PHashDistance: pDistance,
+			})
+		}
+	}
+	sort.SliceStable(collisions, func(i, j int) bool { return collisions[i].Score < collisions[j].Score })
+	return collisions, nil
+}
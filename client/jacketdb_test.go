@@ -0,0 +1,82 @@
+package client
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/corona10/goimagehash"
+)
+
+func TestExtractJacketFromWindowedCapture(t *testing.T) {
+	img, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+
+	jacket, screenType, err := ExtractJacket(windowedCapture(img), calibrationConfig)
+	if err != nil {
+		t.Fatalf("ExtractJacket failed: %v", err)
+	}
+	if screenType != ResultScreen {
+		t.Errorf("expected ResultScreen, got %v", screenType)
+	}
+	hashes, err := ComputeJacketHashes(jacket)
+	if err != nil {
+		t.Fatalf("ComputeJacketHashes failed: %v", err)
+	}
+	distance, err := parseHash(hashes.PHash, goimagehash.PHash).Distance(parseHash("97b438b0674f1d34", goimagehash.PHash))
+	if err != nil {
+		t.Fatalf("Distance failed: %v", err)
+	}
+	if distance > pHashThreshold {
+		t.Errorf("expected the jacket to match the reference pHash, got %s (distance %d)", hashes.PHash, distance)
+	}
+}
+
+// gradientImage returns a 64x64 image whose colour changes along one axis.
+func gradientImage(vertical bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			v := x
+			if vertical {
+				v = y
+			}
+			img.Set(x, y, color.RGBA{uint8(v * 4), uint8(255 - v*4), 40, 255})
+		}
+	}
+	return img
+}
+
+func TestFindJacketCollisions(t *testing.T) {
+	red := checkerImage(color.RGBA{200, 40, 40, 255})
+
+	// The jacket of song 1 is reused as the plus jacket of song 2
+	reused := songFromImage(t, 2, red)
+	reused.PlusPHash, reused.PlusDHash, reused.PlusAHash, reused.PlusColorHist = reused.PHash, reused.DHash, reused.AHash, reused.ColorHist
+	horizontal, err := ComputeJacketHashes(gradientImage(false))
+	if err != nil {
+		t.Fatalf("ComputeJacketHashes failed: %v", err)
+	}
+	reused.SetJacketHashes(horizontal, false)
+	// Both jackets of the same song never collide
+	same := songFromImage(t, 3, gradientImage(true))
+	same.PlusPHash, same.PlusDHash, same.PlusAHash, same.PlusColorHist = same.PHash, same.DHash, same.AHash, same.ColorHist
+	songs := []Song{songFromImage(t, 1, red), reused, same}
+
+	collisions, err := FindJacketCollisions(songs, CollisionThreshold)
+	if err != nil {
+		t.Fatalf("FindJacketCollisions failed: %v", err)
+	}
+	if len(collisions) != 1 {
+		t.Fatalf("expected 1 collision, got %+v", collisions)
+	}
+	c := collisions[0]
+	if c.Song.ID != 1 || c.Plus || c.Other.ID != 2 || !c.OtherPlus {
+		t.Errorf("unexpected collision: %+v", c)
+	}
+	if c.Score != 0 || c.PHashDistance != 0 {
+		t.Errorf("expected identical jackets, got score %v and distance %d", c.Score, c.PHashDistance)
+	}
+}
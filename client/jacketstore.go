@@ -0,0 +1,77 @@
+package client
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// JacketStore keeps the jackets cropped from analysed screenshots, so views can show
+// the jackets of songs the player has seen. Songs only carry jacket hashes.
+type JacketStore struct {
+	dir string
+}
+
+// NewJacketStore returns a store of jackets in dir.
+func NewJacketStore(dir string) *JacketStore {
+	return &JacketStore{dir: dir}
+}
+
+// DefaultJacketStore returns the store in the cache directory.
+func DefaultJacketStore() *JacketStore {
+	return NewJacketStore(filepath.Join(getCacheDirectory(), "jackets"))
+}
+
+// path returns the file of a jacket. PLUS patterns have their own jacket.
+func (s *JacketStore) path(songID int, plus bool) string {
+	name := fmt.Sprintf("%d.png", songID)
+	if plus {
+		name = fmt.Sprintf("%d-plus.png", songID)
+	}
+	return filepath.Join(s.dir, name)
+}
+
+// Has reports whether the jacket is stored.
+func (s *JacketStore) Has(songID int, plus bool) bool {
+	return fileExists(s.path(songID, plus))
+}
+
+// Save stores a jacket, replacing the previous one.
+func (s *JacketStore) Save(songID int, plus bool, img image.Image) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("error creating jacket folder: %v", err)
+	}
+	return SavePNG(s.path(songID, plus), img)
+}
+
+// Load returns a stored jacket. The bool is false when it was never saved.
+func (s *JacketStore) Load(songID int, plus bool) (image.Image, bool) {
+	file, err := os.Open(s.path(songID, plus))
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, false
+	}
+	return img, true
+}
+
+// SaveReportJacket stores the jacket of an analysed report. A confirmed report replaces the stored jacket;
+// otherwise it is only stored when there is none yet and the song was matched with high confidence.
+func (s *JacketStore) SaveReportJacket(report AnalysisReport, confirmed bool) error {
+	plus := report.PatternObject.Difficulty == "PLUS"
+	if report.JacketImage == nil || report.SongObject.ID == 0 {
+		return nil
+	}
+	if !confirmed {
+		_, matched := report.Confidence[FieldJacket]
+		if !matched || report.IsLowConfidence(FieldJacket) || report.HasWarning(FieldJacket) || s.Has(report.SongObject.ID, plus) {
+			return nil
+		}
+	}
+	return s.Save(report.SongObject.ID, plus, report.JacketImage)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSaveReportJacket(t *testing.T) {
+	store := NewJacketStore(t.TempDir())
+	jacket := func(c color.Gray) image.Image {
+		img := image.NewGray(image.Rect(0, 0, 4, 4))
+		for i := range img.Pix {
+			img.Pix[i] = c.Y
+		}
+		return img
+	}
+	report := AnalysisReport{
+		SongObject:    Song{ID: 1},
+		PatternObject: Pattern{Difficulty: "HARD"},
+		JacketImage:   jacket(color.Gray{10}),
+		Confidence:    map[string]float64{FieldJacket: 0.3},
+	}
+	stored := func() uint8 {
+		img, ok := store.Load(1, false)
+		if !ok {
+			return 0
+		}
+		return img.(*image.Gray).Pix[0]
+	}
+
+	if err := store.SaveReportJacket(report, false); err != nil || store.Has(1, false) {
+		t.Fatalf("expected a low-confidence jacket not to be saved, got %v", err)
+	}
+	report.Confidence[FieldJacket] = 1
+	if err := store.SaveReportJacket(report, false); err != nil || stored() != 10 {
+		t.Fatalf("expected a high-confidence jacket to be saved, got %v", err)
+	}
+	report.JacketImage = jacket(color.Gray{20})
+	if err := store.SaveReportJacket(report, false); err != nil || stored() != 10 {
+		t.Errorf("expected an unconfirmed report to keep the stored jacket, got %d, %v", stored(), err)
+	}
+	if err := store.SaveReportJacket(report, true); err != nil || stored() != 20 {
+		t.Errorf("expected a confirmed report to replace the jacket, got %d, %v", stored(), err)
+	}
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+)
+
+// Lamp is the clear status of a pattern, from worst to best.
+type Lamp int
+
+const (
+	LampUnplayed Lamp = iota
+	LampPlayed
+	LampFullCombo
+	LampMaxPatch
+)
+
+func (l Lamp) String() string {
+	switch l {
+	case LampPlayed:
+		return "played"
+	case LampFullCombo:
+		return "FULL COMBO"
+	case LampMaxPatch:
+		return "MAX PATCH"
+	}
+	return "unplayed"
+}
+
+// Color returns the color the lamp is drawn with.
+func (l Lamp) Color() color.RGBA {
+	switch l {
+	case LampPlayed:
+		return color.RGBA{70, 130, 220, 255}
+	case LampFullCombo:
+		return color.RGBA{240, 200, 40, 255}
+	case LampMaxPatch:
+		return color.RGBA{255, 110, 200, 255}
+	}
+	return color.RGBA{70, 70, 70, 255}
+}
+
+// ArchiveLamp returns the lamp of an archive record.
+func ArchiveLamp(a Archive) Lamp {
+	switch {
+	case a.MaxPatch:
+		return LampMaxPatch
+	case a.FullCombo:
+		return LampFullCombo
+	}
+	return LampPlayed
+}
+
+// LampCell is a pattern in the clear-lamp table.
+type LampCell struct {
+	Pattern Pattern
+	Song    Song
+	Lamp    Lamp
+}
+
+// Tag returns the short line and difficulty of the cell, e.g. "4H".
+func (c LampCell) Tag() string {
+	return fmt.Sprintf("%d%c", c.Pattern.Line, firstRune(c.Pattern.Difficulty))
+}
+
+// LampLevel is the row of a level in the clear-lamp table.
+type LampLevel struct {
+	Level int
+	Cells []LampCell
+}
+
+// Count returns the number of patterns with the lamp or a better one.
+func (l LampLevel) Count(lamp Lamp) int {
+	n := 0
+	for _, c := range l.Cells {
+		if c.Lamp >= lamp {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary describes the lamps of the level, e.g. "Lv.13  played 8/10  FC 3  MP 1".
+func (l LampLevel) Summary() string {
+	return fmt.Sprintf("Lv.%d  played %d/%d  FC %d  MP %d",
+		l.Level, l.Count(LampPlayed), len(l.Cells), l.Count(LampFullCombo), l.Count(LampMaxPatch))
+}
+
+// BuildLampTable groups the patterns of the cache passing the filter by level, highest first,
+// with the lamp of every pattern from the archive records. Patterns are sorted by title.
+func BuildLampTable(cache *Cache, archives []Archive, filter PatternFilter) []LampLevel {
+	lamps := make(map[PatternKey]Lamp)
+	for _, a := range archives {
+		key := ArchiveKey(a)
+		lamps[key] = max(lamps[key], ArchiveLamp(a))
+	}
+
+	byLevel := make(map[int][]LampCell)
+	for _, p := range cache.Patterns {
+		song, _ := cache.FindSong(p.SongID)
+		if !filter.Matches(song, p) {
+			continue
+		}
+		lamp := lamps[PatternKey{p.SongID, p.Line, p.Difficulty}]
+		byLevel[p.Level] = append(byLevel[p.Level], LampCell{Pattern: p, Song: song, Lamp: lamp})
+	}
+
+	levels := make([]LampLevel, 0, len(byLevel))
+	for level, cells := range byLevel {
+		sort.Slice(cells, func(i, j int) bool {
+			if cells[i].Song.Title != cells[j].Song.Title {
+				return cells[i].Song.Title < cells[j].Song.Title
+			}
+			if cells[i].Pattern.Line != cells[j].Pattern.Line {
+				return cells[i].Pattern.Line < cells[j].Pattern.Line
+			}
+			return cells[i].Pattern.Difficulty < cells[j].Pattern.Difficulty
+		})
+		levels = append(levels, LampLevel{Level: level, Cells: cells})
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Level > levels[j].Level })
+	return levels
+}
+
+// Layout of the rendered clear-lamp table.
+const (
+	lampColumns   = 10
+	lampCellSize  = 80
+	lampBorder    = 4
+	lampGap       = 6
+	lampMargin    = 16
+	lampHeader    = 36
+	lampTitleSize = 11
+	lampLevelSize = 18
+)
+
+// lampBackground is the background of the rendered table.
+var lampBackground = color.RGBA{24, 24, 28, 255}
+
+// RenderLampTable draws the clear-lamp table, with the stored jackets framed by the lamp color.
+// Patterns without a stored jacket show their title instead.
+func RenderLampTable(levels []LampLevel, jackets *JacketStore, fonts *Fonts) *image.RGBA {
+	step := lampCellSize + lampGap
+	height := lampMargin
+	for _, l := range levels {
+		rows := (len(l.Cells) + lampColumns - 1) / lampColumns
+		height += lampHeader + rows*step
+	}
+	width := 2*lampMargin + lampColumns*step - lampGap
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height+lampMargin))
+	fillRect(canvas, canvas.Bounds(), lampBackground)
+
+	levelFace := fonts.Face(lampLevelSize, true)
+	titleFace := fonts.Face(lampTitleSize, false)
+	y := lampMargin
+	for _, l := range levels {
+		drawText(canvas, levelFace, lampMargin, y+lampHeader-12, l.Summary(), color.White, width-2*lampMargin)
+		y += lampHeader
+		for i, c := range l.Cells {
+			x := lampMargin + (i%lampColumns)*step
+			top := y + (i/lampColumns)*step
+			cell := image.Rect(x, top, x+lampCellSize, top+lampCellSize)
+			fillRect(canvas, cell, c.Lamp.Color())
+			inner := cell.Inset(lampBorder)
+			if img, ok := jackets.Load(c.Song.ID, c.Pattern.Difficulty == "PLUS"); ok {
+				drawScaled(canvas, inner, img)
+			} else {
+				fillRect(canvas, inner, color.RGBA{45, 45, 50, 255})
+				drawText(canvas, titleFace, inner.Min.X+3, inner.Min.Y+lampTitleSize+4, c.Song.Title, color.White, inner.Dx()-6)
+			}
+			// Line and difficulty tag in the corner
+			fillRect(canvas, image.Rect(inner.Min.X, inner.Max.Y-lampTitleSize-5, inner.Min.X+22, inner.Max.Y), color.RGBA{0, 0, 0, 200})
+			drawText(canvas, titleFace, inner.Min.X+2, inner.Max.Y-4, c.Tag(), color.White, 0)
+		}
+		rows := (len(l.Cells) + lampColumns - 1) / lampColumns
+		y += rows * step
+	}
+	return canvas
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return ' '
+}
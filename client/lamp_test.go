@@ -0,0 +1,87 @@
+package client
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// loadTestFonts loads the bundled fonts, or the Go fonts when they are not checked out.
+func loadTestFonts(t *testing.T) *Fonts {
+	t.Helper()
+	fonts, err := LoadFonts("../"+RegularFontPath, "../"+BoldFontPath)
+	if err != nil {
+		t.Logf("using fallback fonts: %v", err)
+		return FallbackFonts()
+	}
+	return fonts
+}
+
+func TestBuildLampTable(t *testing.T) {
+	cache := &Cache{
+		Songs: []Song{{ID: 1}, {ID: 2}},
+		Patterns: []Pattern{
+			{SongID: 1, Line: 4, Difficulty: "EASY", Level: 5},
+			{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10},
+			{SongID: 1, Line: 6, Difficulty: "HARD", Level: 11},
+			{SongID: 1, Line: 6, Difficulty: "OVER", Level: 14},
+			{SongID: 2, Line: 4, Difficulty: "HARD", Level: 10},
+			{SongID: 2, Line: 4, Difficulty: "OVER", Level: 9},
+		},
+	}
+	archives := []Archive{
+		{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10},
+		{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10, FullCombo: true},
+		{SongID: 2, Line: 4, Difficulty: "HARD", Level: 10, FullCombo: true, MaxPatch: true},
+		{SongID: 1, Line: 6, Difficulty: "HARD", Level: 11},
+	}
+	levels := BuildLampTable(cache, archives, PatternFilter{})
+	var order []int
+	for _, l := range levels {
+		order = append(order, l.Level)
+	}
+	if len(order) != 5 || order[0] != 14 || order[4] != 5 {
+		t.Fatalf("expected levels from highest to lowest, got %v", order)
+	}
+	ten := levels[2]
+	if ten.Level != 10 || len(ten.Cells) != 2 || ten.Cells[0].Lamp != LampFullCombo || ten.Cells[1].Lamp != LampMaxPatch {
+		t.Errorf("unexpected level 10 %+v", ten)
+	}
+	if ten.Count(LampPlayed) != 2 || ten.Count(LampFullCombo) != 2 || ten.Count(LampMaxPatch) != 1 {
+		t.Errorf("unexpected counts for %s", ten.Summary())
+	}
+	if levels[0].Cells[0].Lamp != LampUnplayed {
+		t.Errorf("expected the unplayed level 14 pattern to be unplayed")
+	}
+
+	levels = BuildLampTable(cache, archives, PatternFilter{Lines: []int{6}})
+	if len(levels) != 2 {
+		t.Errorf("expected 2 levels of 6 line patterns, got %d", len(levels))
+	}
+}
+
+func TestRenderLampTable(t *testing.T) {
+	jackets := NewJacketStore(t.TempDir())
+	jacket := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	fillRect(jacket, jacket.Bounds(), color.RGBA{0, 255, 0, 255})
+	if err := jackets.Save(1, false, jacket); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	levels := []LampLevel{{Level: 10, Cells: []LampCell{
+		{Pattern: Pattern{SongID: 1, Line: 4, Difficulty: "HARD"}, Song: Song{ID: 1, Title: "Base"}, Lamp: LampFullCombo},
+		{Pattern: Pattern{SongID: 2, Line: 4, Difficulty: "HARD"}, Song: Song{ID: 2, Title: "별의 노래"}, Lamp: LampUnplayed},
+	}}}
+	img := RenderLampTable(levels, jackets, loadTestFonts(t))
+
+	cell := image.Rect(lampMargin, lampMargin+lampHeader, lampMargin+lampCellSize, lampMargin+lampHeader+lampCellSize)
+	if c := img.RGBAAt(cell.Min.X+1, cell.Min.Y+1); c != LampFullCombo.Color() {
+		t.Errorf("expected the full combo frame, got %v", c)
+	}
+	if c := img.RGBAAt(cell.Min.X+lampCellSize/2, cell.Min.Y+lampBorder+2); c != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("expected the stored jacket inside the frame, got %v", c)
+	}
+	if c := img.RGBAAt(cell.Min.X+lampCellSize+lampGap+1, cell.Min.Y+1); c != LampUnplayed.Color() {
+		t.Errorf("expected the unplayed frame, got %v", c)
+	}
+}
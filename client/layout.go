@@ -0,0 +1,117 @@
+package client
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// blackBarLevel is the highest 8-bit channel value treated as part of a black bar.
+const blackBarLevel = 16
+
+// aspectTolerance is the relative aspect ratio difference treated as the reference aspect.
+const aspectTolerance = 0.01
+
+// Layout maps coordinates measured on the reference resolution onto the
+// game viewport inside a screenshot.
+type Layout struct {
+	Viewport  image.Rectangle
+	Reference ScreenSize
+}
+
+// identityLayout returns a layout for a screenshot whose size has its own ROIConfig.
+func identityLayout(bounds image.Rectangle) Layout {
+	return Layout{
+		Viewport:  bounds,
+		Reference: ScreenSize{Width: bounds.Dx(), Height: bounds.Dy()},
+	}
+}
+
+// DetectLayout finds the game viewport inside img.
+// Black bars are trimmed from every edge first. If what remains is wider than
+// the reference aspect ratio the viewport is centred horizontally, and if it is
+// taller the viewport is aligned to the bottom, as window title bars sit on top.
+func DetectLayout(img image.Image, reference ScreenSize) Layout {
+	content := trimBlackBars(img)
+	return Layout{Viewport: fitViewport(content, reference), Reference: reference}
+}
+
+func fitViewport(content image.Rectangle, reference ScreenSize) image.Rectangle {
+	if content.Empty() || reference.Width == 0 || reference.Height == 0 {
+		return content
+	}
+	referenceAspect := float64(reference.Width) / float64(reference.Height)
+	aspect := float64(content.Dx()) / float64(content.Dy())
+	if math.Abs(aspect-referenceAspect)/referenceAspect <= aspectTolerance {
+		return content
+	}
+
+	if aspect > referenceAspect {
+		width := int(math.Round(float64(content.Dy()) * referenceAspect))
+		x := content.Min.X + (content.Dx()-width)/2
+		return image.Rect(x, content.Min.Y, x+width, content.Max.Y)
+	}
+	height := int(math.Round(float64(content.Dx()) / referenceAspect))
+	return image.Rect(content.Min.X, content.Max.Y-height, content.Max.X, content.Max.Y)
+}
+
+// trimBlackBars returns the bounds of img without the black rows and columns at its edges.
+func trimBlackBars(img image.Image) image.Rectangle {
+	b := img.Bounds()
+	rowBlack := func(y int) bool {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !isBlack(img, x, y) {
+				return false
+			}
+		}
+		return true
+	}
+	columnBlack := func(x int, minY int, maxY int) bool {
+		for y := minY; y < maxY; y++ {
+			if !isBlack(img, x, y) {
+				return false
+			}
+		}
+		return true
+	}
+
+	minY, maxY := b.Min.Y, b.Max.Y
+	for minY < maxY && rowBlack(minY) {
+		minY++
+	}
+	for maxY > minY && rowBlack(maxY-1) {
+		maxY--
+	}
+	minX, maxX := b.Min.X, b.Max.X
+	for minX < maxX && columnBlack(minX, minY, maxY) {
+		minX++
+	}
+	for maxX > minX && columnBlack(maxX-1, minY, maxY) {
+		maxX--
+	}
+	if minX >= maxX || minY >= maxY {
+		return b
+	}
+	return image.Rect(minX, minY, maxX, maxY)
+}
+
+func isBlack(img image.Image, x int, y int) bool {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return r>>8 <= blackBarLevel && g>>8 <= blackBarLevel && b>>8 <= blackBarLevel
+}
+
+// Point maps a reference coordinate into the screenshot.
+func (l Layout) Point(x int, y int) image.Point {
+	return image.Point{
+		X: l.Viewport.Min.X + int(math.Round(float64(x)*float64(l.Viewport.Dx())/float64(l.Reference.Width))),
+		Y: l.Viewport.Min.Y + int(math.Round(float64(y)*float64(l.Viewport.Dy())/float64(l.Reference.Height))),
+	}
+}
+
+// Rect maps a reference box given as [x1, y1, x2, y2] into the screenshot.
+func (l Layout) Rect(coords []int) (image.Rectangle, error) {
+	if len(coords) != 4 {
+		return image.Rectangle{}, fmt.Errorf("expected 4 coordinates, got %d", len(coords))
+	}
+	return image.Rectangle{Min: l.Point(coords[0], coords[1]), Max: l.Point(coords[2], coords[3])}, nil
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+var referenceSize = ScreenSize{Width: 1920, Height: 1080}
+
+// syntheticGame fills viewport with a pattern that has no black rows or columns,
+// and leaves the rest of the canvas black.
+func syntheticGame(canvas image.Rectangle, viewport image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(canvas)
+	draw.Draw(img, canvas, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	for y := viewport.Min.Y; y < viewport.Max.Y; y++ {
+		for x := viewport.Min.X; x < viewport.Max.X; x++ {
+			img.Set(x, y, color.RGBA{uint8(64 + x%128), uint8(64 + y%128), 200, 255})
+		}
+	}
+	return img
+}
+
+func TestDetectLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		canvas   image.Rectangle
+		viewport image.Rectangle
+	}{
+		{"Reference", image.Rect(0, 0, 1920, 1080), image.Rect(0, 0, 1920, 1080)},
+		{"Pillarboxed 2560x1080", image.Rect(0, 0, 2560, 1080), image.Rect(320, 0, 2240, 1080)},
+		{"Pillarboxed 3440x1440", image.Rect(0, 0, 3440, 1440), image.Rect(440, 0, 3000, 1440)},
+		{"Letterboxed 1920x1200", image.Rect(0, 0, 1920, 1200), image.Rect(0, 60, 1920, 1140)},
+		{"Windowboxed", image.Rect(0, 0, 1600, 1000), image.Rect(160, 95, 1440, 815)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := syntheticGame(tt.canvas, tt.viewport)
+			layout := DetectLayout(img, referenceSize)
+			if layout.Viewport != tt.viewport {
+				t.Errorf("expected viewport %v, got %v", tt.viewport, layout.Viewport)
+			}
+		})
+	}
+}
+
+func TestDetectLayoutWithTitleBar(t *testing.T) {
+	// A 1280x720 game below a 31px title bar that is not black.
+	canvas := image.Rect(0, 0, 1280, 751)
+	viewport := image.Rect(0, 31, 1280, 751)
+	img := syntheticGame(canvas, viewport)
+	draw.Draw(img, image.Rect(0, 0, 1280, 31), image.NewUniform(color.RGBA{240, 240, 240, 255}), image.Point{}, draw.Src)
+
+	layout := DetectLayout(img, referenceSize)
+	if layout.Viewport != viewport {
+		t.Errorf("expected viewport %v, got %v", viewport, layout.Viewport)
+	}
+}
+
+func TestLayoutRect(t *testing.T) {
+	layout := Layout{Viewport: image.Rect(320, 0, 2240, 1080), Reference: referenceSize}
+	rect, err := layout.Rect([]int{122, 193, 522, 593})
+	if err != nil {
+		t.Fatalf("Rect failed: %v", err)
+	}
+	expected := image.Rect(442, 193, 842, 593)
+	if rect != expected {
+		t.Errorf("expected %v, got %v", expected, rect)
+	}
+
+	layout = Layout{Viewport: image.Rect(440, 0, 3000, 1440), Reference: referenceSize}
+	rect, err = layout.Rect([]int{0, 0, 1920, 1080})
+	if err != nil {
+		t.Fatalf("Rect failed: %v", err)
+	}
+	if rect != layout.Viewport {
+		t.Errorf("expected %v, got %v", layout.Viewport, rect)
+	}
+
+	if _, err := layout.Rect([]int{1, 2}); err == nil {
+		t.Error("expected error for 2 coordinates")
+	}
+}
+
+func TestDecideScreenTypeOnPillarboxedCapture(t *testing.T) {
+	resultImg, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+	padded := image.NewRGBA(image.Rect(0, 0, 2560, 1080))
+	draw.Draw(padded, padded.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	draw.Draw(padded, image.Rect(320, 0, 2240, 1080), resultImg, image.Point{}, draw.Src)
+
+	config := &Config{
+		Reference:         referenceSize,
+		SpeedWidgetPHash:  "c0c73d38273ed2c3",
+		ResultAnchorPHash: "ffda2835444e83e8",
+		Configs: []ROIConfig{
+			{
+				ScreenSize: "1920x1080",
+				Select:     SelectROIConfig{SpeedWidget: []int{30, 908, 119, 932}},
+				Result:     ResultROIConfig{Anchor: []int{628, 98, 822, 132}},
+			},
+		},
+	}
+
+	targetConfig, layout, _ := selectLayout(padded, config)
+	if layout.Viewport != image.Rect(320, 0, 2240, 1080) {
+		t.Fatalf("unexpected viewport %v", layout.Viewport)
+	}
+	screenType, _, err := decideScreenType(padded, targetConfig, layout, config)
+	if err != nil {
+		t.Fatalf("decideScreenType failed: %v", err)
+	}
+	if screenType != ResultScreen {
+		t.Errorf("expected ResultScreen, got %v", screenType)
+	}
+}
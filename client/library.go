@@ -0,0 +1,300 @@
+package client
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sort orders of the library.
+const (
+	LibrarySortRelevance = "relevance"
+	LibrarySortTitle     = "title"
+	LibrarySortArtist    = "artist"
+	LibrarySortBPM       = "BPM"
+	LibrarySortLevel     = "level"
+	LibrarySortDLC       = "DLC"
+)
+
+// LibrarySorts lists the sort orders of the library.
+var LibrarySorts = []string{LibrarySortRelevance, LibrarySortTitle, LibrarySortArtist, LibrarySortBPM, LibrarySortLevel, LibrarySortDLC}
+
+// fuzzyThreshold is the lowest similarity of a typo match.
+const fuzzyThreshold = 0.7
+
+// LibraryQuery searches and filters the songs of the cache. Zero values do not filter.
+type LibraryQuery struct {
+	// Text is matched against titles and artists, in Korean or English, allowing typos.
+	// Korean may be typed partially or as initial consonants, e.g. "ㅂㅇㄴㄹ" for "별의 노래".
+	Text string
+	DLCs []string
+	// MinBPM and MaxBPM keep the songs whose BPM range overlaps them.
+	MinBPM int
+	MaxBPM int
+	// MinLevel and MaxLevel keep the songs with a pattern in the level range.
+	MinLevel int
+	MaxLevel int
+	// Sort is one of LibrarySorts, relevance by default. Relevance ties sort by title.
+	Sort       string
+	Descending bool
+}
+
+// LibraryEntry is a song of the library with its patterns.
+type LibraryEntry struct {
+	Song Song
+	// Patterns are sorted by line and difficulty.
+	Patterns []Pattern
+	// Match is how well the song matches the search text, 1 for an exact part of the title or artist.
+	Match   float64
+	BPMLow  int
+	BPMHigh int
+}
+
+// MaxLevel returns the highest level of the song's patterns.
+func (e LibraryEntry) MaxLevel() int {
+	level := 0
+	for _, p := range e.Patterns {
+		level = max(level, p.Level)
+	}
+	return level
+}
+
+// Levels returns the levels of the song's patterns, e.g. "4L 3/7/11  6L 5/9/12".
+func (e LibraryEntry) Levels() string {
+	var parts []string
+	for _, line := range []int{4, 6} {
+		var levels []string
+		for _, p := range e.Patterns {
+			if p.Line == line {
+				levels = append(levels, strconv.Itoa(p.Level))
+			}
+		}
+		if len(levels) > 0 {
+			parts = append(parts, strconv.Itoa(line)+"L "+strings.Join(levels, "/"))
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// SearchLibrary returns the songs of the cache matching the query.
+func SearchLibrary(cache *Cache, q LibraryQuery) []LibraryEntry {
+	patterns := make(map[int][]Pattern)
+	for _, p := range cache.Patterns {
+		patterns[p.SongID] = append(patterns[p.SongID], p)
+	}
+	query := searchForms(q.Text)
+
+	var entries []LibraryEntry
+	for _, s := range cache.Songs {
+		if len(q.DLCs) > 0 && !slices.Contains(q.DLCs, s.DLC) {
+			continue
+		}
+		low, high, ok := ParseBPM(s.BPM)
+		if (q.MinBPM > 0 || q.MaxBPM > 0) && !ok {
+			continue
+		}
+		if (q.MinBPM > 0 && high < q.MinBPM) || (q.MaxBPM > 0 && low > q.MaxBPM) {
+			continue
+		}
+		entry := LibraryEntry{Song: s, Patterns: patterns[s.ID], BPMLow: low, BPMHigh: high}
+		sortPatterns(entry.Patterns)
+		if (q.MinLevel > 0 || q.MaxLevel > 0) && !slices.ContainsFunc(entry.Patterns, func(p Pattern) bool {
+			return (q.MinLevel == 0 || p.Level >= q.MinLevel) && (q.MaxLevel == 0 || p.Level <= q.MaxLevel)
+		}) {
+			continue
+		}
+		if query.plain != "" {
+			entry.Match = max(matchText(query, s.Title), matchText(query, s.Artist))
+			if entry.Match == 0 {
+				continue
+			}
+		}
+		entries = append(entries, entry)
+	}
+	sortLibrary(entries, q)
+	return entries
+}
+
+func sortLibrary(entries []LibraryEntry, q LibraryQuery) {
+	less := func(a, b LibraryEntry) int {
+		switch q.Sort {
+		case LibrarySortTitle:
+			return strings.Compare(a.Song.Title, b.Song.Title)
+		case LibrarySortArtist:
+			return strings.Compare(a.Song.Artist, b.Song.Artist)
+		case LibrarySortBPM:
+			return a.BPMHigh - b.BPMHigh
+		case LibrarySortLevel:
+			return a.MaxLevel() - b.MaxLevel()
+		case LibrarySortDLC:
+			return strings.Compare(a.Song.DLC, b.Song.DLC)
+		}
+		// Best matches first, unless reversed
+		switch {
+		case a.Match > b.Match:
+			return -1
+		case a.Match < b.Match:
+			return 1
+		}
+		return 0
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		c := less(entries[i], entries[j])
+		if q.Descending {
+			c = -c
+		}
+		if c == 0 {
+			return entries[i].Song.Title < entries[j].Song.Title
+		}
+		return c < 0
+	})
+}
+
+// ParseBPM reads a BPM such as "180" or a range such as "90-180" or "90~180".
+func ParseBPM(bpm string) (int, int, bool) {
+	parts := strings.FieldsFunc(bpm, func(r rune) bool { return r == '-' || r == '~' || r == '–' })
+	if len(parts) == 0 || len(parts) > 2 {
+		return 0, 0, false
+	}
+	var values []int
+	for _, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		values = append(values, int(v+0.5))
+	}
+	return slices.Min(values), slices.Max(values), true
+}
+
+// difficultyOrder sorts difficulties from the easiest.
+var difficultyOrder = map[string]int{"EASY": 0, "HARD": 1, "OVER": 2, "PLUS": 3}
+
+// sortPatterns sorts patterns by line, then difficulty.
+func sortPatterns(patterns []Pattern) {
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Line != patterns[j].Line {
+			return patterns[i].Line < patterns[j].Line
+		}
+		return difficultyOrder[patterns[i].Difficulty] < difficultyOrder[patterns[j].Difficulty]
+	})
+}
+
+// LibraryPattern is a pattern of a song with the best archive record on it.
+type LibraryPattern struct {
+	Pattern Pattern
+	Best    Archive
+	Played  bool
+}
+
+// SongPatterns returns the patterns of a song, sorted by line and difficulty, with the best of the archive records.
+func SongPatterns(cache *Cache, songID int, archives []Archive) []LibraryPattern {
+	bests := make(map[PatternKey]Archive)
+	for _, a := range BestArchives(archives) {
+		bests[ArchiveKey(a)] = a
+	}
+	var patterns []Pattern
+	for _, p := range cache.Patterns {
+		if p.SongID == songID {
+			patterns = append(patterns, p)
+		}
+	}
+	sortPatterns(patterns)
+	result := make([]LibraryPattern, len(patterns))
+	for i, p := range patterns {
+		best, played := bests[PatternKey{p.SongID, p.Line, p.Difficulty}]
+		result[i] = LibraryPattern{Pattern: p, Best: best, Played: played}
+	}
+	return result
+}
+
+// Hangul syllables are composed of an initial, a medial and an optional final jamo.
+const (
+	hangulBase    = 0xAC00
+	hangulLast    = 0xD7A3
+	hangulMedials = 21
+	hangulFinals  = 28
+)
+
+var (
+	hangulInitials    = []rune("ㄱㄲㄴㄷㄸㄹㅁㅂㅃㅅㅆㅇㅈㅉㅊㅋㅌㅍㅎ")
+	hangulMedialJamo  = []rune("ㅏㅐㅑㅒㅓㅔㅕㅖㅗㅘㅙㅚㅛㅜㅝㅞㅟㅠㅡㅢㅣ")
+	hangulFinalJamo   = []rune(" ㄱㄲㄳㄴㄵㄶㄷㄹㄺㄻㄼㄽㄾㄿㅀㅁㅂㅄㅅㅆㅇㅈㅊㅋㅌㅍㅎ")
+	hangulInitialJamo = string(hangulInitials)
+)
+
+// searchText holds the forms of a text that are compared in a search.
+type searchText struct {
+	// plain is the normalised text, jamo the same with Hangul syllables split into jamo
+	// and initials only the initial consonants of its syllables.
+	plain    string
+	jamo     string
+	initials string
+}
+
+func searchForms(s string) searchText {
+	plain := normaliseTitle(s)
+	var jamo, initials strings.Builder
+	for _, r := range plain {
+		if r < hangulBase || r > hangulLast {
+			jamo.WriteRune(r)
+			initials.WriteRune(r)
+			continue
+		}
+		i := int(r - hangulBase)
+		initial := hangulInitials[i/(hangulMedials*hangulFinals)]
+		jamo.WriteRune(initial)
+		jamo.WriteRune(hangulMedialJamo[i/hangulFinals%hangulMedials])
+		if final := i % hangulFinals; final > 0 {
+			jamo.WriteRune(hangulFinalJamo[final])
+		}
+		initials.WriteRune(initial)
+	}
+	return searchText{plain: plain, jamo: jamo.String(), initials: initials.String()}
+}
+
+// matchText scores how well the query matches a text, from 0 for no match to 1 for an exact part of it.
+func matchText(query searchText, text string) float64 {
+	target := searchForms(text)
+	switch {
+	case target.plain == "":
+		return 0
+	case strings.Contains(target.plain, query.plain):
+		return 1
+	case strings.Contains(target.jamo, query.jamo):
+		// A partly typed syllable, e.g. "별의 노" + "ㄹ"
+		return 0.9
+	case isInitials(query.plain) && strings.Contains(target.initials, query.plain):
+		return 0.8
+	}
+	similarity := windowSimilarity([]rune(query.jamo), []rune(target.jamo))
+	if similarity < fuzzyThreshold {
+		return 0
+	}
+	return similarity * 0.7
+}
+
+// isInitials reports whether s only holds initial consonants.
+func isInitials(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune(hangulInitialJamo, r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// windowSimilarity is the best similarity of the query to a part of the text as long as the query,
+// so a typo in a word still matches a long title.
+func windowSimilarity(query, text []rune) float64 {
+	if len(text) <= len(query) {
+		return 1 - float64(editDistance(query, text))/float64(max(len(query), len(text)))
+	}
+	best := 0.0
+	for start := 0; start+len(query) <= len(text); start++ {
+		similarity := 1 - float64(editDistance(query, text[start:start+len(query)]))/float64(len(query))
+		best = max(best, similarity)
+	}
+	return best
+}
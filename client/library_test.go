@@ -0,0 +1,109 @@
+package client
+
+import (
+	"testing"
+)
+
+func libraryTitles(entries []LibraryEntry) []string {
+	var titles []string
+	for _, e := range entries {
+		titles = append(titles, e.Song.Title)
+	}
+	return titles
+}
+
+func TestSearchLibraryText(t *testing.T) {
+	cache := &Cache{Songs: []Song{
+		{ID: 1, Title: "별의 노래", Artist: "Stellar"},
+		{ID: 2, Title: "Firework", Artist: "하늘"},
+		{ID: 3, Title: "Blue Moon", Artist: "Nocturne"},
+	}}
+	for _, tc := range []struct {
+		text string
+		want string
+	}{
+		{"노래", "별의 노래"},
+		{"별의 놀", "별의 노래"}, // the last syllable is being typed
+		{"ㅂㅇㄴㄹ", "별의 노래"},
+		{"FIRE", "Firework"},
+		{"firewrok", "Firework"},
+		{"하늘", "Firework"}, // by artist
+		{"noctrune", "Blue Moon"},
+	} {
+		entries := SearchLibrary(cache, LibraryQuery{Text: tc.text})
+		if len(entries) == 0 || entries[0].Song.Title != tc.want {
+			t.Errorf("%q: expected %q first, got %v", tc.text, tc.want, libraryTitles(entries))
+		}
+	}
+	if entries := SearchLibrary(cache, LibraryQuery{Text: "zzzz"}); len(entries) != 0 {
+		t.Errorf("expected no match, got %v", libraryTitles(entries))
+	}
+}
+
+func TestSearchLibraryFilters(t *testing.T) {
+	cache := &Cache{
+		Songs: []Song{
+			{ID: 1, Title: "별의 노래", BPM: "150", DLC: ""},
+			{ID: 2, Title: "Firework", BPM: "90-180", DLC: "Pack A"},
+			{ID: 3, Title: "Blue Moon", BPM: "200", DLC: "Pack A"},
+		},
+		Patterns: []Pattern{
+			{SongID: 1, Line: 6, Difficulty: "HARD", Level: 9},
+			{SongID: 1, Line: 4, Difficulty: "OVER", Level: 11},
+			{SongID: 1, Line: 4, Difficulty: "EASY", Level: 3},
+			{SongID: 2, Line: 4, Difficulty: "HARD", Level: 12},
+			{SongID: 3, Line: 4, Difficulty: "HARD", Level: 7},
+		},
+	}
+	entries := SearchLibrary(cache, LibraryQuery{DLCs: []string{"Pack A"}, MinBPM: 170})
+	if titles := libraryTitles(entries); len(titles) != 2 || titles[0] != "Blue Moon" || titles[1] != "Firework" {
+		t.Errorf("expected the Pack A songs reaching 170 BPM, got %v", titles)
+	}
+	entries = SearchLibrary(cache, LibraryQuery{MinLevel: 10, MaxLevel: 11})
+	if len(entries) != 1 || entries[0].Song.ID != 1 {
+		t.Errorf("expected the song with a level 11 pattern, got %v", libraryTitles(entries))
+	}
+	if entries[0].Levels() != "4L 3/11  6L 9" || entries[0].MaxLevel() != 11 {
+		t.Errorf("unexpected levels %q", entries[0].Levels())
+	}
+
+	entries = SearchLibrary(cache, LibraryQuery{Sort: LibrarySortBPM, Descending: true})
+	if titles := libraryTitles(entries); titles[0] != "Blue Moon" || titles[2] != "별의 노래" {
+		t.Errorf("expected the fastest song first, got %v", titles)
+	}
+}
+
+func TestParseBPM(t *testing.T) {
+	for bpm, want := range map[string][2]int{"150": {150, 150}, "90-180": {90, 180}, "180~90": {90, 180}, "128.5": {129, 129}} {
+		low, high, ok := ParseBPM(bpm)
+		if !ok || low != want[0] || high != want[1] {
+			t.Errorf("%q: got %d-%d %v", bpm, low, high, ok)
+		}
+	}
+	if _, _, ok := ParseBPM("?"); ok {
+		t.Errorf("expected an unknown BPM to fail")
+	}
+}
+
+func TestSongPatterns(t *testing.T) {
+	cache := &Cache{
+		Songs: []Song{{ID: 1, Title: "별의 노래"}, {ID: 2, Title: "Firework"}},
+		Patterns: []Pattern{
+			{SongID: 1, Line: 6, Difficulty: "HARD", Level: 9},
+			{SongID: 1, Line: 4, Difficulty: "OVER", Level: 11},
+			{SongID: 1, Line: 4, Difficulty: "EASY", Level: 3},
+			{SongID: 2, Line: 4, Difficulty: "HARD", Level: 12},
+		},
+	}
+	archives := []Archive{
+		{SongID: 1, Line: 4, Difficulty: "OVER", Judge: 97},
+		{SongID: 1, Line: 4, Difficulty: "OVER", Judge: 98.5},
+	}
+	patterns := SongPatterns(cache, 1, archives)
+	if len(patterns) != 3 || patterns[0].Pattern.Difficulty != "EASY" || patterns[2].Pattern.Line != 6 {
+		t.Fatalf("expected the patterns by line and difficulty, got %+v", patterns)
+	}
+	if patterns[0].Played || !patterns[1].Played || patterns[1].Best.Judge != 98.5 {
+		t.Errorf("expected the best record on the played pattern, got %+v", patterns)
+	}
+}
@@ -0,0 +1,320 @@
+package client
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numberField is a ROI holding a number, along with how a known value is drawn in it.
+type numberField struct {
+	name   string
+	label  string
+	coords []int
+	// texts lists every way the value can be drawn, so training can pick the one
+	// with as many glyphs as were segmented.
+	texts func(v float64) []string
+}
+
+// noteRows pairs the label of each note count with its row in the result config.
+func noteRows(r ResultROIConfig) []struct {
+	label string
+	rows  []int
+} {
+	return []struct {
+		label string
+		rows  []int
+	}{
+		{"totalNotes", r.TotalNotes},
+		{"perfectHigh", r.PerfectHigh},
+		{"perfect", r.Perfect},
+		{"great", r.Great},
+		{"good", r.Good},
+		{"miss", r.Miss},
+	}
+}
+
+// numberFields lists the number ROIs of a screen type.
+// Labels use the keys of corpus sidecars, and the note counts are keyed as in NoteCounts.
+func numberFields(cfg ROIConfig, screenType ScreenType) []numberField {
+	integer := func(v float64) []string { return []string{strconv.Itoa(int(math.Round(v)))} }
+	switch screenType {
+	case ResultScreen:
+		r := cfg.Result
+		fields := []numberField{
+			{"result.judge", "judge", r.Judge, func(v float64) []string {
+				return []string{fmt.Sprintf("%.4f%%", v), fmt.Sprintf("%.4f", v)}
+			}},
+			{"result.score", "score", r.Score, func(v float64) []string {
+				return []string{groupThousands(int(math.Round(v))), strconv.Itoa(int(math.Round(v)))}
+			}},
+			{"result.patch", "patch", r.Patch, func(v float64) []string { return []string{fmt.Sprintf("%.2f", v)} }},
+			{"result.level", "level", r.Level, integer},
+			{"result.line", "line", r.Line, integer},
+		}
+		if len(r.NotesArea) == 4 {
+			for _, row := range noteRows(r) {
+				if len(row.rows) != 2 {
+					continue
+				}
+				fields = append(fields, numberField{"result." + row.label, row.label,
+					[]int{r.NotesArea[0], row.rows[0], r.NotesArea[2], row.rows[1]},
+					func(v float64) []string {
+						n := int(math.Round(v))
+						return []string{fmt.Sprintf("%04d", n), strconv.Itoa(n)}
+					}})
+			}
+		}
+		return fields
+	case SelectScreen:
+		s := cfg.Select
+		return []numberField{
+			{"select.majorJudge", "judge", s.MajorJudge, func(v float64) []string { return []string{strconv.Itoa(int(v))} }},
+			{"select.minorJudge", "judge", s.MinorJudge, func(v float64) []string {
+				return []string{fmt.Sprintf("%04d", int(math.Round((v-math.Floor(v))*10000)))}
+			}},
+			{"select.majorPatch", "patch", s.MajorPatch, func(v float64) []string { return []string{strconv.Itoa(int(v))} }},
+			{"select.minorPatch", "patch", s.MinorPatch, func(v float64) []string {
+				minor := int(math.Round((v - math.Floor(v)) * 100))
+				return []string{fmt.Sprintf("%02d", minor), fmt.Sprintf(".%02d", minor)}
+			}},
+			{"select.score", "score", s.Score, func(v float64) []string {
+				return []string{groupThousands(int(math.Round(v))), strconv.Itoa(int(math.Round(v)))}
+			}},
+			{"select.line", "line", s.Line, func(v float64) []string {
+				return []string{fmt.Sprintf("%dLINES", int(v)), strconv.Itoa(int(v))}
+			}},
+			{"select.level", "level", s.Level, integer},
+		}
+	}
+	return nil
+}
+
+// groupThousands formats n with a comma between groups of three digits, as the game does.
+func groupThousands(n int) string {
+	digits := strconv.Itoa(n)
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// onlyDigits drops every character of text that is not a digit.
+func onlyDigits(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, text)
+}
+
+// numberRead is the digits read from one field.
+type numberRead struct {
+	digits     string
+	confidence float64
+}
+
+// readNumbers reads every number field of the screen into the report.
+// Values are built from the digits alone, with the decimal places the game always shows,
+// so a misread decimal point does not shift them. Unreadable fields are left out.
+func readNumbers(img image.Image, cfg ROIConfig, layout Layout, screenType ScreenType, templates *DigitTemplates, report *AnalysisReport) {
+	reads := make(map[string]numberRead)
+	for _, field := range numberFields(cfg, screenType) {
+		rect, err := layout.Rect(field.coords)
+		if err != nil {
+			continue
+		}
+		text, confidence, err := templates.ReadText(img, rect, field.name)
+		if err != nil || onlyDigits(text) == "" {
+			continue
+		}
+		reads[field.name] = numberRead{onlyDigits(text), confidence}
+	}
+	value := func(names ...string) (float64, float64, bool) {
+		digits := ""
+		confidence := 1.0
+		for _, name := range names {
+			read, ok := reads[name]
+			if !ok {
+				return 0, 0, false
+			}
+			digits += read.digits
+			confidence = math.Min(confidence, read.confidence)
+		}
+		v, err := strconv.ParseFloat(digits, 64)
+		return v, confidence, err == nil
+	}
+
+	switch screenType {
+	case ResultScreen:
+		if v, c, ok := value("result.judge"); ok {
+			report.Judge, report.Confidence[FieldJudge] = v/10000, c
+		}
+		if v, c, ok := value("result.score"); ok {
+			report.Score, report.Confidence[FieldScore] = v, c
+		}
+		if v, c, ok := value("result.patch"); ok {
+			report.Patch, report.Confidence[FieldPatch] = v/100, c
+		}
+		if v, c, ok := value("result.level"); ok {
+			report.PatternObject.Level, report.Confidence[FieldLevel] = int(v), c
+		}
+		if v, c, ok := value("result.line"); ok {
+			report.PatternObject.Line, report.Confidence[FieldLine] = int(v), c
+		}
+		counts := make([]int, 0, 6)
+		confidence := 1.0
+		for _, row := range noteRows(cfg.Result) {
+			v, c, ok := value("result." + row.label)
+			if !ok {
+				break
+			}
+			counts = append(counts, int(v))
+			confidence = math.Min(confidence, c)
+		}
+		if len(counts) == 6 {
+			report.Notes = NoteCounts{counts[0], counts[1], counts[2], counts[3], counts[4], counts[5]}
+			report.Confidence[FieldNotes] = confidence
+		}
+	case SelectScreen:
+		if major, c, ok := value("select.majorJudge"); ok {
+			if minor, cMinor, ok := value("select.minorJudge"); ok {
+				report.Judge, report.Confidence[FieldJudge] = major+minor/10000, math.Min(c, cMinor)
+			}
+		}
+		if major, c, ok := value("select.majorPatch"); ok {
+			if minor, cMinor, ok := value("select.minorPatch"); ok {
+				report.Patch, report.Confidence[FieldPatch] = major+minor/100, math.Min(c, cMinor)
+			}
+		}
+		if v, c, ok := value("select.score"); ok {
+			report.Score, report.Confidence[FieldScore] = v, c
+		}
+		if v, c, ok := value("select.line"); ok {
+			report.PatternObject.Line, report.Confidence[FieldLine] = int(v), c
+		}
+		if v, c, ok := value("select.level"); ok {
+			report.PatternObject.Level, report.Confidence[FieldLevel] = int(v), c
+		}
+	}
+}
+
+// GlyphSample is a glyph cut from a labelled screenshot, for training templates.
+type GlyphSample struct {
+	Char  string
+	Field string
+	glyph glyph
+}
+
+// ArchiveLabels returns the labels of the number fields an archive record knows.
+func ArchiveLabels(a Archive) map[string]any {
+	return map[string]any{
+		"judge": a.Judge,
+		"score": a.Score,
+		"patch": a.Patch,
+		"level": a.Level,
+		"line":  a.Line,
+	}
+}
+
+// ExtractGlyphSamples cuts the glyphs of every labelled number field of a screenshot.
+// A field is skipped, and described in the returned list, when its glyphs cannot be
+// lined up with any way of drawing its label.
+func ExtractGlyphSamples(img image.Image, config *Config, labels map[string]any) ([]GlyphSample, []string, error) {
+	targetConfig, layout, screenType, _, err := locateScreen(img, config, AnalyzeOptions{Calibrations: NewCalibrationCache()})
+	if err != nil {
+		return nil, nil, err
+	}
+	if screenType != SelectScreen && screenType != ResultScreen {
+		return nil, nil, &ScreenError{ScreenType: screenType}
+	}
+
+	var samples []GlyphSample
+	var skipped []string
+	for _, field := range numberFields(targetConfig, screenType) {
+		label, ok := toFloat(labels[field.label])
+		if !ok {
+			continue
+		}
+		rect, err := layout.Rect(field.coords)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", field.name, err))
+			continue
+		}
+		glyphs := segmentGlyphs(img, rect)
+		text := ""
+		for _, candidate := range field.texts(label) {
+			if len([]rune(candidate)) == len(glyphs) {
+				text = candidate
+				break
+			}
+		}
+		if text == "" {
+			skipped = append(skipped, fmt.Sprintf("%s: found %d glyphs, expected %q", field.name, len(glyphs), field.texts(label)[0]))
+			continue
+		}
+		for i, char := range []rune(text) {
+			samples = append(samples, GlyphSample{Char: string(char), Field: field.name, glyph: glyphs[i]})
+		}
+	}
+	return samples, skipped, nil
+}
+
+// TrainDigitTemplates averages the samples of every character of every field into a template set.
+// Samples drawn at clearly different sizes within a field, like the integer and the
+// decimal places of the judge, are kept apart so the height of each stays exact.
+func TrainDigitTemplates(samples []GlyphSample, version string) *DigitTemplates {
+	type key struct {
+		field, char string
+		size        int
+	}
+	groups := make(map[key][]GlyphSample)
+	for _, s := range samples {
+		k := key{s.Field, s.Char, int(math.Round(s.glyph.height / glyphSizeStep))}
+		groups[k] = append(groups[k], s)
+	}
+
+	templates := &DigitTemplates{Version: version, Width: glyphWidth, Height: glyphHeight}
+	for k, group := range groups {
+		t := GlyphTemplate{Char: k.char, Field: k.field, Samples: len(group), coverage: make([]float64, glyphWidth*glyphHeight)}
+		for _, s := range group {
+			for i, v := range s.glyph.coverage {
+				t.coverage[i] += v / float64(len(group))
+			}
+			t.Aspect += s.glyph.aspect / float64(len(group))
+			t.Height += s.glyph.height / float64(len(group))
+		}
+		data := make([]byte, len(t.coverage))
+		for i, v := range t.coverage {
+			data[i] = byte(math.Round(clamp01(v) * 255))
+		}
+		t.Pixels = hex.EncodeToString(data)
+		templates.Glyphs = append(templates.Glyphs, t)
+	}
+	sort.Slice(templates.Glyphs, func(i, j int) bool {
+		a, b := templates.Glyphs[i], templates.Glyphs[j]
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		if a.Char != b.Char {
+			return a.Char < b.Char
+		}
+		return a.Height < b.Height
+	})
+	return templates
+}
+
+// Classify returns the character the templates read for the sample.
+func (d *DigitTemplates) Classify(s GlyphSample) string {
+	char, _ := d.classify(s.glyph, s.Field)
+	return char
+}
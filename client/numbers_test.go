@@ -0,0 +1,63 @@
+package client
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestGroupThousands(t *testing.T) {
+	for n, expected := range map[int]string{0: "0", 999: "999", 1000: "1,000", 167100: "167,100", 1234567: "1,234,567"} {
+		if got := groupThousands(n); got != expected {
+			t.Errorf("groupThousands(%d) = %q, expected %q", n, got, expected)
+		}
+	}
+}
+
+func TestNumberFieldTexts(t *testing.T) {
+	fields := numberFields(ROIConfig{}, SelectScreen)
+	texts := make(map[string][]string)
+	for _, field := range fields {
+		texts[field.name] = field.texts(99.9158)
+	}
+	if texts["select.majorJudge"][0] != "99" || texts["select.minorJudge"][0] != "9158" {
+		t.Errorf("unexpected judge texts: %v %v", texts["select.majorJudge"], texts["select.minorJudge"])
+	}
+	if texts["select.line"][0] != "99LINES" {
+		t.Errorf("unexpected line text: %v", texts["select.line"])
+	}
+}
+
+// TestAnalyzeImageReadsNumbers reads a capture the templates were not trained on:
+// result.png resampled to 2560x1440, so every glyph differs from the trained ones.
+func TestAnalyzeImageReadsNumbers(t *testing.T) {
+	templates, err := loadDigitTemplatesFrom("../testing/digits.json")
+	if err != nil {
+		t.Fatalf("failed to load digit templates: %v", err)
+	}
+	config, cache := loadGoldenInputs(t, "../testing")
+	capture, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 2560, 1440))
+	drawScaled(img, img.Bounds(), capture)
+
+	report, err := AnalyzeImage(img, cache, config, AnalyzeOptions{Digits: templates})
+	if err != nil {
+		t.Fatalf("AnalyzeImage failed: %v", err)
+	}
+	if math.Abs(report.Judge-99.8829) > 1e-9 || report.Score != 167100 || math.Abs(report.Patch-755.11) > 1e-9 {
+		t.Errorf("unexpected record: judge %v, score %v, patch %v", report.Judge, report.Score, report.Patch)
+	}
+	if report.PatternObject.Level != 18 || report.PatternObject.Line != 4 {
+		t.Errorf("unexpected pattern: %+v", report.PatternObject)
+	}
+	expected := NoteCounts{Total: 854, PerfectHigh: 784, Perfect: 68, Great: 1, Good: 1, Miss: 0}
+	if report.Notes != expected {
+		t.Errorf("expected notes %+v, got %+v", expected, report.Notes)
+	}
+	if !report.HasRecord() {
+		t.Error("expected the report to hold a record")
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ChartPoint is a value on a day.
+type ChartPoint struct {
+	Date  time.Time
+	Value float64
+}
+
+// Chart is a line of values over time, oldest first.
+type Chart struct {
+	Title  string
+	Points []ChartPoint
+}
+
+// ArchiveDate parses the DecodedAt of an archive record.
+func ArchiveDate(a Archive) (time.Time, bool) {
+	t, err := time.ParseInLocation(archiveDateLayout, a.DecodedAt, time.Local)
+	return t, err == nil
+}
+
+// ProgressArchives returns the server records with every confirmed record of the history,
+// as archive records sorted by DecodedAt. History may be nil. Records without a date are left out.
+func ProgressArchives(archives []Archive, history *History, decoder string) ([]Archive, error) {
+	var records []Archive
+	for _, a := range archives {
+		if _, ok := ArchiveDate(a); ok {
+			records = append(records, a)
+		}
+	}
+	if history != nil {
+		err := history.EachPlay(HistoryQuery{RecordsOnly: true, ConfirmedOnly: true}, func(r PlayRecord) error {
+			if !r.PlayedAt.IsZero() {
+				records = append(records, r.ToArchive(decoder))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Dates share one layout, so they sort as strings
+	sort.SliceStable(records, func(i, j int) bool { return records[i].DecodedAt < records[j].DecodedAt })
+	return records, nil
+}
+
+// PatternProgress returns the judge and patch charts of a pattern, with the best of every day.
+// Records must be sorted by DecodedAt, as ProgressArchives returns them.
+func PatternProgress(records []Archive, key PatternKey, cache *Cache) []Chart {
+	name := key.String()
+	if song, ok := cache.FindSong(key.SongID); ok {
+		name = fmt.Sprintf("%s %dL %s", song.Title, key.Line, key.Difficulty)
+	}
+	judge := Chart{Title: "Judge - " + name}
+	patch := Chart{Title: "Patch - " + name}
+	for _, a := range records {
+		if ArchiveKey(a) != key {
+			continue
+		}
+		date, _ := ArchiveDate(a)
+		if n := len(judge.Points); n > 0 && judge.Points[n-1].Date.Equal(date) {
+			judge.Points[n-1].Value = max(judge.Points[n-1].Value, a.Judge)
+			patch.Points[n-1].Value = max(patch.Points[n-1].Value, a.Patch)
+			continue
+		}
+		judge.Points = append(judge.Points, ChartPoint{date, a.Judge})
+		patch.Points = append(patch.Points, ChartPoint{date, a.Patch})
+	}
+	return []Chart{judge, patch}
+}
+
+// OverallProgress returns the average judge and patch of the played patterns and the rating,
+// from the bests at the end of every day. Records must be sorted by DecodedAt.
+func OverallProgress(records []Archive, cache *Cache, formula RatingConfig) []Chart {
+	judge := Chart{Title: "Average judge"}
+	patch := Chart{Title: "Average patch"}
+	rating := Chart{Title: fmt.Sprintf("Rating (top %d %s)", formula.TopN, formula.Method)}
+	for i, a := range records {
+		// Only the last record of a day adds a point
+		if i+1 < len(records) && records[i+1].DecodedAt == a.DecodedAt {
+			continue
+		}
+		date, _ := ArchiveDate(a)
+		bests := BestArchives(records[:i+1])
+		var judges, patches float64
+		for _, b := range bests {
+			judges += b.Judge
+			patches += b.Patch
+		}
+		n := float64(len(bests))
+		judge.Points = append(judge.Points, ChartPoint{date, judges / n})
+		patch.Points = append(patch.Points, ChartPoint{date, patches / n})
+		rating.Points = append(rating.Points, ChartPoint{date, ComputeRating(bests, cache, formula, nil).Total})
+	}
+	return []Chart{judge, patch, rating}
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressArchives(t *testing.T) {
+	history := openTestHistory(t)
+	day := time.Date(2025, 11, 22, 21, 0, 0, 0, time.Local)
+	incomplete := testPlay(1, 0, 0, 0, day)
+	incomplete.Record = false
+	for _, p := range []PlayRecord{testPlay(1, 98.5, 990000, 510, day), incomplete} {
+		if _, err := history.Add(p); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	archives := []Archive{
+		testArchive(1, 97, 980000, 500, "2025-11-20"),
+		testArchive(2, 90, 900000, 400, ""),
+	}
+
+	records, err := ProgressArchives(archives, history, "tester")
+	if err != nil {
+		t.Fatalf("ProgressArchives failed: %v", err)
+	}
+	if len(records) != 2 || records[0].DecodedAt != "2025-11-20" || records[1].DecodedAt != "2025-11-22" || records[1].Patch != 510 {
+		t.Errorf("expected the dated record and the recorded play, got %+v", records)
+	}
+}
+
+func TestPatternProgress(t *testing.T) {
+	cache := &Cache{Songs: []Song{{ID: 1, Title: "Firework"}}}
+	records := []Archive{
+		testArchive(1, 95, 950000, 480, "2025-11-20"),
+		testArchive(2, 99, 990000, 600, "2025-11-20"),
+		testArchive(1, 97, 970000, 470, "2025-11-21"),
+		testArchive(1, 96, 960000, 500, "2025-11-21"),
+	}
+	charts := PatternProgress(records, PatternKey{1, 4, "HARD"}, cache)
+	if len(charts) != 2 || charts[0].Title != "Judge - Firework 4L HARD" {
+		t.Fatalf("unexpected charts %+v", charts)
+	}
+	judge, patch := charts[0].Points, charts[1].Points
+	if len(judge) != 2 || judge[0].Value != 95 || judge[1].Value != 97 {
+		t.Errorf("expected the best judge of every day, got %+v", judge)
+	}
+	if len(patch) != 2 || patch[1].Value != 500 || patch[1].Date.Day() != 21 {
+		t.Errorf("expected the best patch of every day, got %+v", patch)
+	}
+}
+
+func TestOverallProgress(t *testing.T) {
+	records := []Archive{
+		testArchive(1, 95, 950000, 400, "2025-11-20"),
+		testArchive(2, 99, 990000, 600, "2025-11-20"),
+		testArchive(1, 97, 970000, 500, "2025-11-21"),
+	}
+	charts := OverallProgress(records, &Cache{}, RatingConfig{RatingSum, 50, 1})
+	if len(charts) != 3 {
+		t.Fatalf("expected 3 charts, got %d", len(charts))
+	}
+	judge, patch, rating := charts[0].Points, charts[1].Points, charts[2].Points
+	if len(judge) != 2 || judge[0].Value != 97 || judge[1].Value != 98 {
+		t.Errorf("unexpected average judges %+v", judge)
+	}
+	if len(patch) != 2 || patch[0].Value != 500 || patch[1].Value != 550 {
+		t.Errorf("unexpected average patches %+v", patch)
+	}
+	if len(rating) != 2 || rating[0].Value != 1000 || rating[1].Value != 1100 {
+		t.Errorf("unexpected ratings %+v", rating)
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"sort"
+)
+
+// Rating methods.
+const (
+	RatingSum     = "sum"
+	RatingAverage = "average"
+)
+
+// DefaultRatingConfig is the formula used when the config does not set one.
+var DefaultRatingConfig = RatingConfig{Method: RatingSum, TopN: 50, Scale: 1}
+
+// RatingConfig is the formula of the rating: the sum or average of the best TopN patches,
+// multiplied by Scale. An average divides by TopN, so it keeps rising until TopN patterns are played.
+type RatingConfig struct {
+	Method string  `yaml:"method" json:"method"`
+	TopN   int     `yaml:"topN" json:"topN"`
+	Scale  float64 `yaml:"scale" json:"scale"`
+}
+
+// RatingFormula returns the configured rating formula, with unset values taken from DefaultRatingConfig.
+func (c *Config) RatingFormula() RatingConfig {
+	formula := DefaultRatingConfig
+	if c.Rating == nil {
+		return formula
+	}
+	if c.Rating.Method != "" {
+		formula.Method = c.Rating.Method
+	}
+	if c.Rating.TopN > 0 {
+		formula.TopN = c.Rating.TopN
+	}
+	if c.Rating.Scale != 0 {
+		formula.Scale = c.Rating.Scale
+	}
+	return formula
+}
+
+// weight is what a patch point of a counted pattern adds to the total.
+func (f RatingConfig) weight() float64 {
+	if f.Method == RatingAverage && f.TopN > 0 {
+		return f.Scale / float64(f.TopN)
+	}
+	return f.Scale
+}
+
+// RatingEntry is a played pattern in the rating.
+type RatingEntry struct {
+	PatternKey
+	Title string
+	Level int
+	Patch float64
+	// Counted is true when the pattern is one of the TopN.
+	Counted      bool
+	Contribution float64
+	// Needed is the patch the pattern has to exceed to raise the total:
+	// its own patch when counted, the lowest counted patch otherwise.
+	Needed float64
+	// Potential is what the total would gain with the highest patch of the level,
+	// or 0 when that is not configured.
+	Potential float64
+}
+
+// Rating is the total of a formula over the best patch of every played pattern.
+type Rating struct {
+	Formula RatingConfig
+	Total   float64
+	// Cutoff is the lowest counted patch, or 0 while fewer than TopN patterns are played.
+	Cutoff float64
+	// Entries are sorted by patch, highest first.
+	Entries []RatingEntry
+}
+
+// ComputeRating computes the rating of archive records. Only the best patch of every pattern counts.
+// Levels and titles are taken from the cache, falling back to the record's level.
+// maxPatch is the highest patch of every level, as in Config.MaxPatch, and may be nil.
+func ComputeRating(archives []Archive, cache *Cache, formula RatingConfig, maxPatch map[int]float64) Rating {
+	best := make(map[PatternKey]Archive)
+	for _, a := range archives {
+		key := ArchiveKey(a)
+		if b, ok := best[key]; !ok || a.Patch > b.Patch {
+			best[key] = a
+		}
+	}
+
+	rating := Rating{Formula: formula}
+	for key, a := range best {
+		entry := RatingEntry{PatternKey: key, Level: a.Level, Patch: a.Patch}
+		if p, ok := cache.FindPattern(key); ok {
+			entry.Level = p.Level
+		}
+		if s, ok := cache.FindSong(key.SongID); ok {
+			entry.Title = s.Title
+		}
+		rating.Entries = append(rating.Entries, entry)
+	}
+	sort.Slice(rating.Entries, func(i, j int) bool {
+		a, b := rating.Entries[i], rating.Entries[j]
+		if a.Patch != b.Patch {
+			return a.Patch > b.Patch
+		}
+		return a.PatternKey.String() < b.PatternKey.String()
+	})
+
+	weight := formula.weight()
+	if len(rating.Entries) >= formula.TopN && formula.TopN > 0 {
+		rating.Cutoff = rating.Entries[formula.TopN-1].Patch
+	}
+	for i := range rating.Entries {
+		e := &rating.Entries[i]
+		e.Counted = i < formula.TopN
+		e.Needed = e.Patch
+		if e.Counted {
+			e.Contribution = e.Patch * weight
+			rating.Total += e.Contribution
+		} else {
+			e.Needed = rating.Cutoff
+		}
+		if highest, ok := maxPatch[e.Level]; ok && highest > e.Needed {
+			e.Potential = (highest - e.Needed) * weight
+		}
+	}
+	return rating
+}
+
+// With returns the total if the pattern reached the given patch. The pattern may be unplayed.
+func (r Rating) With(key PatternKey, patch float64) float64 {
+	patches := make([]float64, 0, len(r.Entries)+1)
+	found := false
+	for _, e := range r.Entries {
+		if e.PatternKey == key {
+			patches = append(patches, max(e.Patch, patch))
+			found = true
+		} else {
+			patches = append(patches, e.Patch)
+		}
+	}
+	if !found {
+		patches = append(patches, patch)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(patches)))
+
+	total := 0.0
+	for i := 0; i < len(patches) && i < r.Formula.TopN; i++ {
+		total += patches[i]
+	}
+	return total * r.Formula.weight()
+}
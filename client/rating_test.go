@@ -0,0 +1,59 @@
+package client
+
+import (
+	"math"
+	"testing"
+)
+
+func ratingArchive(songID int, level int, patch float64) Archive {
+	return Archive{SongID: songID, Line: 4, Difficulty: "HARD", Level: level, Patch: patch}
+}
+
+func TestRatingFormula(t *testing.T) {
+	config := Config{}
+	if f := config.RatingFormula(); f != DefaultRatingConfig {
+		t.Errorf("expected the default formula, got %+v", f)
+	}
+	config.Rating = &RatingConfig{Method: RatingAverage, TopN: 30}
+	if f := config.RatingFormula(); f != (RatingConfig{RatingAverage, 30, 1}) {
+		t.Errorf("unexpected formula %+v", f)
+	}
+}
+
+func TestComputeRating(t *testing.T) {
+	cache := &Cache{
+		Songs:    []Song{{ID: 1, Title: "Firework"}},
+		Patterns: []Pattern{{SongID: 1, Line: 4, Difficulty: "HARD", Level: 13}},
+	}
+	archives := []Archive{
+		ratingArchive(1, 12, 500),
+		ratingArchive(1, 12, 520), // a later, better record of the same pattern
+		ratingArchive(2, 12, 400),
+		ratingArchive(3, 11, 300),
+	}
+	maxPatch := map[int]float64{13: 600, 11: 450}
+
+	rating := ComputeRating(archives, cache, RatingConfig{RatingSum, 2, 1}, maxPatch)
+	if rating.Total != 920 || rating.Cutoff != 400 || len(rating.Entries) != 3 {
+		t.Fatalf("unexpected rating %+v", rating)
+	}
+	first, last := rating.Entries[0], rating.Entries[2]
+	if first.Title != "Firework" || first.Level != 13 || !first.Counted || first.Contribution != 520 || first.Potential != 80 {
+		t.Errorf("unexpected first entry %+v", first)
+	}
+	if last.Counted || last.Contribution != 0 || last.Needed != 400 || last.Potential != 50 {
+		t.Errorf("unexpected uncounted entry %+v", last)
+	}
+
+	if total := rating.With(PatternKey{3, 4, "HARD"}, 450); total != 970 {
+		t.Errorf("expected 970 with a raised uncounted pattern, got %v", total)
+	}
+	if total := rating.With(PatternKey{9, 4, "HARD"}, 390); total != 920 {
+		t.Errorf("expected 920 with an unplayed pattern below the cutoff, got %v", total)
+	}
+
+	average := ComputeRating(archives, cache, RatingConfig{RatingAverage, 4, 1}, nil)
+	if math.Abs(average.Total-305) > 1e-9 || average.Cutoff != 0 {
+		t.Errorf("expected an average over 4 patterns of 305, got %+v", average)
+	}
+}
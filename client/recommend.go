@@ -0,0 +1,140 @@
+package client
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// defaultLevelRange is how far from the skill level an unplayed pattern is recommended.
+const defaultLevelRange = 1
+
+// PatternFilter selects patterns by the DLC of their song, line and difficulty.
+// Empty lists match everything.
+type PatternFilter struct {
+	DLCs         []string
+	Lines        []int
+	Difficulties []string
+}
+
+// Matches reports whether the pattern of the song passes the filter.
+func (f PatternFilter) Matches(song Song, pattern Pattern) bool {
+	return (len(f.DLCs) == 0 || slices.Contains(f.DLCs, song.DLC)) &&
+		(len(f.Lines) == 0 || slices.Contains(f.Lines, pattern.Line)) &&
+		(len(f.Difficulties) == 0 || slices.Contains(f.Difficulties, pattern.Difficulty))
+}
+
+// RecommendOptions tunes the recommendations.
+type RecommendOptions struct {
+	Filter PatternFilter
+	// LevelRange is how far from the skill level unplayed patterns may be. It defaults to 1.
+	LevelRange int
+	// Limit caps the length of both lists when positive.
+	Limit int
+}
+
+// Recommendation is a pattern worth practising.
+type Recommendation struct {
+	Pattern Pattern
+	Song    Song
+	Played  bool
+	Patch   float64
+	// Achievable is the highest patch of the level if configured, or else the best patch
+	// the player has on a pattern of the same level. It is 0 when neither is known.
+	Achievable float64
+	Gap        float64
+	// RatingGain is what the rating would gain with the achievable patch.
+	RatingGain float64
+}
+
+// Recommendations are the practice lists of a player.
+type Recommendations struct {
+	// SkillLevel is the average level of the patterns counted in the rating.
+	SkillLevel float64
+	// Unplayed are patterns near the skill level, nearest first.
+	Unplayed []Recommendation
+	// Improve are played patterns, largest gap first.
+	Improve []Recommendation
+}
+
+// Recommend builds practice lists from the archive records of a player and the patterns of the cache.
+func Recommend(archives []Archive, cache *Cache, formula RatingConfig, maxPatch map[int]float64, opts RecommendOptions) Recommendations {
+	if opts.LevelRange <= 0 {
+		opts.LevelRange = defaultLevelRange
+	}
+	rating := ComputeRating(archives, cache, formula, maxPatch)
+	played := make(map[PatternKey]RatingEntry)
+	levelBest := make(map[int]float64)
+	levels, counted := 0, 0
+	for _, e := range rating.Entries {
+		played[e.PatternKey] = e
+		levelBest[e.Level] = max(levelBest[e.Level], e.Patch)
+		if e.Counted {
+			levels += e.Level
+			counted++
+		}
+	}
+
+	var recs Recommendations
+	if counted > 0 {
+		recs.SkillLevel = float64(levels) / float64(counted)
+	} else {
+		// Without any record, start from the easiest patterns
+		for i, p := range cache.Patterns {
+			if i == 0 || float64(p.Level) < recs.SkillLevel {
+				recs.SkillLevel = float64(p.Level)
+			}
+		}
+	}
+
+	for _, p := range cache.Patterns {
+		song, _ := cache.FindSong(p.SongID)
+		if !opts.Filter.Matches(song, p) {
+			continue
+		}
+		key := PatternKey{p.SongID, p.Line, p.Difficulty}
+		r := Recommendation{Pattern: p, Song: song}
+		if highest, ok := maxPatch[p.Level]; ok {
+			r.Achievable = highest
+		} else {
+			r.Achievable = levelBest[p.Level]
+		}
+
+		if e, ok := played[key]; ok {
+			r.Played, r.Patch = true, e.Patch
+			r.Gap = r.Achievable - e.Patch
+			if r.Gap <= 0 {
+				continue
+			}
+			r.RatingGain = rating.With(key, r.Achievable) - rating.Total
+			recs.Improve = append(recs.Improve, r)
+			continue
+		}
+		if math.Abs(float64(p.Level)-recs.SkillLevel) > float64(opts.LevelRange) {
+			continue
+		}
+		r.Gap = r.Achievable
+		if r.Achievable > 0 {
+			r.RatingGain = rating.With(key, r.Achievable) - rating.Total
+		}
+		recs.Unplayed = append(recs.Unplayed, r)
+	}
+
+	sort.SliceStable(recs.Unplayed, func(i, j int) bool {
+		a, b := recs.Unplayed[i], recs.Unplayed[j]
+		da := math.Abs(float64(a.Pattern.Level) - recs.SkillLevel)
+		db := math.Abs(float64(b.Pattern.Level) - recs.SkillLevel)
+		if da != db {
+			return da < db
+		}
+		return a.RatingGain > b.RatingGain
+	})
+	sort.SliceStable(recs.Improve, func(i, j int) bool {
+		return recs.Improve[i].Gap > recs.Improve[j].Gap
+	})
+	if opts.Limit > 0 {
+		recs.Unplayed = recs.Unplayed[:min(opts.Limit, len(recs.Unplayed))]
+		recs.Improve = recs.Improve[:min(opts.Limit, len(recs.Improve))]
+	}
+	return recs
+}
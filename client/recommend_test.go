@@ -0,0 +1,78 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestRecommend(t *testing.T) {
+	cache := &Cache{
+		Songs: []Song{{ID: 1, Title: "Base"}, {ID: 2, Title: "Pack", DLC: "Pack 1"}},
+		Patterns: []Pattern{
+			{SongID: 1, Line: 4, Difficulty: "EASY", Level: 5},
+			{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10},
+			{SongID: 1, Line: 6, Difficulty: "HARD", Level: 11},
+			{SongID: 1, Line: 6, Difficulty: "OVER", Level: 14},
+			{SongID: 2, Line: 4, Difficulty: "HARD", Level: 10},
+			{SongID: 2, Line: 4, Difficulty: "OVER", Level: 9},
+		},
+	}
+	archives := []Archive{
+		{SongID: 1, Line: 4, Difficulty: "EASY", Level: 5, Patch: 200},
+		{SongID: 1, Line: 4, Difficulty: "HARD", Level: 10, Patch: 400},
+	}
+	formula := RatingConfig{RatingSum, 1, 1}
+	recs := Recommend(archives, cache, formula, map[int]float64{5: 250, 10: 450}, RecommendOptions{})
+
+	if recs.SkillLevel != 10 {
+		t.Errorf("expected skill level 10, got %v", recs.SkillLevel)
+	}
+	var unplayed []PatternKey
+	for _, r := range recs.Unplayed {
+		unplayed = append(unplayed, PatternKey{r.Pattern.SongID, r.Pattern.Line, r.Pattern.Difficulty})
+	}
+	// Level 10 first, then the levels one away; level 14 is too far
+	expected := []PatternKey{{2, 4, "HARD"}, {1, 6, "HARD"}, {2, 4, "OVER"}}
+	if len(unplayed) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, unplayed)
+	}
+	for i := range expected {
+		if unplayed[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, unplayed)
+		}
+	}
+	if r := recs.Unplayed[0]; r.Achievable != 450 || r.RatingGain != 50 {
+		t.Errorf("unexpected unplayed recommendation %+v", r)
+	}
+
+	if len(recs.Improve) != 2 || recs.Improve[0].Pattern.Level != 5 || recs.Improve[0].Gap != 50 || recs.Improve[0].RatingGain != 0 {
+		t.Errorf("unexpected improvements %+v", recs.Improve)
+	}
+	if r := recs.Improve[1]; r.Gap != 50 || r.RatingGain != 50 {
+		t.Errorf("unexpected improvement %+v", r)
+	}
+
+	// Without configured maxima the best patch of the level is the target
+	recs = Recommend(archives, cache, formula, nil, RecommendOptions{Filter: PatternFilter{DLCs: []string{"Pack 1"}}})
+	if len(recs.Improve) != 0 || len(recs.Unplayed) != 2 || recs.Unplayed[0].Achievable != 400 {
+		t.Errorf("unexpected filtered recommendations %+v", recs)
+	}
+}
+
+func TestPatternFilter(t *testing.T) {
+	song := Song{DLC: "Pack 1"}
+	pattern := Pattern{Line: 6, Difficulty: "OVER"}
+	for _, tt := range []struct {
+		filter   PatternFilter
+		expected bool
+	}{
+		{PatternFilter{}, true},
+		{PatternFilter{DLCs: []string{""}}, false},
+		{PatternFilter{DLCs: []string{"", "Pack 1"}, Lines: []int{6}}, true},
+		{PatternFilter{Lines: []int{4}}, false},
+		{PatternFilter{Difficulties: []string{"HARD", "OVER"}}, true},
+	} {
+		if got := tt.filter.Matches(song, pattern); got != tt.expected {
+			t.Errorf("%+v: expected %v, got %v", tt.filter, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// Fonts bundled with the client, relative to its working directory.
+const (
+	RegularFontPath = "assets/Noto_Sans_KR/static/NotoSansKR-Regular.ttf"
+	BoldFontPath    = "assets/Noto_Sans_KR/static/NotoSansKR-Bold.ttf"
+)
+
+// Fonts holds the faces used to draw generated images.
+// The same font is loaded at every size a drawing needs.
+type Fonts struct {
+	regular *opentype.Font
+	bold    *opentype.Font
+}
+
+// FallbackFonts returns the Go fonts of x/image, for when the bundled fonts are missing.
+// They have no Hangul, so Korean titles are not readable with them.
+func FallbackFonts() *Fonts {
+	regular, _ := opentype.Parse(goregular.TTF)
+	bold, _ := opentype.Parse(gobold.TTF)
+	return &Fonts{regular: regular, bold: bold}
+}
+
+// LoadFonts parses the regular and bold TrueType or OpenType fonts.
+func LoadFonts(regularPath string, boldPath string) (*Fonts, error) {
+	regular, err := parseFont(regularPath)
+	if err != nil {
+		return nil, err
+	}
+	bold, err := parseFont(boldPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Fonts{regular: regular, bold: bold}, nil
+}
+
+func parseFont(path string) (*opentype.Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading font: %v", err)
+	}
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing font %s: %v", path, err)
+	}
+	return f, nil
+}
+
+// Face returns a face of the given pixel size.
+func (f *Fonts) Face(size float64, bold bool) font.Face {
+	src := f.regular
+	if bold {
+		src = f.bold
+	}
+	face, err := opentype.NewFace(src, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		// NewFace only fails on invalid options
+		panic(err)
+	}
+	return face
+}
+
+// drawText draws text with its baseline at y, cut with an ellipsis to fit maxWidth when positive.
+// It returns the width drawn.
+func drawText(dst *image.RGBA, face font.Face, x int, y int, text string, c color.Color, maxWidth int) int {
+	if maxWidth > 0 {
+		text = fitText(face, text, maxWidth)
+	}
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(c), Face: face, Dot: fixed.P(x, y)}
+	d.DrawString(text)
+	return d.Dot.X.Ceil() - x
+}
+
+// fitText cuts text with an ellipsis until it is at most maxWidth pixels wide.
+func fitText(face font.Face, text string, maxWidth int) string {
+	if font.MeasureString(face, text).Ceil() <= maxWidth {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if candidate := string(runes) + "…"; font.MeasureString(face, candidate).Ceil() <= maxWidth {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// fillRect fills a rectangle of dst with a color.
+func fillRect(dst *image.RGBA, rect image.Rectangle, c color.Color) {
+	xdraw.Draw(dst, rect, image.NewUniform(c), image.Point{}, xdraw.Over)
+}
+
+// drawScaled draws src scaled to fill rect.
+func drawScaled(dst *image.RGBA, rect image.Rectangle, src image.Image) {
+	xdraw.CatmullRom.Scale(dst, rect, src, src.Bounds(), xdraw.Over, nil)
+}
+
+// drawPolyline draws an antialiased line through the points.
+func drawPolyline(dst *image.RGBA, points []image.Point, width float64, c color.Color) {
+	b := dst.Bounds()
+	z := vector.NewRasterizer(b.Dx(), b.Dy())
+	for i := 1; i < len(points); i++ {
+		p, q := points[i-1].Sub(b.Min), points[i].Sub(b.Min)
+		dx, dy := float64(q.X-p.X), float64(q.Y-p.Y)
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		// Every segment is a quad of the width around it
+		nx, ny := float32(-dy/length*width/2), float32(dx/length*width/2)
+		z.MoveTo(float32(p.X)+nx, float32(p.Y)+ny)
+		z.LineTo(float32(q.X)+nx, float32(q.Y)+ny)
+		z.LineTo(float32(q.X)-nx, float32(q.Y)-ny)
+		z.LineTo(float32(p.X)-nx, float32(p.Y)-ny)
+		z.ClosePath()
+	}
+	z.Draw(dst, b, image.NewUniform(c), image.Point{})
+}
+
+// SavePNG writes an image to a PNG file.
+func SavePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		return fmt.Errorf("error writing PNG: %v", err)
+	}
+	return nil
+}
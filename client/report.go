@@ -0,0 +1,95 @@
+package client
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Field names used as keys of AnalysisReport.Confidence.
+const (
+	FieldScreen     = "screen"
+	FieldJacket     = "jacket"
+	FieldDifficulty = "difficulty"
+	FieldJudge      = "judge"
+	FieldScore      = "score"
+	FieldPatch      = "patch"
+	FieldLevel      = "level"
+	FieldLine       = "line"
+	FieldNotes      = "notes"
+	FieldFullCombo  = "fullCombo"
+)
+
+// LowConfidence is the confidence below which a field has to be confirmed by the user.
+const LowConfidence = 0.6
+
+// thresholdConfidence is the confidence of a distance right at its acceptance threshold.
+// It is above LowConfidence, so an accepted match is not flagged for its distance alone.
+const thresholdConfidence = 0.7
+
+// distanceConfidence maps a distance to [0, 1]. It is 1 for an exact match,
+// reaches thresholdConfidence at the acceptance threshold and falls linearly beyond it.
+func distanceConfidence(distance float64, threshold float64) float64 {
+	return clamp01(1 - (1-thresholdConfidence)*distance/threshold)
+}
+
+// marginConfidence maps the gap between the best and the second best candidate to [0, 1].
+// It is 1 once the gap reaches scale.
+func marginConfidence(margin float64, scale float64) float64 {
+	if scale <= 0 {
+		return 1
+	}
+	return clamp01(margin / scale)
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// LowConfidenceFields returns the extracted fields whose confidence is below LowConfidence, sorted by name.
+func (r AnalysisReport) LowConfidenceFields() []string {
+	var fields []string
+	for field, confidence := range r.Confidence {
+		if confidence < LowConfidence {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// IsLowConfidence reports whether the field was extracted with low confidence.
+// Fields that were not extracted are not low confidence.
+func (r AnalysisReport) IsLowConfidence(field string) bool {
+	confidence, ok := r.Confidence[field]
+	return ok && confidence < LowConfidence
+}
+
+// HasRecord reports whether the report holds a full play record that can be uploaded.
+// The select screen does not show the difficulty, so its reports never hold one.
+func (r AnalysisReport) HasRecord() bool {
+	for _, field := range []string{FieldJudge, FieldScore, FieldPatch} {
+		if _, ok := r.Confidence[field]; !ok {
+			return false
+		}
+	}
+	p := r.PatternObject
+	return p.Level > 0 && p.Line > 0 && p.Difficulty != ""
+}
+
+// ToArchive converts the report into an archive record of the given decoder.
+func (r AnalysisReport) ToArchive(decoder string) Archive {
+	return Archive{
+		Decoder:    decoder,
+		SongID:     r.SongObject.ID,
+		Line:       r.PatternObject.Line,
+		Difficulty: r.PatternObject.Difficulty,
+		Level:      r.PatternObject.Level,
+		Judge:      r.Judge,
+		Score:      r.Score,
+		Patch:      r.Patch,
+		DecodedAt:  time.Now().Format("2006-01-02"),
+		FullCombo:  r.FullCombo,
+		MaxPatch:   r.MaxPatch,
+	}
+}
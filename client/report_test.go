@@ -0,0 +1,122 @@
+package client
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestLowConfidenceFields(t *testing.T) {
+	report := AnalysisReport{Confidence: map[string]float64{
+		FieldScreen:     1,
+		FieldJacket:     0.3,
+		FieldDifficulty: LowConfidence,
+		FieldJudge:      0.59,
+	}}
+	expected := []string{FieldJacket, FieldJudge}
+	if fields := report.LowConfidenceFields(); !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected %v, got %v", expected, fields)
+	}
+	if !report.IsLowConfidence(FieldJacket) {
+		t.Error("expected jacket to be low confidence")
+	}
+	if report.IsLowConfidence(FieldPatch) {
+		t.Error("expected missing patch not to be low confidence")
+	}
+}
+
+func TestHasRecord(t *testing.T) {
+	report := AnalysisReport{
+		PatternObject: Pattern{SongID: 1, Line: 4, Difficulty: "OVER", Level: 18},
+		Confidence:    map[string]float64{FieldJudge: 1, FieldScore: 1},
+	}
+	if report.HasRecord() {
+		t.Error("expected report without patch not to have a record")
+	}
+	report.Confidence[FieldPatch] = 0.1
+	if !report.HasRecord() {
+		t.Error("expected report with judge, score and patch to have a record")
+	}
+	report.PatternObject.Level = 0
+	if report.HasRecord() {
+		t.Error("expected report without level not to have a record")
+	}
+	report.PatternObject.Level = 18
+	report.PatternObject.Difficulty = ""
+	if report.HasRecord() {
+		t.Error("expected report without difficulty not to have a record")
+	}
+}
+
+func TestSelectScreenHasNoRecord(t *testing.T) {
+	templates, err := loadDigitTemplatesFrom("../testing/digits.json")
+	if err != nil {
+		t.Fatalf("failed to load digit templates: %v", err)
+	}
+	config, cache := loadGoldenInputs(t, "../testing")
+	img, err := loadImage("../testing/select.png")
+	if err != nil {
+		t.Fatalf("failed to load select.png: %v", err)
+	}
+	report, err := AnalyzeImage(img, cache, config, AnalyzeOptions{Digits: templates})
+	if err != nil {
+		t.Fatalf("AnalyzeImage failed: %v", err)
+	}
+	// Every number is read, but the difficulty is unknown
+	if report.PatternObject.Level != 21 || report.Score != 209500 {
+		t.Fatalf("unexpected select report: level %d, score %v", report.PatternObject.Level, report.Score)
+	}
+	if report.HasRecord() {
+		t.Error("expected a select screen not to hold a record")
+	}
+}
+
+func TestConfidenceScales(t *testing.T) {
+	if c := distanceConfidence(0, 2); c != 1 {
+		t.Errorf("expected 1 for exact match, got %v", c)
+	}
+	if c := distanceConfidence(2, 2); c < LowConfidence {
+		t.Errorf("expected an accepted distance to stay above %v, got %v", LowConfidence, c)
+	}
+	if c := distanceConfidence(4, 2); c >= LowConfidence {
+		t.Errorf("expected twice the threshold to be low confidence, got %v", c)
+	}
+	if c := distanceConfidence(10, 2); c != 0 {
+		t.Errorf("expected 0 far beyond threshold, got %v", c)
+	}
+	if c := marginConfidence(1, 0.5); c != 1 {
+		t.Errorf("expected 1 for a wide margin, got %v", c)
+	}
+}
+
+func TestClassifyDifficulty(t *testing.T) {
+	resultImg, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+	config := &Config{
+		DifficultyColors: DifficultyColors{
+			Easy: []int{254, 179, 26},
+			Hard: []int{252, 109, 111},
+			Over: []int{187, 99, 219},
+			Plus: []int{69, 81, 141},
+		},
+		ColorTolerance: 5,
+	}
+
+	difficulty, confidence, err := classifyDifficulty(resultImg, []int{300, 730}, identityLayout(resultImg.Bounds()), config)
+	if err != nil {
+		t.Fatalf("classifyDifficulty failed: %v", err)
+	}
+	if difficulty != "OVER" {
+		t.Errorf("expected OVER, got %s", difficulty)
+	}
+	if confidence < LowConfidence {
+		t.Errorf("expected high confidence, got %.2f", confidence)
+	}
+
+	blank := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	if _, _, err := classifyDifficulty(blank, []int{300, 730}, identityLayout(blank.Bounds()), config); err == nil {
+		t.Error("expected black sample not to match any difficulty")
+	}
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ROICheck is the anchor check of a proposed ROIConfig against one screenshot.
+type ROICheck struct {
+	ScreenType ScreenType
+	Distance   int
+}
+
+// ScaleROIConfig maps every ROI of a reference config through the layout,
+// giving a config for screenshots of the given size that needs no layout mapping.
+// Note rows only hold Y coordinates and the notes area only holds X coordinates.
+func ScaleROIConfig(cfg ROIConfig, layout Layout, size ScreenSize) ROIConfig {
+	box := func(coords []int) []int {
+		if len(coords) != 4 {
+			return coords
+		}
+		topLeft := layout.Point(coords[0], coords[1])
+		bottomRight := layout.Point(coords[2], coords[3])
+		return []int{topLeft.X, topLeft.Y, bottomRight.X, bottomRight.Y}
+	}
+	point := func(coords []int) []int {
+		if len(coords) != 2 {
+			return coords
+		}
+		p := layout.Point(coords[0], coords[1])
+		return []int{p.X, p.Y}
+	}
+	row := func(coords []int) []int {
+		if len(coords) != 2 {
+			return coords
+		}
+		return []int{layout.Point(0, coords[0]).Y, layout.Point(0, coords[1]).Y}
+	}
+	columns := func(coords []int) []int {
+		if len(coords) != 4 {
+			return coords
+		}
+		// Only the X range is used, the Y values are placeholders
+		return []int{layout.Point(coords[0], 0).X, coords[1], layout.Point(coords[2], 0).X, coords[3]}
+	}
+
+	s, r := cfg.Select, cfg.Result
+	return ROIConfig{
+		ScreenSize: size.String(),
+		Select: SelectROIConfig{
+			SpeedWidget: box(s.SpeedWidget),
+			PopupAnchor: box(s.PopupAnchor),
+			Jacket:      box(s.Jacket),
+			MajorJudge:  box(s.MajorJudge),
+			MinorJudge:  box(s.MinorJudge),
+			Line:        box(s.Line),
+			Level:       box(s.Level),
+			MajorPatch:  box(s.MajorPatch),
+			MinorPatch:  box(s.MinorPatch),
+			Score:       box(s.Score),
+			FullCombo:   box(s.FullCombo),
+			MaxPatch:    point(s.MaxPatch),
+			Rank:        box(s.Rank),
+		},
+		Result: ResultROIConfig{
+			Anchor:      box(r.Anchor),
+			Jacket:      box(r.Jacket),
+			Judge:       box(r.Judge),
+			Line:        box(r.Line),
+			Level:       box(r.Level),
+			Patch:       box(r.Patch),
+			Score:       box(r.Score),
+			Rank:        box(r.Rank),
+			NotesArea:   columns(r.NotesArea),
+			TotalNotes:  row(r.TotalNotes),
+			PerfectHigh: row(r.PerfectHigh),
+			Perfect:     row(r.Perfect),
+			Great:       row(r.Great),
+			Good:        row(r.Good),
+			Miss:        row(r.Miss),
+			Difficulty:  point(r.Difficulty),
+			FullCombo:   box(r.FullCombo),
+		},
+		Pause: PauseROIConfig{
+			Anchor: box(cfg.Pause.Anchor),
+		},
+	}
+}
+
+// ProposeROIConfig calibrates the reference config against screenshots of a new resolution
+// and scales every ROI with the calibration that identifies the most screenshots by their anchor.
+// The reference may be any entry: its coordinates are scaled from its own screen size.
+// The checks of the chosen config against every screenshot are returned with it.
+// All screenshots must have the same size.
+func ProposeROIConfig(images []image.Image, reference ROIConfig, config *Config) (ROIConfig, Calibration, []ROICheck, error) {
+	if len(images) == 0 {
+		return ROIConfig{}, Calibration{}, nil, fmt.Errorf("no screenshots given")
+	}
+	referenceSize, err := ParseScreenSize(reference.ScreenSize)
+	if err != nil {
+		return ROIConfig{}, Calibration{}, nil, fmt.Errorf("error reading reference entry: %v", err)
+	}
+	size := ScreenSize{Width: images[0].Bounds().Dx(), Height: images[0].Bounds().Dy()}
+
+	// The detected viewport comes first, so it wins when the anchors agree with it
+	initial := DetectLayout(images[0], referenceSize)
+	calibrations := []Calibration{{
+		OffsetX: initial.Viewport.Min.X,
+		OffsetY: initial.Viewport.Min.Y,
+		Scale:   float64(initial.Viewport.Dx()) / float64(referenceSize.Width),
+		Anchor:  UnknownScreen,
+	}}
+	for i, img := range images {
+		imgSize := ScreenSize{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+		if imgSize != size {
+			return ROIConfig{}, Calibration{}, nil, fmt.Errorf("screenshot %d is %v, expected %v", i+1, imgSize, size)
+		}
+		calibration, err := Calibrate(img, reference, config, DetectLayout(img, referenceSize))
+		if err != nil {
+			continue
+		}
+		calibrations = append(calibrations, calibration)
+	}
+
+	// Each calibration is only exact for its own anchor, so keep the one that fits every screenshot best
+	var best ROIConfig
+	var bestCalibration Calibration
+	var bestChecks []ROICheck
+	bestFound, bestDistance := -1, math.MaxInt
+	for _, calibration := range calibrations {
+		proposed := ScaleROIConfig(reference, calibration.Layout(referenceSize), size)
+		checks, err := checkROIConfig(images, proposed, config)
+		if err != nil {
+			return ROIConfig{}, Calibration{}, nil, err
+		}
+		found, distance := 0, 0
+		for _, check := range checks {
+			if check.ScreenType != UnknownScreen {
+				found++
+				distance += check.Distance
+			}
+		}
+		if found > bestFound || (found == bestFound && distance < bestDistance) {
+			best, bestCalibration, bestChecks = proposed, calibration, checks
+			bestFound, bestDistance = found, distance
+		}
+	}
+	if bestFound == 0 {
+		return ROIConfig{}, Calibration{}, nil, fmt.Errorf("no screen anchor found in any screenshot")
+	}
+	return best, bestCalibration, bestChecks, nil
+}
+
+// checkROIConfig decides the screen type of every screenshot with the anchors of cfg.
+func checkROIConfig(images []image.Image, cfg ROIConfig, config *Config) ([]ROICheck, error) {
+	checks := make([]ROICheck, len(images))
+	for i, img := range images {
+		screenType, distance, err := decideScreenType(img, cfg, identityLayout(img.Bounds()), config)
+		if err != nil {
+			return nil, err
+		}
+		checks[i] = ROICheck{ScreenType: screenType, Distance: distance}
+	}
+	return checks, nil
+}
+
+// MarshalROIConfigs returns a YAML "configs:" document holding the given entries,
+// with coordinates written as flow sequences as in config.yaml. ROIs that are not set are left out.
+func MarshalROIConfigs(configs ...ROIConfig) ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(map[string][]ROIConfig{"configs": configs}); err != nil {
+		return nil, err
+	}
+	flowCoordinates(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// flowCoordinates switches every sequence of scalars to flow style and drops empty sequences from mappings.
+func flowCoordinates(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		content := node.Content[:0]
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if value := node.Content[i+1]; value.Kind == yaml.SequenceNode && len(value.Content) == 0 {
+				continue
+			}
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+		node.Content = content
+	}
+	if node.Kind == yaml.SequenceNode {
+		scalars := true
+		for _, child := range node.Content {
+			if child.Kind != yaml.ScalarNode {
+				scalars = false
+				break
+			}
+		}
+		if scalars {
+			node.Style = yaml.FlowStyle
+			return
+		}
+	}
+	for _, child := range node.Content {
+		flowCoordinates(child)
+	}
+}
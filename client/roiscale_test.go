@@ -0,0 +1,128 @@
+package client
+
+import (
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestScaleROIConfig(t *testing.T) {
+	cfg := ROIConfig{
+		ScreenSize: "1920x1080",
+		Select:     SelectROIConfig{Jacket: []int{760, 66, 1160, 466}, MaxPatch: []int{1033, 726}},
+		Result:     ResultROIConfig{NotesArea: []int{874, 0, 950, 0}, Miss: []int{800, 828}},
+	}
+	layout := Layout{Viewport: image.Rect(10, 20, 1290, 740), Reference: referenceSize}
+
+	scaled := ScaleROIConfig(cfg, layout, ScreenSize{Width: 1300, Height: 760})
+	if scaled.ScreenSize != "1300x760" {
+		t.Errorf("expected screen size 1300x760, got %s", scaled.ScreenSize)
+	}
+	for _, tt := range []struct {
+		name     string
+		got      []int
+		expected []int
+	}{
+		{"jacket", scaled.Select.Jacket, []int{517, 64, 783, 331}},
+		{"maxPatch", scaled.Select.MaxPatch, []int{699, 504}},
+		{"notesArea", scaled.Result.NotesArea, []int{593, 0, 643, 0}},
+		{"miss", scaled.Result.Miss, []int{553, 572}},
+		{"speedWidget", scaled.Select.SpeedWidget, nil},
+	} {
+		if !reflect.DeepEqual(tt.got, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, tt.got)
+		}
+	}
+}
+
+func TestProposeROIConfig(t *testing.T) {
+	var images []image.Image
+	for _, path := range []string{"../testing/select.png", "../testing/result.png"} {
+		img, err := loadImage(path)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", path, err)
+		}
+		images = append(images, windowedCapture(img))
+	}
+
+	proposed, _, checks, err := ProposeROIConfig(images, calibrationConfig.Configs[0], calibrationConfig)
+	if err != nil {
+		t.Fatalf("ProposeROIConfig failed: %v", err)
+	}
+	if proposed.ScreenSize != "1936x1119" {
+		t.Errorf("expected screen size 1936x1119, got %s", proposed.ScreenSize)
+	}
+	for i, expected := range []ScreenType{SelectScreen, ResultScreen} {
+		if checks[i].ScreenType != expected {
+			t.Errorf("screenshot %d: expected %v, got %v", i+1, expected, checks[i].ScreenType)
+		}
+	}
+	jacket := proposed.Result.Jacket
+	if abs(jacket[0]-130) > 3 || abs(jacket[1]-224) > 3 {
+		t.Errorf("expected the result jacket near (130, 224), got %v", jacket)
+	}
+}
+
+func TestProposeROIConfigFromOtherEntry(t *testing.T) {
+	// A 2560x1440 entry, scaled from the reference entry, proposed for screenshots of its own size
+	large := ScreenSize{Width: 2560, Height: 1440}
+	entry := ScaleROIConfig(calibrationConfig.Configs[0], Layout{Viewport: image.Rect(0, 0, 2560, 1440), Reference: referenceSize}, large)
+	config := *calibrationConfig
+	config.Configs = append([]ROIConfig{entry}, config.Configs...)
+
+	var images []image.Image
+	for _, path := range []string{"../testing/select.png", "../testing/result.png"} {
+		img, err := loadImage(path)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", path, err)
+		}
+		scaled := image.NewRGBA(image.Rect(0, 0, large.Width, large.Height))
+		drawScaled(scaled, scaled.Bounds(), img)
+		images = append(images, scaled)
+	}
+
+	proposed, _, checks, err := ProposeROIConfig(images, entry, &config)
+	if err != nil {
+		t.Fatalf("ProposeROIConfig failed: %v", err)
+	}
+	for i, expected := range []ScreenType{SelectScreen, ResultScreen} {
+		if checks[i].ScreenType != expected {
+			t.Errorf("screenshot %d: expected %v, got %v", i+1, expected, checks[i].ScreenType)
+		}
+	}
+	// The entry is already at the screenshot size, so its boxes are kept:
+	// the result jacket at (122, 193) of 1920x1080 is at (163, 257)
+	jacket := proposed.Result.Jacket
+	if abs(jacket[0]-163) > 3 || abs(jacket[1]-257) > 3 {
+		t.Errorf("expected the result jacket near (163, 257), got %v", jacket)
+	}
+
+	if _, _, _, err := ProposeROIConfig(images, ROIConfig{}, &config); err == nil {
+		t.Error("expected an entry without a screen size to be refused")
+	}
+}
+
+func TestMarshalROIConfigs(t *testing.T) {
+	cfg := ROIConfig{
+		ScreenSize: "1280x720",
+		Select:     SelectROIConfig{Jacket: []int{507, 44, 773, 311}},
+	}
+	data, err := MarshalROIConfigs(cfg)
+	if err != nil {
+		t.Fatalf("MarshalROIConfigs failed: %v", err)
+	}
+	if !strings.Contains(string(data), "jacket: [507, 44, 773, 311]") {
+		t.Errorf("expected flow-style coordinates, got:\n%s", data)
+	}
+
+	var decoded Config
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to parse YAML: %v", err)
+	}
+	if len(decoded.Configs) != 1 || !reflect.DeepEqual(decoded.Configs[0].Select.Jacket, cfg.Select.Jacket) {
+		t.Errorf("unexpected round trip: %+v", decoded.Configs)
+	}
+}
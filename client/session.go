@@ -0,0 +1,251 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultSessionIdle is how long a session waits for the next play when the config does not set it.
+const DefaultSessionIdle = 30 * time.Minute
+
+// SessionIdle returns the configured idle timeout of play sessions.
+func (c *Config) SessionIdle() time.Duration {
+	if c.SessionIdleMinutes > 0 {
+		return time.Duration(c.SessionIdleMinutes) * time.Minute
+	}
+	return DefaultSessionIdle
+}
+
+// SessionPlay is an analysed play of a session.
+type SessionPlay struct {
+	PlayRecord
+	Improvement Improvement `json:"improvement"`
+}
+
+// Session is a run of plays without a pause longer than the idle timeout.
+type Session struct {
+	Start time.Time
+	// Last is the time of the latest play, or when the session was ended manually.
+	Last  time.Time
+	Plays []SessionPlay
+	// Baseline holds the personal bests from before the session, to compute the rating change.
+	Baseline []Archive
+	Ended    bool
+}
+
+// SessionTracker collects the plays of the current session. A play after the idle timeout
+// starts a new session, and the session is ended by a timer once it has been idle that long.
+// It is safe for concurrent use.
+type SessionTracker struct {
+	idle  time.Duration
+	onEnd func(Session)
+
+	mu      sync.Mutex
+	current *Session
+	timer   *time.Timer
+}
+
+// NewSessionTracker returns a tracker ending sessions after idle, calling onEnd with every ended session.
+// onEnd may be nil, and is called from its own goroutine.
+func NewSessionTracker(idle time.Duration, onEnd func(Session)) *SessionTracker {
+	return &SessionTracker{idle: idle, onEnd: onEnd}
+}
+
+// Add adds a play to the current session, starting one if there is none or it has been idle too long.
+// Reports without a play record, such as select screens, are not plays: they are left out and the bool is false.
+// baseline is only called when a session starts, and should return the bests from before the play.
+func (t *SessionTracker) Add(report AnalysisReport, improvement Improvement, playedAt time.Time, baseline func() []Archive) (Session, bool) {
+	if !report.HasRecord() {
+		return Session{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current != nil && playedAt.Sub(t.current.Last) >= t.idle {
+		t.endLocked()
+	}
+	if t.current == nil {
+		t.current = &Session{Start: playedAt, Baseline: baseline()}
+	}
+	t.current.Last = playedAt
+	t.current.Plays = append(t.current.Plays, SessionPlay{NewPlayRecord(report, playedAt, ""), improvement})
+
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	session := t.current
+	t.timer = time.AfterFunc(t.idle, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		// A play added since may have replaced the session
+		if t.current == session {
+			t.endLocked()
+		}
+	})
+	return *session, true
+}
+
+// Current returns the current session. The bool is false when there is none.
+func (t *SessionTracker) Current() (Session, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == nil {
+		return Session{}, false
+	}
+	return *t.current, true
+}
+
+// End ends the current session. The bool is false when there is none.
+func (t *SessionTracker) End() (Session, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == nil {
+		return Session{}, false
+	}
+	return t.endLocked(), true
+}
+
+func (t *SessionTracker) endLocked() Session {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.current.Ended = true
+	session := *t.current
+	t.current = nil
+	if t.onEnd != nil {
+		go t.onEnd(session)
+	}
+	return session
+}
+
+// SessionPattern is a pattern played in a session.
+type SessionPattern struct {
+	PatternKey
+	Title     string  `json:"title"`
+	Level     int     `json:"level"`
+	Plays     int     `json:"plays"`
+	BestJudge float64 `json:"bestJudge"`
+	BestPatch float64 `json:"bestPatch"`
+	NewBest   bool    `json:"newBest"`
+}
+
+// SessionSummary describes a session.
+type SessionSummary struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Plays int       `json:"plays"`
+	// Patterns are in the order they were first played.
+	Patterns []SessionPattern `json:"patterns"`
+	NewBests int              `json:"newBests"`
+	// RatingBefore and RatingAfter are the rating of the personal bests without and with the session.
+	RatingBefore float64 `json:"ratingBefore"`
+	RatingAfter  float64 `json:"ratingAfter"`
+	// Accuracy is the judge of every play with a record, in order.
+	Accuracy     []ChartPoint `json:"accuracy"`
+	AverageJudge float64      `json:"averageJudge"`
+	// JudgeTrend is the average judge of the second half of the plays minus that of the first half.
+	JudgeTrend float64 `json:"judgeTrend"`
+}
+
+// Summary summarises the plays of the session with a record. Titles and levels are taken from the cache.
+func (s Session) Summary(cache *Cache, formula RatingConfig) SessionSummary {
+	summary := SessionSummary{Start: s.Start, End: s.Last}
+	index := make(map[PatternKey]int)
+	records := append([]Archive(nil), s.Baseline...)
+	var judges []float64
+	for _, p := range s.Plays {
+		if !p.Record {
+			continue
+		}
+		summary.Plays++
+		if p.Improvement.NewRecord() {
+			summary.NewBests++
+		}
+		key := p.Key()
+		i, ok := index[key]
+		if !ok {
+			i = len(summary.Patterns)
+			index[key] = i
+			pattern := SessionPattern{PatternKey: key, Title: p.Title, Level: p.Level}
+			if found, ok := cache.FindPattern(key); ok {
+				pattern.Level = found.Level
+			}
+			summary.Patterns = append(summary.Patterns, pattern)
+		}
+		pattern := &summary.Patterns[i]
+		pattern.Plays++
+		pattern.BestJudge = max(pattern.BestJudge, p.Judge)
+		pattern.BestPatch = max(pattern.BestPatch, p.Patch)
+		pattern.NewBest = pattern.NewBest || p.Improvement.NewRecord()
+		records = append(records, p.ToArchive(""))
+		judges = append(judges, p.Judge)
+		summary.Accuracy = append(summary.Accuracy, ChartPoint{p.PlayedAt, p.Judge})
+	}
+
+	summary.RatingBefore = ComputeRating(s.Baseline, cache, formula, nil).Total
+	summary.RatingAfter = ComputeRating(records, cache, formula, nil).Total
+	summary.AverageJudge = average(judges)
+	if half := len(judges) / 2; half > 0 {
+		summary.JudgeTrend = average(judges[len(judges)-half:]) - average(judges[:half])
+	}
+	return summary
+}
+
+func average(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Lines describes the summary in lines of text, patterns with the most plays first.
+func (s SessionSummary) Lines() []string {
+	lines := []string{
+		fmt.Sprintf("%s - %s (%s)", s.Start.Format("2006-01-02 15:04"), s.End.Format("15:04"), s.End.Sub(s.Start).Round(time.Minute)),
+		fmt.Sprintf("Plays: %d, patterns: %d, new bests: %d", s.Plays, len(s.Patterns), s.NewBests),
+		fmt.Sprintf("Rating: %.2f → %.2f (%+.2f)", s.RatingBefore, s.RatingAfter, s.RatingAfter-s.RatingBefore),
+		fmt.Sprintf("Average judge: %.4f%% (trend %+.4f%%)", s.AverageJudge, s.JudgeTrend),
+	}
+	patterns := append([]SessionPattern(nil), s.Patterns...)
+	sort.SliceStable(patterns, func(i, j int) bool { return patterns[i].Plays > patterns[j].Plays })
+	for _, p := range patterns {
+		line := fmt.Sprintf("%s %dL %s Lv.%d  x%d  %.4f%%  %.2f", p.Title, p.Line, p.Difficulty, p.Level, p.Plays, p.BestJudge, p.BestPatch)
+		if p.NewBest {
+			line += "  NEW"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Session summary export formats.
+const (
+	SessionFormatText = "txt"
+	SessionFormatJSON = "json"
+)
+
+// WriteSessionSummary writes the summary as text lines or as indented JSON.
+func WriteSessionSummary(w io.Writer, format string, summary SessionSummary) error {
+	switch format {
+	case SessionFormatText:
+		for _, line := range summary.Lines() {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	case SessionFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summary)
+	}
+	return fmt.Errorf("unknown session format %q", format)
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sessionReport(songID int, judge, patch float64) AnalysisReport {
+	return AnalysisReport{
+		ScreenType:    ResultScreen,
+		SongObject:    Song{ID: songID, Title: "Song"},
+		PatternObject: Pattern{SongID: songID, Line: 4, Difficulty: "HARD", Level: 12},
+		Judge:         judge, Score: judge * 10000, Patch: patch,
+		Confidence: map[string]float64{FieldJudge: 1, FieldScore: 1, FieldPatch: 1},
+	}
+}
+
+// archivesOf returns a baseline of the session tracker.
+func archivesOf(archives ...Archive) func() []Archive {
+	return func() []Archive { return archives }
+}
+
+func TestSessionTracker(t *testing.T) {
+	ended := make(chan Session, 2)
+	tracker := NewSessionTracker(time.Hour, func(s Session) { ended <- s })
+	start := time.Date(2025, 11, 20, 21, 0, 0, 0, time.Local)
+
+	tracker.Add(sessionReport(1, 95, 400), Improvement{}, start, archivesOf(testArchive(1, 96, 960000, 450, "2025-11-19")))
+	// The baseline is only needed when a session starts
+	noBaseline := func() []Archive {
+		t.Error("unexpected baseline of a running session")
+		return nil
+	}
+	session, _ := tracker.Add(sessionReport(1, 97, 460), Improvement{NewPatch: true}, start.Add(30*time.Minute), noBaseline)
+	// Select screens are not plays, even when every number is read
+	selectScreen := sessionReport(1, 95, 400)
+	selectScreen.ScreenType, selectScreen.PatternObject.Difficulty = SelectScreen, ""
+	if added, ok := tracker.Add(selectScreen, Improvement{}, start.Add(40*time.Minute), noBaseline); ok || len(added.Plays) != 0 {
+		t.Errorf("expected a select screen not to be added, got %+v", added)
+	}
+	if current, _ := tracker.Current(); len(current.Plays) != 2 {
+		t.Errorf("expected the select screen not to count as a play, got %d plays", len(current.Plays))
+	}
+	if len(session.Plays) != 2 || len(session.Baseline) != 1 || !session.Last.Equal(start.Add(30*time.Minute)) {
+		t.Fatalf("expected one session of 2 plays with the first baseline, got %+v", session)
+	}
+
+	// A play after the idle timeout starts a new session
+	session, _ = tracker.Add(sessionReport(2, 90, 300), Improvement{}, start.Add(2*time.Hour), archivesOf())
+	if len(session.Plays) != 1 || !session.Start.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("expected a new session, got %+v", session)
+	}
+	select {
+	case s := <-ended:
+		if !s.Ended || len(s.Plays) != 2 {
+			t.Errorf("unexpected ended session %+v", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the idle session to end")
+	}
+
+	if _, ok := tracker.End(); !ok {
+		t.Errorf("expected to end the current session")
+	}
+	if _, ok := tracker.Current(); ok {
+		t.Errorf("expected no session after ending it")
+	}
+	<-ended
+}
+
+func TestSessionTrackerTimeout(t *testing.T) {
+	ended := make(chan Session, 1)
+	tracker := NewSessionTracker(10*time.Millisecond, func(s Session) { ended <- s })
+	tracker.Add(sessionReport(1, 95, 400), Improvement{}, time.Now(), archivesOf())
+	select {
+	case <-ended:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the session to end after the idle timeout")
+	}
+	if _, ok := tracker.Current(); ok {
+		t.Errorf("expected no current session")
+	}
+}
+
+func TestSessionSummary(t *testing.T) {
+	start := time.Date(2025, 11, 20, 21, 0, 0, 0, time.Local)
+	tracker := NewSessionTracker(time.Hour, nil)
+	tracker.Add(sessionReport(1, 95, 400), Improvement{}, start, archivesOf(testArchive(1, 96, 960000, 450, "2025-11-19")))
+	tracker.Add(sessionReport(2, 90, 300), CompareWithBest(sessionReport(2, 90, 300), PatternBest{}, false), start.Add(time.Minute), nil)
+	tracker.Add(sessionReport(1, 97, 460), Improvement{NewPatch: true}, start.Add(2*time.Minute), nil)
+	tracker.Add(sessionReport(1, 98, 470), Improvement{NewPatch: true}, start.Add(3*time.Minute), nil)
+	session, _ := tracker.End()
+	// Plays without a record are left out of the summary
+	session.Plays = append(session.Plays, SessionPlay{PlayRecord: PlayRecord{SongID: 3, Line: 4, Difficulty: "HARD"}})
+
+	cache := &Cache{Patterns: []Pattern{{SongID: 1, Line: 4, Difficulty: "HARD", Level: 13}}}
+	summary := session.Summary(cache, RatingConfig{RatingSum, 50, 1})
+	if summary.Plays != 4 || summary.NewBests != 3 || len(summary.Patterns) != 2 || len(summary.Accuracy) != 4 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	first := summary.Patterns[0]
+	if first.Plays != 3 || first.Level != 13 || first.BestJudge != 98 || first.BestPatch != 470 || !first.NewBest {
+		t.Errorf("unexpected pattern %+v", first)
+	}
+	if summary.RatingBefore != 450 || summary.RatingAfter != 770 {
+		t.Errorf("expected the rating to go from 450 to 770, got %v to %v", summary.RatingBefore, summary.RatingAfter)
+	}
+	if summary.AverageJudge != 95 || math.Abs(summary.JudgeTrend-5) > 1e-9 {
+		t.Errorf("unexpected accuracy %v, trend %v", summary.AverageJudge, summary.JudgeTrend)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSessionSummary(&buf, SessionFormatText, summary); err != nil {
+		t.Fatalf("WriteSessionSummary failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Rating: 450.00 → 770.00 (+320.00)") || !strings.Contains(buf.String(), "x3") {
+		t.Errorf("unexpected text summary:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := WriteSessionSummary(&buf, SessionFormatJSON, summary); err != nil {
+		t.Fatalf("WriteSessionSummary failed: %v", err)
+	}
+	var decoded SessionSummary
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded.NewBests != 3 {
+		t.Errorf("expected the JSON summary to decode, got %+v, %v", decoded, err)
+	}
+}
@@ -0,0 +1,295 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// archiveDateLayout is the layout of Archive.DecodedAt.
+const archiveDateLayout = "2006-01-02"
+
+// ArchiveServer is the remote archive of an account.
+type ArchiveServer interface {
+	FetchArchive() ([]Archive, error)
+	UpdateArchive(archive Archive) (bool, error)
+}
+
+// apiArchiveServer is the archive of the PLATiNA-ARCHiVE API.
+type apiArchiveServer struct {
+	b64APIKey string
+}
+
+// NewArchiveServer returns the archive of the account with the base64 encoded API key.
+func NewArchiveServer(b64APIKey string) ArchiveServer {
+	return apiArchiveServer{b64APIKey}
+}
+
+func (s apiArchiveServer) FetchArchive() ([]Archive, error) {
+	return FetchArchive(s.b64APIKey)
+}
+
+func (s apiArchiveServer) UpdateArchive(archive Archive) (bool, error) {
+	return UpdateArchive(s.b64APIKey, archive)
+}
+
+// SyncConflict is a pattern on which no single record is at least as good as the other side in every value:
+// the server holds a better value than the local store, or the local bests come from several plays
+// none of which beats the server record on its own.
+type SyncConflict struct {
+	Key    PatternKey
+	Local  PatternBest
+	Server Archive
+	// Pushed is true when a record was uploaded anyway: a local play beating the server in the other values,
+	// or the merged best when merging was asked for.
+	Pushed bool
+}
+
+func (c SyncConflict) String() string {
+	return fmt.Sprintf("%s: server %.4f%% / %.0f / %.2f, local %.4f%% / %.0f / %.2f",
+		c.Key, c.Server.Judge, c.Server.Score, c.Server.Patch, c.Local.Judge, c.Local.Score, c.Local.Patch)
+}
+
+// SyncFailure is an upload the server refused.
+type SyncFailure struct {
+	Archive Archive
+	Err     error
+}
+
+// SyncResult describes what a sync changed.
+type SyncResult struct {
+	// Pulled lists the server records stored locally.
+	Pulled []Archive
+	// Pushed lists the records uploaded to the server.
+	Pushed    []Archive
+	Conflicts []SyncConflict
+	Failed    []SyncFailure
+}
+
+// ToArchive converts the best into an archive record of the given decoder, dated by the last play.
+func (b PatternBest) ToArchive(decoder string) Archive {
+	return Archive{
+		Decoder:    decoder,
+		SongID:     b.SongID,
+		Line:       b.Line,
+		Difficulty: b.Difficulty,
+		Level:      b.Level,
+		Judge:      b.Judge,
+		Score:      b.Score,
+		Patch:      b.Patch,
+		DecodedAt:  b.LastPlayed.Format(archiveDateLayout),
+		FullCombo:  b.FullCombo,
+		MaxPatch:   b.MaxPatch,
+	}
+}
+
+// ToArchive converts the play into an archive record of the given decoder, dated by the play.
+func (r PlayRecord) ToArchive(decoder string) Archive {
+	return Archive{
+		Decoder:    decoder,
+		SongID:     r.SongID,
+		Line:       r.Line,
+		Difficulty: r.Difficulty,
+		Level:      r.Level,
+		Judge:      r.Judge,
+		Score:      r.Score,
+		Patch:      r.Patch,
+		DecodedAt:  r.PlayedAt.Format(archiveDateLayout),
+		FullCombo:  r.FullCombo,
+		MaxPatch:   r.MaxPatch,
+	}
+}
+
+// ArchiveKey returns the pattern of an archive record.
+func ArchiveKey(a Archive) PatternKey {
+	return PatternKey{a.SongID, a.Line, a.Difficulty}
+}
+
+// NewServerPlayRecord converts a server archive record into a play record.
+// It is dated by DecodedAt, or left undated when the date cannot be parsed.
+func NewServerPlayRecord(a Archive) PlayRecord {
+	playedAt, _ := time.ParseInLocation(archiveDateLayout, a.DecodedAt, time.Local)
+	return PlayRecord{
+		PlayedAt:   playedAt,
+		Source:     PlaySourceServer,
+		SongID:     a.SongID,
+		Line:       a.Line,
+		Difficulty: a.Difficulty,
+		Level:      a.Level,
+		Judge:      a.Judge,
+		Score:      a.Score,
+		Patch:      a.Patch,
+		FullCombo:  a.FullCombo,
+		MaxPatch:   a.MaxPatch,
+		Record:     true,
+		// Server records were uploaded before
+		Confirmed: true,
+	}
+}
+
+// betterValues reports whether a has any value better than b.
+func betterValues(a, b Archive) bool {
+	return a.Judge > b.Judge || a.Score > b.Score || a.Patch > b.Patch ||
+		(a.FullCombo && !b.FullCombo) || (a.MaxPatch && !b.MaxPatch)
+}
+
+// Sync brings the local store and the server archive to the same bests.
+//
+// Records are paired by pattern. A server record that is better than the local best
+// in any value is stored locally, unless the same record, by pattern and DecodedAt,
+// was pulled before; when the local store also had a best, this is reported as a conflict.
+//
+// Only confirmed local plays are uploaded, each with its own values: the best play that is
+// better than the server record in a value without being worse in any. When the local bests
+// are better but no single play is, the pattern is a conflict, and nothing is uploaded unless
+// merge is set; the best of every value of both sides is then uploaded as one record.
+// Refused uploads are reported, and do not stop the sync.
+func Sync(history *History, server ArchiveServer, decoder string, merge bool) (SyncResult, error) {
+	var result SyncResult
+	archives, err := server.FetchArchive()
+	if err != nil {
+		return result, fmt.Errorf("error fetching archive: %v", err)
+	}
+	bests, err := history.Bests()
+	if err != nil {
+		return result, err
+	}
+	confirmed, err := history.Plays(HistoryQuery{RecordsOnly: true, ConfirmedOnly: true})
+	if err != nil {
+		return result, err
+	}
+	alreadyPulled := make(map[string]bool)
+	plays := make(map[PatternKey][]PlayRecord)
+	for _, p := range confirmed {
+		if p.Source == PlaySourceServer {
+			alreadyPulled[p.Key().String()+"@"+p.PlayedAt.Format(archiveDateLayout)] = true
+			continue
+		}
+		plays[p.Key()] = append(plays[p.Key()], p)
+	}
+
+	local := make(map[PatternKey]PatternBest)
+	for _, b := range bests {
+		local[b.PatternKey] = b
+	}
+	onServer := make(map[PatternKey]bool)
+	for _, a := range archives {
+		key := ArchiveKey(a)
+		onServer[key] = true
+		best, ok := local[key]
+		localArchive := best.ToArchive(decoder)
+
+		if !ok || betterValues(a, localArchive) {
+			if !alreadyPulled[key.String()+"@"+a.DecodedAt] {
+				if _, err := history.Add(NewServerPlayRecord(a)); err != nil {
+					return result, err
+				}
+				result.Pulled = append(result.Pulled, a)
+			}
+		}
+		if !ok {
+			continue
+		}
+		if !betterValues(localArchive, a) {
+			if betterValues(a, localArchive) {
+				result.Conflicts = append(result.Conflicts, SyncConflict{Key: key, Local: best, Server: a})
+			}
+			continue
+		}
+
+		conflict := SyncConflict{Key: key, Local: best, Server: a}
+		play, single := bestPlay(plays[key], a, decoder)
+		switch {
+		case single:
+			conflict.Pushed = result.push(server, play)
+		case merge:
+			conflict.Pushed = result.push(server, mergeArchives(localArchive, a))
+		}
+		if !single || betterValues(a, localArchive) {
+			result.Conflicts = append(result.Conflicts, conflict)
+		}
+	}
+
+	for _, b := range bests {
+		if onServer[b.PatternKey] {
+			continue
+		}
+		if play, ok := bestPlay(plays[b.PatternKey], Archive{}, decoder); ok {
+			result.push(server, play)
+		}
+	}
+	return result, nil
+}
+
+// bestPlay returns the best play, by rankAbove, of those that beat the server record.
+// The bool is false when no play does.
+func bestPlay(plays []PlayRecord, server Archive, decoder string) (Archive, bool) {
+	var best Archive
+	found := false
+	for _, p := range plays {
+		a := p.ToArchive(decoder)
+		if !beats(a, server) {
+			continue
+		}
+		if !found || rankAbove(a, best) {
+			best, found = a, true
+		}
+	}
+	return best, found
+}
+
+// beats reports whether a is better than b in a value without being worse in any.
+func beats(a, b Archive) bool {
+	return betterValues(a, b) && !betterValues(b, a)
+}
+
+// rankAbove reports whether the play a ranks above b: by patch, then judge, then score.
+func rankAbove(a, b Archive) bool {
+	if a.Patch != b.Patch {
+		return a.Patch > b.Patch
+	}
+	if a.Judge != b.Judge {
+		return a.Judge > b.Judge
+	}
+	return a.Score > b.Score
+}
+
+// push uploads a record and notes the outcome. It returns whether the upload succeeded.
+func (r *SyncResult) push(server ArchiveServer, archive Archive) bool {
+	if _, err := server.UpdateArchive(archive); err != nil {
+		r.Failed = append(r.Failed, SyncFailure{archive, err})
+		return false
+	}
+	r.Pushed = append(r.Pushed, archive)
+	return true
+}
+
+// mergeArchives keeps the best of every value of two records of the same pattern.
+// The date of the newer record is kept.
+func mergeArchives(a, b Archive) Archive {
+	merged := a
+	merged.Judge = max(a.Judge, b.Judge)
+	merged.Score = max(a.Score, b.Score)
+	merged.Patch = max(a.Patch, b.Patch)
+	merged.FullCombo = a.FullCombo || b.FullCombo
+	merged.MaxPatch = a.MaxPatch || b.MaxPatch
+	// Dates share one layout, so they compare as strings
+	merged.DecodedAt = max(a.DecodedAt, b.DecodedAt)
+	return merged
+}
+
+// BestArchives merges the records of every pattern into one, keeping the best of every value.
+// Patterns keep the order of their first record.
+func BestArchives(archives []Archive) []Archive {
+	index := make(map[PatternKey]int)
+	var bests []Archive
+	for _, a := range archives {
+		key := ArchiveKey(a)
+		if i, ok := index[key]; ok {
+			bests[i] = mergeArchives(bests[i], a)
+			continue
+		}
+		index[key] = len(bests)
+		bests = append(bests, a)
+	}
+	return bests
+}
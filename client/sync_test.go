@@ -0,0 +1,150 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeArchiveServer keeps the archive in memory, keeping one record per pattern as the server does.
+type fakeArchiveServer struct {
+	archives map[PatternKey]Archive
+	refuse   map[PatternKey]bool
+}
+
+func newFakeArchiveServer(archives ...Archive) *fakeArchiveServer {
+	s := &fakeArchiveServer{archives: make(map[PatternKey]Archive), refuse: make(map[PatternKey]bool)}
+	for _, a := range archives {
+		s.archives[ArchiveKey(a)] = a
+	}
+	return s
+}
+
+func (s *fakeArchiveServer) FetchArchive() ([]Archive, error) {
+	var archives []Archive
+	for _, a := range s.archives {
+		archives = append(archives, a)
+	}
+	return archives, nil
+}
+
+func (s *fakeArchiveServer) UpdateArchive(archive Archive) (bool, error) {
+	if s.refuse[ArchiveKey(archive)] {
+		return false, fmt.Errorf("refused")
+	}
+	s.archives[ArchiveKey(archive)] = archive
+	return true, nil
+}
+
+func testArchive(songID int, judge, score, patch float64, decodedAt string) Archive {
+	return Archive{Decoder: "tester", SongID: songID, Line: 4, Difficulty: "HARD", Level: 12,
+		Judge: judge, Score: score, Patch: patch, DecodedAt: decodedAt}
+}
+
+func TestSync(t *testing.T) {
+	history := openTestHistory(t)
+	day := time.Date(2025, 11, 20, 21, 0, 0, 0, time.Local)
+	unconfirmed := testPlay(7, 90, 800000, 400, day) // refused in the confirm dialog
+	unconfirmed.Confirmed = false
+	for _, p := range []PlayRecord{
+		testPlay(1, 99, 990000, 600, day), // better than the server
+		testPlay(2, 95, 900000, 500, day), // the server has a better judge
+		testPlay(3, 90, 800000, 400, day), // never uploaded
+		testPlay(5, 90, 800000, 400, day), // refused
+		testPlay(6, 96, 950000, 400, day), // better than the server only together
+		testPlay(6, 94, 990000, 410, day.Add(time.Hour)),
+		unconfirmed,
+	} {
+		if _, err := history.Add(p); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	server := newFakeArchiveServer(
+		testArchive(1, 98, 980000, 590, "2025-11-01"),
+		testArchive(2, 97, 880000, 480, "2025-11-21"),
+		testArchive(4, 80, 700000, 300, "2025-11-02"), // played on another PC
+		testArchive(5, 80, 700000, 300, "2025-11-02"),
+		testArchive(6, 95, 960000, 405, "2025-11-02"),
+	)
+	server.refuse[PatternKey{5, 4, "HARD"}] = true
+
+	result, err := Sync(history, server, "tester", false)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(result.Pulled) != 2 || len(result.Pushed) != 2 || len(result.Conflicts) != 2 || len(result.Failed) != 1 {
+		t.Fatalf("unexpected result: %d pulled, %d pushed, %d conflicts, %d failed",
+			len(result.Pulled), len(result.Pushed), len(result.Conflicts), len(result.Failed))
+	}
+	for _, c := range result.Conflicts {
+		if (c.Key.SongID != 2 && c.Key.SongID != 6) || c.Pushed {
+			t.Errorf("unexpected conflict %+v", c)
+		}
+	}
+
+	// Uploads hold the values of a single play
+	if pushed := server.archives[PatternKey{1, 4, "HARD"}]; pushed != testPlay(1, 99, 990000, 600, day).ToArchive("tester") {
+		t.Errorf("unexpected pushed record %+v", pushed)
+	}
+	if server.archives[PatternKey{2, 4, "HARD"}].Score != 880000 || server.archives[PatternKey{6, 4, "HARD"}].Judge != 95 {
+		t.Error("expected conflicting patterns to be left alone without merging")
+	}
+	best, ok, err := history.Best(PatternKey{2, 4, "HARD"})
+	if err != nil || !ok || best.Judge != 97 || best.Score != 900000 {
+		t.Errorf("unexpected local best %+v, %v", best, err)
+	}
+	if _, ok, _ := history.Best(PatternKey{4, 4, "HARD"}); !ok {
+		t.Error("expected the record of the other PC to be pulled")
+	}
+	if server.archives[PatternKey{3, 4, "HARD"}].Score != 800000 {
+		t.Error("expected the local-only play to be pushed")
+	}
+	if _, ok := server.archives[PatternKey{7, 4, "HARD"}]; ok {
+		t.Error("expected the unconfirmed play not to be pushed")
+	}
+
+	// Merging uploads the best of both sides, and the refused upload is retried
+	server.refuse = nil
+	result, err = Sync(history, server, "tester", true)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(result.Pulled) != 0 || len(result.Pushed) != 3 || len(result.Conflicts) != 2 || len(result.Failed) != 0 {
+		t.Errorf("unexpected second result %+v", result)
+	}
+	for _, c := range result.Conflicts {
+		if !c.Pushed {
+			t.Errorf("expected the merged best of %s to be pushed", c.Key)
+		}
+	}
+	merged := server.archives[PatternKey{2, 4, "HARD"}]
+	if merged.Judge != 97 || merged.Score != 900000 || merged.Patch != 500 || merged.DecodedAt != "2025-11-21" {
+		t.Errorf("unexpected merged record %+v", merged)
+	}
+	merged = server.archives[PatternKey{6, 4, "HARD"}]
+	if merged.Judge != 96 || merged.Score != 990000 || merged.Patch != 410 {
+		t.Errorf("unexpected merged record %+v", merged)
+	}
+}
+
+func TestMergeArchives(t *testing.T) {
+	a := testArchive(1, 99, 900000, 500, "2025-11-01")
+	a.FullCombo = true
+	b := testArchive(1, 98, 950000, 510, "2025-11-03")
+	b.MaxPatch = true
+	merged := mergeArchives(a, b)
+	if merged.Judge != 99 || merged.Score != 950000 || merged.Patch != 510 || !merged.FullCombo || !merged.MaxPatch || merged.DecodedAt != "2025-11-03" {
+		t.Errorf("unexpected merge %+v", merged)
+	}
+}
+
+func TestBestArchives(t *testing.T) {
+	bests := BestArchives([]Archive{
+		testArchive(2, 90, 800000, 300, "2025-11-01"),
+		testArchive(1, 99, 900000, 500, "2025-11-01"),
+		testArchive(2, 95, 700000, 310, "2025-11-02"),
+	})
+	if len(bests) != 2 || bests[0].SongID != 2 || bests[0].Judge != 95 || bests[0].Score != 800000 || bests[0].DecodedAt != "2025-11-02" {
+		t.Errorf("unexpected bests %+v", bests)
+	}
+}
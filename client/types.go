@@ -16,6 +16,7 @@ func (e *APIError) Error() string {
 }
 
 type AnalysisReport struct {
+	ScreenType    ScreenType
 	SongObject    Song
 	PatternObject Pattern
 	JacketImage   image.Image
@@ -25,6 +26,24 @@ type AnalysisReport struct {
 	Rank          string
 	FullCombo     bool
 	MaxPatch      bool
+	Notes         NoteCounts
+	// Warnings lists the extracted values that contradict each other.
+	Warnings []ValidationWarning
+	// DisabledChecks lists the codes of the consistency checks that could not run
+	// because the config does not set them up.
+	DisabledChecks []string
+	// Confidence holds a score in [0, 1] for every extracted field, keyed by the Field constants.
+	Confidence map[string]float64
+}
+
+// NoteCounts holds the judgement counts of the result screen.
+type NoteCounts struct {
+	Total       int `json:"totalNotes"`
+	PerfectHigh int `json:"perfectHigh"`
+	Perfect     int `json:"perfect"`
+	Great       int `json:"great"`
+	Good        int `json:"good"`
+	Miss        int `json:"miss"`
 }
 
 // Archive represents a user's play record for a song.
@@ -76,12 +95,30 @@ func (v Version) Compare(other Version) int {
 }
 
 type Config struct {
-	Version          string           `yaml:"version" json:"version"`
-	Reference        ScreenSize       `yaml:"reference" json:"reference"`
-	SpeedWidgetPHash string           `yaml:"speedWidgetPHash" json:"speedWidgetPHash"`
-	Configs          []ROIConfig      `yaml:"configs" json:"configs"`
-	DifficultyColors DifficultyColors `yaml:"difficultyColors" json:"difficultyColors"`
-	ColorTolerance   int              `yaml:"colorTolerance" json:"colorTolerance"`
+	Version                string           `yaml:"version" json:"version"`
+	Reference              ScreenSize       `yaml:"reference" json:"reference"`
+	SpeedWidgetPHash       string           `yaml:"speedWidgetPHash" json:"speedWidgetPHash"`
+	ResultAnchorPHash      string           `yaml:"resultAnchorPHash" json:"resultAnchorPHash"`
+	PauseAnchorPHash       string           `yaml:"pauseAnchorPHash" json:"pauseAnchorPHash"`
+	SelectPopupAnchorPHash string           `yaml:"selectPopupAnchorPHash" json:"selectPopupAnchorPHash"`
+	Configs                []ROIConfig      `yaml:"configs" json:"configs"`
+	DifficultyColors       DifficultyColors `yaml:"difficultyColors" json:"difficultyColors"`
+	ColorTolerance         int              `yaml:"colorTolerance" json:"colorTolerance"`
+	DigitTemplates         string           `yaml:"digitTemplates" json:"digitTemplates"`
+	FullComboPHash         string           `yaml:"fullComboPHash" json:"fullComboPHash"`
+	JudgeWeights           *JudgeWeights    `yaml:"judgeWeights" json:"judgeWeights"`
+	MaxPatch               map[int]float64  `yaml:"maxPatch" json:"maxPatch"`
+	Rating                 *RatingConfig    `yaml:"rating" json:"rating"`
+	SessionIdleMinutes     int              `yaml:"sessionIdleMinutes" json:"sessionIdleMinutes"`
+}
+
+// JudgeWeights is the fraction of a note each judgement is worth in the judge rate.
+type JudgeWeights struct {
+	PerfectHigh float64 `yaml:"perfectHigh" json:"perfectHigh"`
+	Perfect     float64 `yaml:"perfect" json:"perfect"`
+	Great       float64 `yaml:"great" json:"great"`
+	Good        float64 `yaml:"good" json:"good"`
+	Miss        float64 `yaml:"miss" json:"miss"`
 }
 
 type ScreenSize struct {
@@ -93,18 +130,30 @@ func (s ScreenSize) String() string {
 	return fmt.Sprintf("%dx%d", s.Width, s.Height)
 }
 
+// ParseScreenSize reads a size as written by String, e.g. "1920x1080".
+func ParseScreenSize(s string) (ScreenSize, error) {
+	var size ScreenSize
+	if _, err := fmt.Sscanf(s, "%dx%d", &size.Width, &size.Height); err != nil || size.Width <= 0 || size.Height <= 0 {
+		return ScreenSize{}, fmt.Errorf("invalid screen size %q", s)
+	}
+	return size, nil
+}
+
 type ROIConfig struct {
 	ScreenSize string          `yaml:"screenSize" json:"screenSize"`
 	Select     SelectROIConfig `yaml:"select" json:"select"`
 	Result     ResultROIConfig `yaml:"result" json:"result"`
+	Pause      PauseROIConfig  `yaml:"pause" json:"pause"`
 }
 
 type SelectROIConfig struct {
 	SpeedWidget []int `yaml:"speedWidget" json:"speedWidget"`
+	PopupAnchor []int `yaml:"popupAnchor" json:"popupAnchor"`
 	Jacket      []int `yaml:"jacket" json:"jacket"`
 	MajorJudge  []int `yaml:"majorJudge" json:"majorJudge"`
 	MinorJudge  []int `yaml:"minorJudge" json:"minorJudge"`
 	Line        []int `yaml:"line" json:"line"`
+	Level       []int `yaml:"level" json:"level"`
 	MajorPatch  []int `yaml:"majorPatch" json:"majorPatch"`
 	MinorPatch  []int `yaml:"minorPatch" json:"minorPatch"`
 	Score       []int `yaml:"score" json:"score"`
@@ -114,6 +163,7 @@ type SelectROIConfig struct {
 }
 
 type ResultROIConfig struct {
+	Anchor      []int `yaml:"anchor" json:"anchor"`
 	Jacket      []int `yaml:"jacket" json:"jacket"`
 	Judge       []int `yaml:"judge" json:"judge"`
 	Line        []int `yaml:"line" json:"line"`
@@ -129,6 +179,11 @@ type ResultROIConfig struct {
 	Good        []int `yaml:"good" json:"good"`
 	Miss        []int `yaml:"miss" json:"miss"`
 	Difficulty  []int `yaml:"difficulty" json:"difficulty"`
+	FullCombo   []int `yaml:"fullCombo" json:"fullCombo"`
+}
+
+type PauseROIConfig struct {
+	Anchor []int `yaml:"anchor" json:"anchor"`
 }
 
 type DifficultyColors struct {
@@ -168,4 +223,11 @@ type Song struct {
 	DLC       string `json:"DLC"`
 	PHash     string `json:"pHash"`
 	PlusPHash string `json:"plusPHash"`
+	// Optional secondary hashes. They are left out of jacket matching when empty.
+	DHash         string `json:"dHash,omitempty"`
+	PlusDHash     string `json:"plusDHash,omitempty"`
+	AHash         string `json:"aHash,omitempty"`
+	PlusAHash     string `json:"plusAHash,omitempty"`
+	ColorHist     string `json:"colorHist,omitempty"`
+	PlusColorHist string `json:"plusColorHist,omitempty"`
 }
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"image/png"
 	"os"
 	"path/filepath"
 	"time"
@@ -115,11 +116,16 @@ func getCacheDirectory() string {
 	return filepath.Join(configDir, "PLATiNA-ARCHiVE")
 }
 
+// ConfigPath returns the path of the config cached by LoadConfig.
+func ConfigPath() string {
+	return filepath.Join(getCacheDirectory(), "config.yaml")
+}
+
 func LoadConfig() (Config, error) {
 	// Creates the cache directory if it doesn't exist
 	os.MkdirAll(getCacheDirectory(), os.ModeDir)
 	// Creates the empty cache file if it doesn't exist
-	cacheFilePath := filepath.Join(getCacheDirectory(), "config.yaml")
+	cacheFilePath := ConfigPath()
 
 	defaultConfig := Config{Version: "2025-04-11"}
 
@@ -188,3 +194,36 @@ func LoadImageFromClipboard() (image.Image, error) {
 	}
 	return img, nil
 }
+
+// CopyImageToClipboard puts an image on the clipboard as PNG.
+func CopyImageToClipboard(img image.Image) error {
+	if err := clipboard.Init(); err != nil {
+		return fmt.Errorf("error initializing clipboard: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return fmt.Errorf("error encoding image: %v", err)
+	}
+	clipboard.Write(clipboard.FmtImage, buf.Bytes())
+	return nil
+}
+
+// FindSong returns the song with the given ID.
+func (c *Cache) FindSong(id int) (Song, bool) {
+	for _, s := range c.Songs {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return Song{}, false
+}
+
+// FindPattern returns the pattern with the given key.
+func (c *Cache) FindPattern(key PatternKey) (Pattern, bool) {
+	for _, p := range c.Patterns {
+		if p.SongID == key.SongID && p.Line == key.Line && p.Difficulty == key.Difficulty {
+			return p, true
+		}
+	}
+	return Pattern{}, false
+}
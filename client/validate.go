@@ -0,0 +1,131 @@
+package client
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Codes of the consistency checks.
+const (
+	WarningNoteSum   = "noteSum"
+	WarningJudge     = "judge"
+	WarningFullCombo = "fullCombo"
+	WarningMaxPatch  = "maxPatch"
+)
+
+// judgeTolerance is the largest gap accepted between the shown judge and the one
+// computed from the note counts. The judge is shown rounded to 4 decimal places.
+const judgeTolerance = 0.0001
+
+// fullComboThreshold is the largest badge pHash distance at which the full combo flag is set.
+// The badge is drawn over the rank card, so it is more tolerant than the screen anchors.
+const fullComboThreshold = 8
+
+// ValidationWarning describes extracted values that contradict each other.
+type ValidationWarning struct {
+	Code    string   `json:"code"`
+	Fields  []string `json:"fields"`
+	Message string   `json:"message"`
+}
+
+// validateReport checks the extracted values against each other.
+// A check is skipped when one of its values was not extracted or it is not configured.
+// Checks whose values were extracted but that are not configured are returned as disabled.
+func validateReport(report AnalysisReport, config *Config) ([]ValidationWarning, []string) {
+	var warnings []ValidationWarning
+	var disabled []string
+	has := func(fields ...string) bool {
+		for _, field := range fields {
+			if _, ok := report.Confidence[field]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	n := report.Notes
+
+	if has(FieldNotes) {
+		if sum := n.PerfectHigh + n.Perfect + n.Great + n.Good + n.Miss; sum != n.Total {
+			warnings = append(warnings, ValidationWarning{
+				Code:    WarningNoteSum,
+				Fields:  []string{FieldNotes},
+				Message: fmt.Sprintf("note counts add up to %d, but total notes is %d", sum, n.Total),
+			})
+		}
+	}
+
+	if config.JudgeWeights == nil && has(FieldNotes, FieldJudge) {
+		disabled = append(disabled, WarningJudge)
+	}
+	if w := config.JudgeWeights; w != nil && has(FieldNotes, FieldJudge) && n.Total > 0 {
+		weighted := w.PerfectHigh*float64(n.PerfectHigh) + w.Perfect*float64(n.Perfect) +
+			w.Great*float64(n.Great) + w.Good*float64(n.Good) + w.Miss*float64(n.Miss)
+		expected := 100 * weighted / float64(n.Total)
+		if math.Abs(expected-report.Judge) > judgeTolerance {
+			warnings = append(warnings, ValidationWarning{
+				Code:    WarningJudge,
+				Fields:  []string{FieldJudge, FieldNotes},
+				Message: fmt.Sprintf("judge is %.4f%%, but the note counts give %.4f%%", report.Judge, expected),
+			})
+		}
+	}
+
+	if has(FieldFullCombo, FieldNotes) && report.FullCombo != (n.Miss == 0) {
+		message := fmt.Sprintf("full combo is shown with %d misses", n.Miss)
+		if !report.FullCombo {
+			message = "no miss was counted, but full combo is not shown"
+		}
+		warnings = append(warnings, ValidationWarning{
+			Code:    WarningFullCombo,
+			Fields:  []string{FieldFullCombo, FieldNotes},
+			Message: message,
+		})
+	}
+
+	maxPatch, ok := config.MaxPatch[report.PatternObject.Level]
+	if !ok && has(FieldPatch) && report.PatternObject.Level > 0 {
+		disabled = append(disabled, WarningMaxPatch)
+	}
+	if ok && has(FieldPatch) && report.Patch > maxPatch {
+		warnings = append(warnings, ValidationWarning{
+			Code:    WarningMaxPatch,
+			Fields:  []string{FieldPatch, FieldLevel},
+			Message: fmt.Sprintf("patch %.2f is above the maximum of %.2f for level %d", report.Patch, maxPatch, report.PatternObject.Level),
+		})
+	}
+	return warnings, disabled
+}
+
+// detectFullCombo compares the full combo badge ROI with its known hash.
+// The returned confidence grows as the distance moves away from the threshold on either side.
+func detectFullCombo(img image.Image, coords []int, layout Layout, config *Config) (bool, float64, error) {
+	if config.FullComboPHash == "" {
+		return false, 0, fmt.Errorf("no full combo hash configured")
+	}
+	distance, err := anchorDistance(img, screenAnchor{ResultScreen, coords, config.FullComboPHash}, layout)
+	if err != nil {
+		return false, 0, err
+	}
+	if distance <= fullComboThreshold {
+		return true, distanceConfidence(float64(distance), fullComboThreshold), nil
+	}
+	return false, marginConfidence(float64(distance-fullComboThreshold), fullComboThreshold), nil
+}
+
+// HasWarnings reports whether any consistency check failed.
+func (r AnalysisReport) HasWarnings() bool {
+	return len(r.Warnings) > 0
+}
+
+// HasWarning reports whether a failed consistency check involves the field.
+func (r AnalysisReport) HasWarning(field string) bool {
+	for _, w := range r.Warnings {
+		for _, f := range w.Fields {
+			if f == field {
+				return true
+			}
+		}
+	}
+	return false
+}
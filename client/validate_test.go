@@ -0,0 +1,81 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+// consistentReport is the Firework result screen with every number extracted.
+func consistentReport() AnalysisReport {
+	return AnalysisReport{
+		PatternObject: Pattern{Line: 4, Difficulty: "OVER", Level: 18},
+		Judge:         99.8829,
+		Patch:         755.11,
+		FullCombo:     true,
+		Notes:         NoteCounts{Total: 854, PerfectHigh: 784, Perfect: 68, Great: 1, Good: 1, Miss: 0},
+		Confidence:    map[string]float64{FieldJudge: 1, FieldPatch: 1, FieldNotes: 1, FieldFullCombo: 1, FieldLevel: 1},
+	}
+}
+
+func warningCodes(warnings []ValidationWarning) []string {
+	var codes []string
+	for _, w := range warnings {
+		codes = append(codes, w.Code)
+	}
+	return codes
+}
+
+func TestValidateReport(t *testing.T) {
+	// These weights give the shown judge for the Firework counts
+	weights := &JudgeWeights{PerfectHigh: 1, Perfect: 1, Great: 0.75, Good: 0.25}
+	limits := map[int]float64{18: 800}
+	for _, tt := range []struct {
+		name     string
+		modify   func(r *AnalysisReport)
+		config   Config
+		expected []string
+		disabled []string
+	}{
+		{"consistent", func(r *AnalysisReport) {}, Config{JudgeWeights: weights, MaxPatch: limits}, nil, nil},
+		{"misread count", func(r *AnalysisReport) { r.Notes.Perfect = 88 }, Config{JudgeWeights: weights, MaxPatch: limits}, []string{WarningNoteSum, WarningJudge}, nil},
+		{"misread judge", func(r *AnalysisReport) { r.Judge = 99.8820 }, Config{JudgeWeights: weights, MaxPatch: limits}, []string{WarningJudge}, nil},
+		{"judge without weights", func(r *AnalysisReport) { r.Judge = 99.8820 }, Config{MaxPatch: limits}, nil, []string{WarningJudge}},
+		{"full combo with a miss", func(r *AnalysisReport) { r.Notes.Miss, r.Notes.PerfectHigh = 1, 783 }, Config{JudgeWeights: weights, MaxPatch: limits}, []string{WarningJudge, WarningFullCombo}, nil},
+		{"missing full combo", func(r *AnalysisReport) { r.FullCombo = false }, Config{JudgeWeights: weights, MaxPatch: limits}, []string{WarningFullCombo}, nil},
+		{"full combo not extracted", func(r *AnalysisReport) { r.FullCombo = false; delete(r.Confidence, FieldFullCombo) }, Config{JudgeWeights: weights, MaxPatch: limits}, nil, nil},
+		{"patch above maximum", func(r *AnalysisReport) { r.Patch = 955.11 }, Config{JudgeWeights: weights, MaxPatch: limits}, []string{WarningMaxPatch}, nil},
+		{"level without maximum", func(r *AnalysisReport) { r.Patch = 955.11 }, Config{JudgeWeights: weights, MaxPatch: map[int]float64{17: 800}}, nil, []string{WarningMaxPatch}},
+		{"nothing configured", func(r *AnalysisReport) {}, Config{}, nil, []string{WarningJudge, WarningMaxPatch}},
+		{"judge not extracted", func(r *AnalysisReport) { delete(r.Confidence, FieldJudge) }, Config{MaxPatch: limits}, nil, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			report := consistentReport()
+			tt.modify(&report)
+			warnings, disabled := validateReport(report, &tt.config)
+			if codes := warningCodes(warnings); !reflect.DeepEqual(codes, tt.expected) {
+				t.Errorf("expected warnings %v, got %v", tt.expected, codes)
+			}
+			if !reflect.DeepEqual(disabled, tt.disabled) {
+				t.Errorf("expected disabled checks %v, got %v", tt.disabled, disabled)
+			}
+		})
+	}
+}
+
+func TestAnalyzeImageDetectsFullCombo(t *testing.T) {
+	config, cache := loadGoldenInputs(t, "../testing")
+	img, err := loadImage("../testing/result.png")
+	if err != nil {
+		t.Fatalf("failed to load result.png: %v", err)
+	}
+	report, err := AnalyzeImage(img, cache, config, AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("AnalyzeImage failed: %v", err)
+	}
+	if !report.FullCombo || report.IsLowConfidence(FieldFullCombo) {
+		t.Errorf("expected a confident full combo, got %v (confidence %.2f)", report.FullCombo, report.Confidence[FieldFullCombo])
+	}
+	if report.HasWarnings() {
+		t.Errorf("unexpected warnings: %+v", report.Warnings)
+	}
+}
@@ -0,0 +1,251 @@
+// Command archive works with the archive records and the local play history outside the client.
+//
+// Usage:
+//
+//	archive export [-source archive|history] [-format csv|jsonl|xlsx] [-archives archives.json] [-cache db.json] [-history history.db] [-o file]
+//	archive import [-map field=column,...] [-cache db.json] [-upload] file
+//	archive chart [-song id -line 4|6 -difficulty HARD] [-archives archives.json] [-cache db.json] [-history history.db] [-config config.yaml] -o file.png|file.svg
+//
+// export writes the server archive of the saved account, or the records of a file
+// holding the output of FetchArchive, joined with the song list. With -source history
+// it writes every play of the local history instead. Songs and patterns come from the
+// client cache, or from -cache.
+//
+// import reads play records from a CSV file with a header row or from JSON, and
+// prints how they differ from the server archive of the saved account: "+" for a
+// new pattern, "~" for an improved one, "?" for one better in a value but worse in
+// another, which is not uploaded, "=" for no change and "!" for a row that cannot be
+// imported. Rows without a date are marked undated. Columns are mapped to archive
+// fields by their names, or with -map, and titles are matched to the nearest song.
+// With -upload the new and improved records are sent to the server as they were read.
+//
+// chart draws the progress over time of the server archive and the local history:
+// the average judge, average patch and rating, or the judge and patch of the pattern
+// given by -song, -line and -difficulty. The rating uses the formula of the config
+// cached by the client, or of -config, without fetching it. The format follows the
+// -o extension.
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Minsuh1204/PLATiNA-ARCHiVE-Go-Client/client"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s export [flags]\n       %s import [flags] file\n       %s chart [flags]\n", os.Args[0], os.Args[0], os.Args[0])
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(os.Args[2:])
+	case "import":
+		err = runImport(os.Args[2:])
+	case "chart":
+		err = runChart(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	source := flags.String("source", "archive", "records to export: archive or history")
+	format := flags.String("format", "", "output format: "+strings.Join(client.ExportFormats, ", ")+" (default from the -o extension, or csv)")
+	archivesPath := flags.String("archives", "", "JSON array of archive records (default fetch the saved account)")
+	cachePath := flags.String("cache", "", "client cache or song list JSON (default the client cache)")
+	historyPath := flags.String("history", "", "history database (default the client history)")
+	output := flags.String("o", "", "output file (default stdout)")
+	flags.Parse(args)
+
+	if *format == "" {
+		*format = client.FormatCSV
+		for _, f := range client.ExportFormats {
+			if strings.HasSuffix(*output, "."+f) {
+				*format = f
+			}
+		}
+	}
+	cache, err := loadCache(*cachePath)
+	if err != nil {
+		return err
+	}
+
+	switch *source {
+	case "archive":
+		archives, err := client.LoadArchives(*archivesPath)
+		if err != nil {
+			return fmt.Errorf("failed to load archive records: %v", err)
+		}
+		return client.WriteOutput(*output, func(w io.Writer) error {
+			return client.ExportArchives(w, *format, archives, &cache)
+		})
+	case "history":
+		if *historyPath == "" {
+			*historyPath = client.HistoryPath()
+		}
+		history, err := client.OpenHistory(*historyPath)
+		if err != nil {
+			return err
+		}
+		defer history.Close()
+		return client.WriteOutput(*output, func(w io.Writer) error {
+			return client.ExportPlays(w, *format, history, client.HistoryQuery{}, &cache)
+		})
+	}
+	return fmt.Errorf("unknown source %q", *source)
+}
+
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	mappingFlag := flags.String("map", "", "field=column pairs overriding the columns guessed from the header")
+	cachePath := flags.String("cache", "", "client cache JSON (default the client cache)")
+	upload := flags.Bool("upload", false, "upload the new and improved records")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one file to import")
+	}
+
+	apiKey := client.LoadAPIKey()
+	if apiKey == "" {
+		return fmt.Errorf("no API key saved, log in with the client first")
+	}
+	server := client.NewArchiveServer(base64.StdEncoding.EncodeToString([]byte(apiKey)))
+	cache, err := loadCache(*cachePath)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	table, err := client.ReadImportTable(file)
+	if err != nil {
+		return err
+	}
+	mapping := client.GuessImportMapping(table.Header)
+	overrides, err := client.ParseImportMapping(*mappingFlag)
+	if err != nil {
+		return err
+	}
+	for field, column := range overrides {
+		mapping[field] = column
+	}
+
+	existing, err := server.FetchArchive()
+	if err != nil {
+		return fmt.Errorf("failed to fetch the archive: %v", err)
+	}
+	plan := client.PlanImport(table, mapping, &cache, existing, strings.Split(apiKey, "::")[0])
+	for _, line := range plan.Diff() {
+		fmt.Println(line)
+	}
+	counts := plan.Counts()
+	fmt.Fprintf(os.Stderr, "%d new, %d improved, %d conflicting, %d unchanged, %d invalid\n",
+		counts[client.ImportNew], counts[client.ImportImproved], counts[client.ImportConflict], counts[client.ImportUnchanged], counts[client.ImportInvalid])
+	if !*upload {
+		return nil
+	}
+
+	pushed, failed := plan.Upload(server)
+	for _, f := range failed {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", client.ArchiveKey(f.Archive), f.Err)
+	}
+	fmt.Fprintf(os.Stderr, "%d uploaded, %d failed\n", len(pushed), len(failed))
+	if len(failed) > 0 {
+		return fmt.Errorf("%d uploads failed", len(failed))
+	}
+	return nil
+}
+
+func runChart(args []string) error {
+	flags := flag.NewFlagSet("chart", flag.ExitOnError)
+	songID := flags.Int("song", 0, "song ID of the pattern to chart (default overall progress)")
+	line := flags.Int("line", 4, "line of the pattern")
+	difficulty := flags.String("difficulty", "HARD", "difficulty of the pattern")
+	archivesPath := flags.String("archives", "", "JSON array of archive records (default fetch the saved account)")
+	cachePath := flags.String("cache", "", "client cache or song list JSON (default the client cache)")
+	historyPath := flags.String("history", "", "history database (default the client history)")
+	configPath := flags.String("config", "", "config file holding the rating formula (default the client config)")
+	output := flags.String("o", "", "output file, .png or .svg")
+	flags.Parse(args)
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+	if format != client.ChartFormatPNG && format != client.ChartFormatSVG {
+		return fmt.Errorf("-o must be a .png or .svg file")
+	}
+	cache, err := loadCache(*cachePath)
+	if err != nil {
+		return err
+	}
+	archives, err := client.LoadArchives(*archivesPath)
+	if err != nil {
+		return fmt.Errorf("failed to load archive records: %v", err)
+	}
+	if *historyPath == "" {
+		*historyPath = client.HistoryPath()
+	}
+	history, err := client.OpenHistory(*historyPath)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+	records, err := client.ProgressArchives(archives, history, "")
+	if err != nil {
+		return err
+	}
+
+	var charts []client.Chart
+	if *songID != 0 {
+		charts = client.PatternProgress(records, client.PatternKey{SongID: *songID, Line: *line, Difficulty: *difficulty}, &cache)
+	} else {
+		// The rating follows the client config, as in the client
+		if *configPath == "" {
+			*configPath = client.ConfigPath()
+		}
+		formula := client.DefaultRatingConfig
+		if config, err := client.ReadConfigFile(*configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v, using the default rating formula\n", err)
+		} else {
+			formula = config.RatingFormula()
+		}
+		charts = client.OverallProgress(records, &cache, formula)
+	}
+	fonts, err := client.LoadFonts(client.RegularFontPath, client.BoldFontPath)
+	if err != nil {
+		fonts = client.FallbackFonts()
+	}
+	return client.WriteOutput(*output, func(w io.Writer) error {
+		return client.WriteCharts(w, format, charts, fonts)
+	})
+}
+
+// loadCache reads a client cache or a JSON array of songs, or the client cache when path is empty.
+func loadCache(path string) (client.Cache, error) {
+	if path == "" {
+		return client.LoadCache("db.json")
+	}
+	cache, err := client.ReadCacheFile(path)
+	if err != nil {
+		return client.Cache{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return cache, nil
+}
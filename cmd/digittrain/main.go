@@ -0,0 +1,165 @@
+// Command digittrain trains the digit templates used to read the number fields of the game.
+//
+// Usage:
+//
+//	digittrain -screenshots dir [-archives archives.json | -fetch] [-songs songs.json] [-config config.yaml] [-version v] [-o digits.json]
+//
+// Every screenshot is labelled by its sidecar JSON file, as in the golden corpus,
+// or by the archive record of its pattern. Records come from a file holding the
+// output of FetchArchive, or with -fetch from the server using the saved API key.
+// A screenshot is matched to a record by the song of its jacket, and by the line
+// and difficulty when they are known, so -songs is needed for archive labels.
+//
+// Glyphs are cut from every labelled number ROI and averaged per character and field.
+// The digits of every field are shared, so a field reads digits only other fields showed.
+// The templates are written as JSON, to be referenced by digitTemplates in the config.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"sort"
+
+	"github.com/Minsuh1204/PLATiNA-ARCHiVE-Go-Client/client"
+)
+
+func main() {
+	screenshotsDir := flag.String("screenshots", "", "folder of select or result screenshots")
+	archivesPath := flag.String("archives", "", "JSON array of archive records to label screenshots with")
+	fetch := flag.Bool("fetch", false, "fetch the archive records of the saved account")
+	songsPath := flag.String("songs", "", "song list used to match screenshots to archive records")
+	configPath := flag.String("config", "config.yaml", "ROI config of the screenshots")
+	version := flag.String("version", "", "game version the templates are trained on")
+	output := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+	if *screenshotsDir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config, err := client.ReadConfigFile(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+	if *fetch && *archivesPath != "" {
+		fmt.Fprintln(os.Stderr, "-archives and -fetch cannot be used together")
+		os.Exit(2)
+	}
+	var archives []client.Archive
+	if *fetch || *archivesPath != "" {
+		if archives, err = client.LoadArchives(*archivesPath); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load archive records: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	var cache client.Cache
+	if *songsPath != "" {
+		if cache, err = client.ReadCacheFile(*songsPath); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *songsPath, err)
+			os.Exit(1)
+		}
+	}
+	screenshots, err := client.LoadScreenshots(*screenshotsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	var samples []client.GlyphSample
+	for _, c := range screenshots {
+		img, err := c.LoadImage()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", c.ImagePath, err)
+			continue
+		}
+		labels := c.Expected
+		if labels == nil {
+			labels = make(map[string]any)
+		}
+		if len(archives) > 0 {
+			if err := addArchiveLabels(labels, img, &cache, config, archives); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", c.ImagePath, err)
+			}
+		}
+		found, skipped, err := client.ExtractGlyphSamples(img, config, labels)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", c.ImagePath, err)
+			continue
+		}
+		for _, s := range skipped {
+			fmt.Fprintf(os.Stderr, "%s: skipped %s\n", c.ImagePath, s)
+		}
+		samples = append(samples, found...)
+	}
+	if len(samples) == 0 {
+		fmt.Fprintln(os.Stderr, "no glyph samples found")
+		os.Exit(1)
+	}
+
+	templates := client.TrainDigitTemplates(samples, *version)
+	report(templates, samples)
+	if err := client.WriteOutput(*output, templates.Write); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write templates: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// addArchiveLabels finds the archive record of the screenshot's pattern and adds
+// the values it knows to labels. Labels from the sidecar are kept.
+func addArchiveLabels(labels map[string]any, img image.Image, cache *client.Cache, config *client.Config, archives []client.Archive) error {
+	report, err := client.AnalyzeImage(img, cache, config, client.AnalyzeOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to match the pattern: %v", err)
+	}
+	line, _ := labels["line"].(float64)
+	difficulty, _ := labels["difficulty"].(string)
+	if difficulty == "" {
+		difficulty = report.PatternObject.Difficulty
+	}
+
+	var matches []client.Archive
+	for _, a := range archives {
+		if a.SongID != report.SongObject.ID {
+			continue
+		}
+		if (line > 0 && a.Line != int(line)) || (difficulty != "" && a.Difficulty != difficulty) {
+			continue
+		}
+		matches = append(matches, a)
+	}
+	if len(matches) != 1 {
+		return fmt.Errorf("%d archive records match %s, label the line and difficulty in a sidecar", len(matches), report.SongObject.Title)
+	}
+	for key, value := range client.ArchiveLabels(matches[0]) {
+		if _, ok := labels[key]; !ok {
+			labels[key] = value
+		}
+	}
+	return nil
+}
+
+// report prints the number of samples of every character, and how many of them
+// the trained templates read back correctly.
+func report(templates *client.DigitTemplates, samples []client.GlyphSample) {
+	total := make(map[string]int)
+	correct := make(map[string]int)
+	for _, s := range samples {
+		total[s.Char]++
+		if templates.Classify(s) == s.Char {
+			correct[s.Char]++
+		}
+	}
+	chars := make([]string, 0, len(total))
+	for char := range total {
+		chars = append(chars, char)
+	}
+	sort.Strings(chars)
+	for _, char := range chars {
+		fmt.Fprintf(os.Stderr, "%q: %d samples, %d read back\n", char, total[char], correct[char])
+	}
+}
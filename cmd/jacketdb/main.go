@@ -0,0 +1,261 @@
+// Command jacketdb builds the jacket hash database used by the analyzer.
+//
+// Usage:
+//
+//	jacketdb [-songs songs.json] [-jackets dir] [-screenshots dir] [-config config.yaml] [-o out.json]
+//
+// Jacket images in the -jackets folder are named after the song ID or title,
+// with a "+" or "_plus" suffix for plus jackets, e.g. 12.png, 12+.png or "Firework.png".
+// Screenshots in the -screenshots folder need a sidecar JSON file, as in the golden corpus,
+// holding "songId" or "title", and "plus": true or "difficulty": "PLUS" for plus jackets.
+// Jackets are cropped from screenshots with the ROIs of the config.
+//
+// The songs are written as a JSON array in the Song shape, and every pair of songs
+// whose jackets are closer than -threshold is reported. The command exits with
+// status 1 when a near-collision is found or an input cannot be used.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Minsuh1204/PLATiNA-ARCHiVE-Go-Client/client"
+)
+
+// database holds the songs being built, indexed by ID and lower-case title.
+type database struct {
+	songs   map[int]*client.Song
+	byTitle map[string]*client.Song
+	sources map[string]string
+}
+
+func main() {
+	songsPath := flag.String("songs", "", "song list to fill in, as a JSON array of songs or a client cache file")
+	jacketsDir := flag.String("jackets", "", "folder of jacket images named after the song ID or title")
+	screenshotsDir := flag.String("screenshots", "", "folder of labelled select or result screenshots")
+	configPath := flag.String("config", "config.yaml", "ROI config used to crop jackets from screenshots")
+	threshold := flag.Float64("threshold", client.CollisionThreshold, "combined score at or below which two songs are reported as a near-collision")
+	output := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+	if *jacketsDir == "" && *screenshotsDir == "" && *songsPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db := &database{songs: make(map[int]*client.Song), byTitle: make(map[string]*client.Song), sources: make(map[string]string)}
+	if *songsPath != "" {
+		if err := db.load(*songsPath); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *songsPath, err)
+			os.Exit(1)
+		}
+	}
+
+	failed := false
+	// Screenshots go first, so clean jacket images override the cropped ones
+	if *screenshotsDir != "" {
+		config, err := client.ReadConfigFile(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+			os.Exit(1)
+		}
+		if !db.addScreenshots(*screenshotsDir, config) {
+			failed = true
+		}
+	}
+	if *jacketsDir != "" && !db.addJackets(*jacketsDir) {
+		failed = true
+	}
+
+	songs := db.list()
+	for _, song := range songs {
+		if song.PHash == "" {
+			fmt.Fprintf(os.Stderr, "missing jacket: %d %s\n", song.ID, song.Title)
+		}
+	}
+	collisions, err := client.FindJacketCollisions(songs, *threshold)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to compare jackets: %v\n", err)
+		os.Exit(1)
+	}
+	for _, c := range collisions {
+		fmt.Fprintf(os.Stderr, "near-collision: %s and %s (score %.3f, pHash distance %d)\n",
+			describe(c.Song, c.Plus), describe(c.Other, c.OtherPlus), c.Score, c.PHashDistance)
+	}
+
+	err = client.WriteOutput(*output, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(songs)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write songs: %v\n", err)
+		os.Exit(1)
+	}
+	if failed || len(collisions) > 0 {
+		os.Exit(1)
+	}
+}
+
+// load reads a JSON array of songs, or the songs of a client cache file.
+func (db *database) load(path string) error {
+	cache, err := client.ReadCacheFile(path)
+	if err != nil {
+		return err
+	}
+	for _, song := range cache.Songs {
+		db.add(song)
+	}
+	return nil
+}
+
+func (db *database) add(song client.Song) *client.Song {
+	s := &song
+	db.songs[song.ID] = s
+	if song.Title != "" {
+		db.byTitle[strings.ToLower(song.Title)] = s
+	}
+	return s
+}
+
+// lookup finds a song by ID, falling back to the title.
+// Unknown IDs are added, so a database can be built without a song list.
+func (db *database) lookup(id int, title string) (*client.Song, error) {
+	if id > 0 {
+		if song, ok := db.songs[id]; ok {
+			return song, nil
+		}
+		return db.add(client.Song{ID: id, Title: title}), nil
+	}
+	if song, ok := db.byTitle[strings.ToLower(title)]; ok {
+		return song, nil
+	}
+	return nil, fmt.Errorf("no song titled %q", title)
+}
+
+// setHashes stores the hashes of a jacket and warns when an earlier input is overridden.
+func (db *database) setHashes(song *client.Song, plus bool, hashes client.JacketHashes, source string) {
+	key := describe(*song, plus)
+	if previous, ok := db.sources[key]; ok {
+		fmt.Fprintf(os.Stderr, "%s: overrides the jacket of %s from %s\n", source, key, previous)
+	}
+	db.sources[key] = source
+	song.SetJacketHashes(hashes, plus)
+}
+
+func (db *database) addJackets(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+		return false
+	}
+	ok := true
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".png" && ext != ".jpg" && ext != ".jpeg") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		name, plus := parseJacketName(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		id, _ := strconv.Atoi(name)
+		song, err := db.lookup(id, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			ok = false
+			continue
+		}
+		img, err := client.ReadImageFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			ok = false
+			continue
+		}
+		hashes, err := client.ComputeJacketHashes(img)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			ok = false
+			continue
+		}
+		db.setHashes(song, plus, hashes, path)
+	}
+	return ok
+}
+
+// parseJacketName strips the plus suffix from a jacket file name.
+func parseJacketName(name string) (string, bool) {
+	for _, suffix := range []string{"+", "_plus", " plus"} {
+		if len(name) > len(suffix) && strings.HasSuffix(strings.ToLower(name), suffix) {
+			return name[:len(name)-len(suffix)], true
+		}
+	}
+	return name, false
+}
+
+func (db *database) addScreenshots(dir string, config *client.Config) bool {
+	cases, err := client.LoadCorpus(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+		return false
+	}
+	ok := true
+	for _, c := range cases {
+		id, _ := c.Expected["songId"].(float64)
+		title, _ := c.Expected["title"].(string)
+		plus, _ := c.Expected["plus"].(bool)
+		if difficulty, _ := c.Expected["difficulty"].(string); difficulty == "PLUS" {
+			plus = true
+		}
+		song, err := db.lookup(int(id), title)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", c.ImagePath, err)
+			ok = false
+			continue
+		}
+		img, err := c.LoadImage()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", c.ImagePath, err)
+			ok = false
+			continue
+		}
+		jacket, _, err := client.ExtractJacket(img, config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", c.ImagePath, err)
+			ok = false
+			continue
+		}
+		hashes, err := client.ComputeJacketHashes(jacket)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", c.ImagePath, err)
+			ok = false
+			continue
+		}
+		db.setHashes(song, plus, hashes, c.ImagePath)
+	}
+	return ok
+}
+
+// list returns the songs sorted by ID.
+func (db *database) list() []client.Song {
+	songs := make([]client.Song, 0, len(db.songs))
+	for _, song := range db.songs {
+		songs = append(songs, *song)
+	}
+	sort.Slice(songs, func(i, j int) bool { return songs[i].ID < songs[j].ID })
+	return songs
+}
+
+func describe(song client.Song, plus bool) string {
+	name := fmt.Sprintf("%d %s", song.ID, song.Title)
+	if plus {
+		name += " (plus)"
+	}
+	return name
+}
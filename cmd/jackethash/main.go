@@ -0,0 +1,76 @@
+// Command jackethash computes the jacket hashes used by the analyzer from reference jacket images.
+//
+// Usage:
+//
+//	jackethash [-plus] image...
+//
+// For each image it prints a JSON object whose keys match the Song fields,
+// so the output can be merged into the song database directly. To build the
+// whole database from a folder of jackets named after their songs, use jacketdb.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+
+	"github.com/Minsuh1204/PLATiNA-ARCHiVE-Go-Client/client"
+)
+
+func main() {
+	plus := flag.Bool("plus", false, "emit the hashes under the plus jacket keys")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-plus] image...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	failed := false
+	for _, path := range flag.Args() {
+		hashes, err := hashFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		encoder.Encode(toSongFields(path, hashes, *plus))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func hashFile(path string) (client.JacketHashes, error) {
+	img, err := client.ReadImageFile(path)
+	if err != nil {
+		return client.JacketHashes{}, err
+	}
+	return client.ComputeJacketHashes(img)
+}
+
+func toSongFields(path string, hashes client.JacketHashes, plus bool) map[string]string {
+	if plus {
+		return map[string]string{
+			"file":          path,
+			"plusPHash":     hashes.PHash,
+			"plusDHash":     hashes.DHash,
+			"plusAHash":     hashes.AHash,
+			"plusColorHist": hashes.ColorHist,
+		}
+	}
+	return map[string]string{
+		"file":      path,
+		"pHash":     hashes.PHash,
+		"dHash":     hashes.DHash,
+		"aHash":     hashes.AHash,
+		"colorHist": hashes.ColorHist,
+	}
+}
@@ -0,0 +1,111 @@
+// Command roicalibrate proposes a ROI config for a new screen resolution.
+//
+// Usage:
+//
+//	roicalibrate [-config config.yaml] [-reference 1920x1080] [-o entry.yaml] screenshot...
+//
+// The screenshots must share the new resolution and show the select or result screen.
+// The screen anchors of the reference entry are searched for in every screenshot,
+// every ROI is scaled with the best placement and the anchors are hashed again
+// at the proposed boxes. The entry is printed as a YAML "configs:" document,
+// ready to merge into config.yaml. The command exits with status 1 when a
+// screenshot is not identified with the proposed boxes.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+
+	"github.com/Minsuh1204/PLATiNA-ARCHiVE-Go-Client/client"
+)
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "config holding the reference ROI entry and anchor hashes")
+	referenceSize := flag.String("reference", "", "screen size of the entry to scale (default the config reference size)")
+	output := flag.String("o", "", "output file (default stdout)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] screenshot...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config, err := client.ReadConfigFile(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+	if *referenceSize == "" {
+		*referenceSize = config.Reference.String()
+	}
+	reference, ok := findEntry(config, *referenceSize)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: no configs entry for %s\n", *configPath, *referenceSize)
+		os.Exit(1)
+	}
+
+	var images []image.Image
+	for _, path := range flag.Args() {
+		img, err := client.ReadImageFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			os.Exit(1)
+		}
+		images = append(images, img)
+	}
+
+	proposed, calibration, checks, err := client.ProposeROIConfig(images, reference, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "calibration failed: %v\n", err)
+		os.Exit(1)
+	}
+	source := "the detected viewport"
+	if calibration.Anchor != client.UnknownScreen {
+		source = fmt.Sprintf("the %v anchor", calibration.Anchor)
+	}
+	fmt.Fprintf(os.Stderr, "calibration: offset (%d, %d), scale %.4f, from %s\n",
+		calibration.OffsetX, calibration.OffsetY, calibration.Scale, source)
+	failed := false
+	for i, check := range checks {
+		if check.ScreenType == client.UnknownScreen {
+			fmt.Fprintf(os.Stderr, "%s: no anchor matches the proposed boxes\n", flag.Arg(i))
+			failed = true
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v screen, anchor distance %d\n", flag.Arg(i), check.ScreenType, check.Distance)
+	}
+
+	data, err := client.MarshalROIConfigs(proposed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write YAML: %v\n", err)
+		os.Exit(1)
+	}
+	err = client.WriteOutput(*output, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *output, err)
+		os.Exit(1)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func findEntry(config *client.Config, screenSize string) (client.ROIConfig, bool) {
+	for _, cfg := range config.Configs {
+		if cfg.ScreenSize == screenSize {
+			return cfg, true
+		}
+	}
+	return client.ROIConfig{}, false
+}
@@ -3,8 +3,14 @@ package main
 import (
 	"encoding/base64"
 	"fmt"
+	"image"
 	"image/color"
+	"image/png"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -12,6 +18,7 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
 	"golang.design/x/hotkey"
 
@@ -22,6 +29,12 @@ import (
 
 var cache client.Cache
 var config client.Config
+var calibrations *client.CalibrationCache
+var digits *client.DigitTemplates
+var history *client.History
+var goals *client.GoalStore
+var jackets = client.DefaultJacketStore()
+var archives []client.Archive
 var APIKey string
 var b64APIKey string
 var decoderName string
@@ -31,8 +44,17 @@ var songLevelLabel *widget.Label
 var judgeLabel *widget.Label
 var scoreLabel *widget.Label
 var patchLabel *widget.Label
+var recordLabel *widget.Label
 var jacketContainer *fyne.Container
 var analyzeButton *widget.Button
+var syncButton *widget.Button
+var shareButton *widget.Button
+var lastReport *client.AnalysisReport
+var lastImprovement client.Improvement
+var sessions *client.SessionTracker
+var lastSession *client.Session
+var debugCheck *widget.Check
+var mainWindow fyne.Window
 
 func main() {
 	currentVersion := client.Version{Major: 1, Minor: 0, Patch: 0}
@@ -45,6 +67,7 @@ func main() {
 	}
 	a.Settings().SetTheme(&MyTheme{})
 	w := a.NewWindow(fmt.Sprintf("PLATiNA-ARCHiVE %s", currentVersion))
+	mainWindow = w
 	w.Resize(fyne.NewSize(800, 600))
 	w.SetFixedSize(true)
 
@@ -57,25 +80,63 @@ func main() {
 	judgeLabel = widget.NewLabel("")
 	scoreLabel = widget.NewLabel("")
 	patchLabel = widget.NewLabel("")
-	statsContainer := container.NewVBox(songTitleLabel, songLevelLabel, judgeLabel, scoreLabel, patchLabel)
+	recordLabel = widget.NewLabel("")
+	statsContainer := container.NewVBox(songTitleLabel, songLevelLabel, judgeLabel, scoreLabel, patchLabel, recordLabel)
 	topContainer := container.NewHBox(jacketContainer, layout.NewSpacer(), statsContainer)
 	paddedTopContainer := container.NewPadded(topContainer)
 
 	analyzeButton = widget.NewButton("Analyze", startAnalyze)
-	buttonContainer := container.New(layout.NewCenterLayout(), analyzeButton)
+	syncButton = widget.NewButton("Sync", startSync)
+	ratingButton := widget.NewButton("Rating", showRating)
+	practiceButton := widget.NewButton("Practice", showRecommendations)
+	exportButton := widget.NewButton("Export", showExportDialog)
+	importButton := widget.NewButton("Import", showImportDialog)
+	shareButton = widget.NewButton("Share", shareResult)
+	shareButton.Disable()
+	sessionButton := widget.NewButton("Session", showSession)
+	goalsButton := widget.NewButton("Goals", showGoals)
+	debugCheck = widget.NewCheck("Debug", nil)
+	buttonContainer := container.New(layout.NewCenterLayout(), container.NewHBox(analyzeButton, shareButton, syncButton, ratingButton, practiceButton, goalsButton, sessionButton, exportButton, importButton, debugCheck))
 
 	logLabel = widget.NewMultiLineEntry()
 	logLabel.Wrapping = fyne.TextWrapWord
 	logScroll := container.NewScroll(logLabel)
-	logScroll.SetMinSize(fyne.NewSize(0, 400))
+	logScroll.SetMinSize(fyne.NewSize(0, 340))
 	mainContainer := container.NewVBox(paddedTopContainer, canvas.NewLine(color.Gray{}), buttonContainer, canvas.NewLine(color.Gray{}), logScroll)
-	w.SetContent(mainContainer)
+
+	lampTab, refreshLamps := newLampTab()
+	progressTab, refreshProgress := newProgressTab()
+	libraryTab, refreshLibrary := newLibraryTab()
+	designerTab, refreshDesigners := newDesignerTab()
+	tabs := container.NewAppTabs(
+		container.NewTabItem("Analyze", mainContainer),
+		container.NewTabItem("Clear Lamp", lampTab),
+		container.NewTabItem("Progress", progressTab),
+		container.NewTabItem("Library", libraryTab),
+		container.NewTabItem("Designers", designerTab),
+	)
+	tabs.OnSelected = func(tab *container.TabItem) {
+		switch tab.Content {
+		case lampTab:
+			refreshLamps()
+		case progressTab:
+			refreshProgress()
+		case libraryTab:
+			refreshLibrary()
+		case designerTab:
+			refreshDesigners()
+		}
+	}
+	w.SetContent(tabs)
 
 	// Start goroutines for background tasks
 	go registerHotkeys()
 	go initSession(w)
 	go initCache()
 	go initConfig()
+	go initCalibrations()
+	go initHistory()
+	go initGoals()
 	go checkNewerVersion(w, currentVersion)
 
 	w.ShowAndRun()
@@ -117,6 +178,47 @@ func initConfig() {
 		logMessage(fmt.Sprintf("%v", err))
 	}
 	logMessage("설정 파일 로딩 성공")
+	sessions = client.NewSessionTracker(config.SessionIdle(), endSession)
+	initDigits()
+}
+
+// initDigits loads the digit templates referenced by the config.
+// Numbers are not read until they are loaded.
+func initDigits() {
+	if config.DigitTemplates == "" {
+		return
+	}
+	templates, err := client.LoadDigitTemplates(&config)
+	if err != nil {
+		logMessage(fmt.Sprintf("숫자 템플릿 로딩 실패: %v", err))
+		return
+	}
+	digits = templates
+	logMessage(fmt.Sprintf("숫자 템플릿 로딩 성공 (%s)", templates.Version))
+}
+
+func initCalibrations() {
+	var err error
+	calibrations, err = client.LoadCalibrationCache()
+	if err != nil {
+		logMessage(fmt.Sprintf("%v", err))
+	}
+}
+
+func initHistory() {
+	var err error
+	history, err = client.OpenHistory(client.HistoryPath())
+	if err != nil {
+		logMessage(fmt.Sprintf("플레이 기록 DB 로딩 실패: %v", err))
+	}
+}
+
+func initGoals() {
+	var err error
+	goals, err = client.LoadGoals()
+	if err != nil {
+		logMessage(fmt.Sprintf("목표 로딩 실패: %v", err))
+	}
 }
 
 func initSession(w fyne.Window) {
@@ -128,7 +230,18 @@ func initSession(w fyne.Window) {
 		decoderName = strings.Split(APIKey, "::")[0]
 		b64APIKey = base64.StdEncoding.EncodeToString([]byte(APIKey))
 		logMessage(fmt.Sprintf("환영합니다, %s님!", decoderName))
+		loadArchive()
+	}
+}
+
+// loadArchive fetches the server archive, used as the personal bests along with the local history.
+func loadArchive() {
+	fetched, err := client.FetchArchive(b64APIKey)
+	if err != nil {
+		logMessage(fmt.Sprintf("아카이브 로딩 실패: %v", err))
+		return
 	}
+	archives = fetched
 }
 
 func showWelcomeDialog(w fyne.Window) {
@@ -235,6 +348,7 @@ func handleAuthSuccess(key string) {
 	decoderName = strings.Split(APIKey, "::")[0]
 	b64APIKey = base64.StdEncoding.EncodeToString([]byte(APIKey))
 	logMessage(fmt.Sprintf("Success! Welcome, %s!", decoderName))
+	go loadArchive()
 }
 
 func logMessage(msg string) {
@@ -262,6 +376,12 @@ func startAnalyze() {
 	}
 	analyzeButton.Disable()
 	logMessage("Analyze started...")
+	opts := client.AnalyzeOptions{Calibrations: calibrations, Digits: digits, CacheError: func(err error) {
+		logMessage(fmt.Sprintf("보정 캐시 저장 실패: %v", err))
+	}}
+	if debugCheck.Checked {
+		opts.Debug = client.NewDebugRecorder()
+	}
 
 	go func() {
 		defer func() {
@@ -270,13 +390,30 @@ func startAnalyze() {
 			})
 		}()
 
-		report, err := client.AnalyzeScreenshot(&cache, &config)
+		img, err := client.LoadImageFromClipboard()
+		if err != nil {
+			logMessage(fmt.Sprintf("Analyze failed: failed to load image from clipboard: %v", err))
+			return
+		}
+		report, err := client.AnalyzeImage(img, &cache, &config, opts)
+		if opts.Debug != nil {
+			writeDebugBundle(opts.Debug)
+		}
 		if err != nil {
 			logMessage(fmt.Sprintf("Analyze failed: %v", err))
 			return
 		}
 
-		go updateDisplay(&report)
+		improvement := compareWithBest(report)
+		if sessions != nil {
+			sessions.Add(report, improvement, time.Now(), personalArchives)
+		}
+		play, recorded := recordPlay(img, report)
+		if err := jackets.SaveReportJacket(report, false); err != nil {
+			logMessage(fmt.Sprintf("자켓 저장 실패: %v", err))
+		}
+		go updateDisplay(&report, improvement)
+		go uploadReport(&report, improvement, play, recorded)
 
 		fyne.Do(func() {
 			logMessage("Analyze finished!")
@@ -284,13 +421,34 @@ func startAnalyze() {
 	}()
 }
 
-func updateDisplay(report *client.AnalysisReport) {
+// compareWithBest compares the report with the personal best of its pattern, before the play is recorded.
+func compareWithBest(report client.AnalysisReport) client.Improvement {
+	best, found, err := client.PersonalBest(client.ReportKey(report), archives, history)
+	if err != nil {
+		logMessage(fmt.Sprintf("최고 기록 조회 실패: %v", err))
+	}
+	return client.CompareWithBest(report, best, found)
+}
+
+func updateDisplay(report *client.AnalysisReport, improvement client.Improvement) {
 	fyne.Do(func() {
 		songTitleLabel.SetText(report.SongObject.Title)
 		songLevelLabel.SetText(fmt.Sprintf("Level: %d", report.PatternObject.Level))
 		judgeLabel.SetText(fmt.Sprintf("Judge: %v", report.Judge))
 		scoreLabel.SetText(fmt.Sprintf("Score: %v", report.Score))
 		patchLabel.SetText(fmt.Sprintf("Patch: %v", report.Patch))
+		highlightConfidence(songTitleLabel, report, client.FieldJacket)
+		highlightConfidence(songLevelLabel, report, client.FieldDifficulty)
+		highlightConfidence(judgeLabel, report, client.FieldJudge)
+		highlightConfidence(scoreLabel, report, client.FieldScore)
+		highlightConfidence(patchLabel, report, client.FieldPatch)
+		showImprovement(report, improvement)
+		lastReport, lastImprovement = report, improvement
+		if report.HasRecord() {
+			shareButton.Enable()
+		} else {
+			shareButton.Disable()
+		}
 
 		if report.JacketImage != nil {
 			img := canvas.NewImageFromImage(report.JacketImage)
@@ -301,3 +459,1023 @@ func updateDisplay(report *client.AnalysisReport) {
 		}
 	})
 }
+
+// showImprovement shows the change from the personal best, marking new records.
+func showImprovement(report *client.AnalysisReport, improvement client.Improvement) {
+	switch {
+	case !report.HasRecord():
+		recordLabel.SetText("")
+	case improvement.NewRecord():
+		recordLabel.SetText(fmt.Sprintf("NEW RECORD! %s", improvement.Summary()))
+		recordLabel.Importance = widget.SuccessImportance
+	default:
+		recordLabel.SetText(improvement.Summary())
+		recordLabel.Importance = widget.MediumImportance
+	}
+	recordLabel.Refresh()
+}
+
+// shareResult renders a card of the last analysed play, copies it to the clipboard and saves it.
+func shareResult() {
+	report, improvement := *lastReport, lastImprovement
+	go func() {
+		card := client.RenderShareCard(report, improvement, decoderName, renderFonts())
+		if err := client.CopyImageToClipboard(card); err != nil {
+			logMessage(fmt.Sprintf("클립보드 복사 실패: %v", err))
+		} else {
+			logMessage("결과 카드를 클립보드에 복사했습니다")
+		}
+		path := client.ShareCardPath(report, time.Now())
+		if err := client.SaveShareCard(path, card); err != nil {
+			logMessage(fmt.Sprintf("결과 카드 저장 실패: %v", err))
+			return
+		}
+		logMessage(fmt.Sprintf("결과 카드 저장 완료: %s", path))
+	}()
+}
+
+// endSession keeps the ended session to be shown and logs its summary.
+func endSession(session client.Session) {
+	fyne.Do(func() {
+		lastSession = &session
+	})
+	summary := session.Summary(&cache, config.RatingFormula())
+	logMessage(fmt.Sprintf("세션 종료: %d회 플레이, 신기록 %d개, Rating %+.2f",
+		summary.Plays, summary.NewBests, summary.RatingAfter-summary.RatingBefore))
+}
+
+// showSession shows the summary of the current session, or of the last one once it ended.
+func showSession() {
+	session, ok := client.Session{}, false
+	if sessions != nil {
+		session, ok = sessions.Current()
+	}
+	if !ok && lastSession != nil {
+		session, ok = *lastSession, true
+	}
+	if !ok {
+		logMessage("아직 세션이 없습니다. 분석하면 세션이 시작됩니다")
+		return
+	}
+
+	summaryLabel := widget.NewLabel("")
+	var summary client.SessionSummary
+	show := func(s client.Session) {
+		summary = s.Summary(&cache, config.RatingFormula())
+		text := strings.Join(summary.Lines(), "\n")
+		if !s.Ended {
+			text = "진행 중인 세션\n" + text
+		}
+		summaryLabel.SetText(text)
+	}
+	show(session)
+
+	var endButton *widget.Button
+	endButton = widget.NewButton("세션 종료", func() {
+		if ended, ok := sessions.End(); ok {
+			show(ended)
+		}
+		endButton.Disable()
+	})
+	if session.Ended {
+		endButton.Disable()
+	}
+	saveButton := widget.NewButton("저장", func() {
+		save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				logMessage(fmt.Sprintf("세션 저장 실패: %v", err))
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+			format := client.SessionFormatText
+			if strings.EqualFold(writer.URI().Extension(), "."+client.SessionFormatJSON) {
+				format = client.SessionFormatJSON
+			}
+			if err := client.WriteSessionSummary(writer, format, summary); err != nil {
+				logMessage(fmt.Sprintf("세션 저장 실패: %v", err))
+				return
+			}
+			logMessage(fmt.Sprintf("세션 저장 완료: %s", writer.URI().Path()))
+		}, mainWindow)
+		save.SetFilter(storage.NewExtensionFileFilter([]string{"." + client.SessionFormatText, "." + client.SessionFormatJSON}))
+		save.SetFileName(fmt.Sprintf("platina-session-%s.txt", summary.Start.Format("20060102-1504")))
+		save.Show()
+	})
+
+	content := container.NewBorder(nil, container.NewHBox(endButton, saveButton), nil, nil, container.NewVScroll(summaryLabel))
+	d := dialog.NewCustom("Session", "닫기", content, mainWindow)
+	d.Resize(fyne.NewSize(700, 500))
+	d.Show()
+}
+
+// checkGoals checks the goals against the personal bests, announcing the goals reached for the first time.
+// It returns the progress of every goal.
+func checkGoals() []client.GoalProgress {
+	if goals == nil {
+		return nil
+	}
+	progress, reached, err := goals.Check(personalArchives(), &cache)
+	if err != nil {
+		logMessage(fmt.Sprintf("목표 저장 실패: %v", err))
+	}
+	for _, g := range reached {
+		description := g.Describe(&cache)
+		logMessage(fmt.Sprintf("목표 달성: %s", description))
+		fyne.CurrentApp().SendNotification(fyne.NewNotification("목표 달성!", description))
+	}
+	return progress
+}
+
+// showGoals lists the goals with their progress, and lets goals be added and removed.
+func showGoals() {
+	if goals == nil {
+		logMessage("목표를 불러오지 못했습니다")
+		return
+	}
+	var progress []client.GoalProgress
+	var refresh func()
+	list := widget.NewList(
+		func() int { return len(progress) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil, widget.NewButton("삭제", nil),
+				container.NewVBox(widget.NewLabel(""), widget.NewProgressBar()))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			p := progress[i]
+			row := o.(*fyne.Container)
+			texts := row.Objects[0].(*fyne.Container)
+			text := fmt.Sprintf("%s  (%s)", p.Goal.Describe(&cache), p)
+			if p.Goal.Reached() {
+				text += fmt.Sprintf("  달성 %s", p.Goal.Achieved.Format("2006-01-02"))
+			}
+			texts.Objects[0].(*widget.Label).SetText(text)
+			texts.Objects[1].(*widget.ProgressBar).SetValue(p.Fraction)
+			id := p.Goal.ID
+			row.Objects[1].(*widget.Button).OnTapped = func() {
+				if err := goals.Remove(id); err != nil {
+					logMessage(fmt.Sprintf("목표 삭제 실패: %v", err))
+				}
+				refresh()
+			}
+		})
+	refresh = func() {
+		progress = checkGoals()
+		list.Refresh()
+	}
+	refresh()
+
+	addButton := widget.NewButton("목표 추가", func() { showAddGoalDialog(refresh) })
+	d := dialog.NewCustom("Goals", "닫기", container.NewBorder(nil, addButton, nil, nil, list), mainWindow)
+	d.Resize(fyne.NewSize(700, 500))
+	d.Show()
+}
+
+// Scopes of a new goal.
+const (
+	goalPattern = "패턴"
+	goalLevel   = "레벨 전체"
+)
+
+// showAddGoalDialog asks for a goal on a pattern, found by song title, or on every pattern of a level.
+func showAddGoalDialog(onAdded func()) {
+	kindSelect := widget.NewSelect(client.GoalKinds, nil)
+	kindSelect.SetSelected(client.GoalJudge)
+	targetEntry := widget.NewEntry()
+	targetEntry.SetPlaceHolder("99.5")
+	scopeSelect := widget.NewSelect([]string{goalPattern, goalLevel}, nil)
+	scopeSelect.SetSelected(goalPattern)
+	songEntry := widget.NewEntry()
+	songEntry.SetPlaceHolder("곡 제목")
+	levelEntry := widget.NewEntry()
+	levelEntry.SetPlaceHolder("12")
+	lineSelect := widget.NewSelect([]string{allOption, "4", "6"}, nil)
+	lineSelect.SetSelected(allOption)
+	difficultySelect := widget.NewSelect([]string{allOption, "EASY", "HARD", "OVER", "PLUS"}, nil)
+	difficultySelect.SetSelected(allOption)
+
+	form := []*widget.FormItem{
+		widget.NewFormItem("종류", kindSelect),
+		widget.NewFormItem("목표값", targetEntry),
+		widget.NewFormItem("범위", scopeSelect),
+		widget.NewFormItem("곡", songEntry),
+		widget.NewFormItem("레벨", levelEntry),
+		widget.NewFormItem("라인", lineSelect),
+		widget.NewFormItem("난이도", difficultySelect),
+	}
+	dialog.ShowForm("목표 추가", "추가", "취소", form, func(ok bool) {
+		if !ok {
+			return
+		}
+		goal := client.Goal{Kind: kindSelect.Selected}
+		goal.Target, _ = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(targetEntry.Text), "%"), 64)
+		filter := patternFilter(lineSelect, difficultySelect)
+		goal.Lines, goal.Difficulties = filter.Lines, filter.Difficulties
+		if scopeSelect.Selected == goalPattern {
+			song, _, found := client.MatchSongTitle(songEntry.Text, cache.Songs)
+			if !found || len(goal.Lines) != 1 || len(goal.Difficulties) != 1 {
+				logMessage("목표 추가 실패: 곡, 라인, 난이도를 모두 정해야 합니다")
+				return
+			}
+			key := client.PatternKey{SongID: song.ID, Line: goal.Lines[0], Difficulty: goal.Difficulties[0]}
+			if _, ok := cache.FindPattern(key); !ok {
+				logMessage(fmt.Sprintf("목표 추가 실패: %s %dL %s 패턴이 없습니다", song.Title, key.Line, key.Difficulty))
+				return
+			}
+			goal.Pattern = &key
+			goal.Lines, goal.Difficulties = nil, nil
+		} else {
+			goal.Level, _ = strconv.Atoi(strings.TrimSpace(levelEntry.Text))
+		}
+		added, err := goals.Add(goal)
+		if err != nil {
+			logMessage(fmt.Sprintf("목표 추가 실패: %v", err))
+			return
+		}
+		logMessage(fmt.Sprintf("목표 추가: %s", added.Describe(&cache)))
+		onAdded()
+	}, mainWindow)
+}
+
+// startSync asks whether to merge the bests of different plays, then brings the local play history
+// and the server archive to the same bests.
+func startSync() {
+	if history == nil {
+		logMessage("플레이 기록 DB가 없어 동기화할 수 없습니다")
+		return
+	}
+	if b64APIKey == "" {
+		logMessage("로그인 후 동기화할 수 있습니다")
+		return
+	}
+	mergeCheck := widget.NewCheck("서로 다른 플레이의 최고 값을 합쳐서 업로드", nil)
+	content := container.NewVBox(
+		widget.NewLabel("확인된 플레이만 그 플레이의 값 그대로 업로드합니다.\n"+
+			"합치기를 켜면 한 번도 함께 기록된 적 없는 판정, 점수, 패치를 하나의 기록으로 올릴 수 있습니다."),
+		mergeCheck,
+	)
+	dialog.ShowCustomConfirm("동기화", "동기화", "취소", content, func(confirm bool) {
+		if confirm {
+			runSync(mergeCheck.Checked)
+		}
+	}, mainWindow)
+}
+
+func runSync(merge bool) {
+	syncButton.Disable()
+	logMessage("Sync started...")
+
+	go func() {
+		defer fyne.Do(func() { syncButton.Enable() })
+
+		result, err := client.Sync(history, client.NewArchiveServer(b64APIKey), decoderName, merge)
+		if err != nil {
+			logMessage(fmt.Sprintf("Sync failed: %v", err))
+			return
+		}
+		for _, f := range result.Failed {
+			logMessage(fmt.Sprintf("업로드 실패 (%s): %v", client.ArchiveKey(f.Archive), f.Err))
+		}
+		logMessage(fmt.Sprintf("동기화 완료: 받은 기록 %d개, 올린 기록 %d개, 충돌 %d개, 실패 %d개",
+			len(result.Pulled), len(result.Pushed), len(result.Conflicts), len(result.Failed)))
+		loadArchive()
+		checkGoals()
+		if len(result.Conflicts) == 0 {
+			return
+		}
+
+		lines := make([]string, len(result.Conflicts))
+		for i, c := range result.Conflicts {
+			lines[i] = c.String()
+			if !c.Pushed {
+				lines[i] += " (업로드 안 함)"
+			}
+			logMessage(fmt.Sprintf("동기화 충돌: %s", lines[i]))
+		}
+		fyne.Do(func() {
+			dialog.ShowInformation("동기화 충돌",
+				fmt.Sprintf("서버와 로컬 기록이 서로 다른 값에서 앞서는 패턴입니다. 서버 기록은 로컬에 반영했습니다:\n%s",
+					strings.Join(lines, "\n")), mainWindow)
+		})
+	}()
+}
+
+// personalArchives returns the best record of every pattern from the server archive and the confirmed plays of the local history.
+func personalArchives() []client.Archive {
+	records := append([]client.Archive(nil), archives...)
+	if history == nil {
+		return client.BestArchives(records)
+	}
+	bests, err := history.Bests()
+	if err != nil {
+		logMessage(fmt.Sprintf("최고 기록 조회 실패: %v", err))
+	}
+	for _, b := range bests {
+		records = append(records, b.ToArchive(decoderName))
+	}
+	return client.BestArchives(records)
+}
+
+// showRating shows the rating with the contribution of every pattern and the patch it needs to raise the total.
+func showRating() {
+	rating := client.ComputeRating(personalArchives(), &cache, config.RatingFormula(), config.MaxPatch)
+	list := widget.NewList(
+		func() int { return len(rating.Entries) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			e := rating.Entries[i]
+			text := fmt.Sprintf("%d. %s %dL %s Lv.%d  %.2f", i+1, e.Title, e.Line, e.Difficulty, e.Level, e.Patch)
+			if e.Counted {
+				text += fmt.Sprintf("  (+%.2f)", e.Contribution)
+			} else {
+				text += fmt.Sprintf("  %.2f 초과 필요", e.Needed)
+			}
+			if e.Potential > 0 {
+				text += fmt.Sprintf("  최대 +%.2f", e.Potential)
+			}
+			o.(*widget.Label).SetText(text)
+		})
+	header := widget.NewLabel(fmt.Sprintf("Rating: %.2f (상위 %d개 %s)", rating.Total, rating.Formula.TopN, rating.Formula.Method))
+	d := dialog.NewCustom("Rating", "닫기", container.NewBorder(header, nil, nil, nil, list), mainWindow)
+	d.Resize(fyne.NewSize(700, 500))
+	d.Show()
+}
+
+// allOption is the filter choice that matches everything.
+const allOption = "전체"
+
+// baseDLC names the songs without a DLC in DLC selects.
+const baseDLC = "기본"
+
+// dlcOptions returns the choices of a DLC select: allOption, then every DLC of the song list.
+func dlcOptions() []string {
+	dlcSet := make(map[string]bool)
+	for _, s := range cache.Songs {
+		dlcSet[s.DLC] = true
+	}
+	dlcs := []string{allOption}
+	for dlc := range dlcSet {
+		if dlc == "" {
+			dlc = baseDLC
+		}
+		dlcs = append(dlcs, dlc)
+	}
+	sort.Strings(dlcs[1:])
+	return dlcs
+}
+
+// dlcFilter returns the DLCs to filter by for the choice of a DLC select, or nil for allOption.
+func dlcFilter(selected string) []string {
+	switch selected {
+	case allOption, "":
+		return nil
+	case baseDLC:
+		return []string{""}
+	}
+	return []string{selected}
+}
+
+// showRecommendations shows practice lists, filtered by DLC, line and difficulty.
+func showRecommendations() {
+	dlcSelect := widget.NewSelect(dlcOptions(), nil)
+	lineSelect := widget.NewSelect([]string{allOption, "4", "6"}, nil)
+	difficultySelect := widget.NewSelect([]string{allOption, "EASY", "HARD", "OVER", "PLUS"}, nil)
+
+	var recs client.Recommendations
+	skillLabel := widget.NewLabel("")
+	recommendationList := func(items *[]client.Recommendation) *widget.List {
+		return widget.NewList(
+			func() int { return len(*items) },
+			func() fyne.CanvasObject { return widget.NewLabel("") },
+			func(i widget.ListItemID, o fyne.CanvasObject) {
+				r := (*items)[i]
+				text := fmt.Sprintf("%s %dL %s Lv.%d", r.Song.Title, r.Pattern.Line, r.Pattern.Difficulty, r.Pattern.Level)
+				if r.Played {
+					text += fmt.Sprintf("  %.2f → %.2f", r.Patch, r.Achievable)
+				}
+				if r.RatingGain > 0 {
+					text += fmt.Sprintf("  Rating +%.2f", r.RatingGain)
+				}
+				o.(*widget.Label).SetText(text)
+			})
+	}
+	unplayedList := recommendationList(&recs.Unplayed)
+	improveList := recommendationList(&recs.Improve)
+
+	refresh := func(string) {
+		filter := patternFilter(lineSelect, difficultySelect)
+		filter.DLCs = dlcFilter(dlcSelect.Selected)
+		recs = client.Recommend(personalArchives(), &cache, config.RatingFormula(), config.MaxPatch,
+			client.RecommendOptions{Filter: filter, Limit: 100})
+		skillLabel.SetText(fmt.Sprintf("실력 레벨: %.1f", recs.SkillLevel))
+		unplayedList.Refresh()
+		improveList.Refresh()
+	}
+	dlcSelect.OnChanged = refresh
+	lineSelect.OnChanged = refresh
+	difficultySelect.OnChanged = refresh
+	dlcSelect.SetSelected(allOption)
+	lineSelect.SetSelected(allOption)
+	difficultySelect.SetSelected(allOption)
+
+	filters := container.NewHBox(dlcSelect, lineSelect, difficultySelect, skillLabel)
+	tabs := container.NewAppTabs(
+		container.NewTabItem("안 해본 패턴", unplayedList),
+		container.NewTabItem("올릴 수 있는 패턴", improveList),
+	)
+	d := dialog.NewCustom("Practice", "닫기", container.NewBorder(filters, nil, nil, nil, tabs), mainWindow)
+	d.Resize(fyne.NewSize(700, 500))
+	d.Show()
+}
+
+// patternFilter returns the pattern filter of the line and difficulty selects.
+func patternFilter(lineSelect *widget.Select, difficultySelect *widget.Select) client.PatternFilter {
+	var filter client.PatternFilter
+	if line, err := strconv.Atoi(lineSelect.Selected); err == nil {
+		filter.Lines = []int{line}
+	}
+	if difficultySelect.Selected != allOption {
+		filter.Difficulties = []string{difficultySelect.Selected}
+	}
+	return filter
+}
+
+// newLampTab builds the clear-lamp table tab. The returned function rebuilds the table
+// from the current archive.
+func newLampTab() (fyne.CanvasObject, func()) {
+	lineSelect := widget.NewSelect([]string{allOption, "4", "6"}, nil)
+	difficultySelect := widget.NewSelect([]string{allOption, "EASY", "HARD", "OVER", "PLUS"}, nil)
+	lineSelect.SetSelected(allOption)
+	difficultySelect.SetSelected(allOption)
+	levelsBox := container.NewVBox()
+
+	refresh := func() {
+		levels := client.BuildLampTable(&cache, personalArchives(), patternFilter(lineSelect, difficultySelect))
+		levelsBox.Objects = nil
+		for _, l := range levels {
+			grid := container.NewGridWrap(fyne.NewSize(72, 72))
+			for _, c := range l.Cells {
+				grid.Add(newLampCell(c))
+			}
+			levelsBox.Add(widget.NewLabelWithStyle(l.Summary(), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+			levelsBox.Add(grid)
+		}
+		levelsBox.Refresh()
+	}
+	lineSelect.OnChanged = func(string) { refresh() }
+	difficultySelect.OnChanged = func(string) { refresh() }
+
+	saveButton := widget.NewButton("PNG 저장", func() {
+		levels := client.BuildLampTable(&cache, personalArchives(), patternFilter(lineSelect, difficultySelect))
+		save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				logMessage(fmt.Sprintf("PNG 저장 실패: %v", err))
+				return
+			}
+			if writer == nil {
+				return
+			}
+			go func() {
+				defer writer.Close()
+				img := client.RenderLampTable(levels, jackets, renderFonts())
+				if err := png.Encode(writer, img); err != nil {
+					logMessage(fmt.Sprintf("PNG 저장 실패: %v", err))
+					return
+				}
+				logMessage(fmt.Sprintf("클리어 램프 저장 완료: %s", writer.URI().Path()))
+			}()
+		}, mainWindow)
+		save.SetFileName(fmt.Sprintf("platina-lamp-%s.png", time.Now().Format("20060102")))
+		save.Show()
+	})
+	filters := container.NewHBox(lineSelect, difficultySelect, widget.NewButton("새로고침", refresh), saveButton)
+	return container.NewBorder(filters, nil, nil, nil, container.NewVScroll(levelsBox)), refresh
+}
+
+// newLampCell shows the jacket of a pattern framed by its lamp color, or its title without a stored jacket.
+func newLampCell(c client.LampCell) fyne.CanvasObject {
+	frame := canvas.NewRectangle(c.Lamp.Color())
+	var inner fyne.CanvasObject
+	if img, ok := jackets.Load(c.Song.ID, c.Pattern.Difficulty == "PLUS"); ok {
+		jacket := canvas.NewImageFromImage(img)
+		jacket.FillMode = canvas.ImageFillContain
+		inner = jacket
+	} else {
+		title := canvas.NewText(c.Song.Title, color.White)
+		title.TextSize = 10
+		inner = container.NewStack(canvas.NewRectangle(color.RGBA{45, 45, 50, 255}), container.NewCenter(title))
+	}
+	tag := canvas.NewText(c.Tag(), color.White)
+	tag.TextSize = 10
+	tag.TextStyle.Bold = true
+	cell := container.NewStack(inner, container.NewVBox(layout.NewSpacer(), tag))
+	return container.NewStack(frame, container.New(layout.NewCustomPaddedLayout(4, 4, 4, 4), cell))
+}
+
+// newLibraryTab builds the song browser: search and filters over the song list, and the patterns
+// of the selected song with the personal bests. The returned function reloads the song list.
+func newLibraryTab() (fyne.CanvasObject, func()) {
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("제목 또는 아티스트 (초성 검색 가능)")
+	dlcSelect := widget.NewSelect([]string{allOption}, nil)
+	dlcSelect.SetSelected(allOption)
+	minBPMEntry, maxBPMEntry := widget.NewEntry(), widget.NewEntry()
+	minBPMEntry.SetPlaceHolder("BPM 이상")
+	maxBPMEntry.SetPlaceHolder("BPM 이하")
+	minLevelEntry, maxLevelEntry := widget.NewEntry(), widget.NewEntry()
+	minLevelEntry.SetPlaceHolder("레벨 이상")
+	maxLevelEntry.SetPlaceHolder("레벨 이하")
+	sortSelect := widget.NewSelect(client.LibrarySorts, nil)
+	sortSelect.SetSelected(client.LibrarySortRelevance)
+	descendingCheck := widget.NewCheck("역순", nil)
+
+	var entries []client.LibraryEntry
+	var bests []client.Archive
+	songList := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			e := entries[i]
+			o.(*widget.Label).SetText(fmt.Sprintf("%s - %s  (%s)", e.Song.Title, e.Song.Artist, e.Levels()))
+		})
+
+	jacketPlaceholder := canvas.NewRectangle(color.Black)
+	jacketPlaceholder.SetMinSize(fyne.NewSize(150, 150))
+	detailJacket := container.NewStack(jacketPlaceholder)
+	detailTitle := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	detailTitle.Wrapping = fyne.TextWrapWord
+	detailInfo := widget.NewLabel("")
+	var patterns []client.LibraryPattern
+	patternList := widget.NewList(
+		func() int { return len(patterns) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			p := patterns[i]
+			text := fmt.Sprintf("%dL %s Lv.%d", p.Pattern.Line, p.Pattern.Difficulty, p.Pattern.Level)
+			if p.Played {
+				text += fmt.Sprintf("  %.4f%%  %.0f  %.2f  %s", p.Best.Judge, p.Best.Score, p.Best.Patch, client.ArchiveLamp(p.Best))
+			} else {
+				text += "  기록 없음"
+			}
+			o.(*widget.Label).SetText(text)
+		})
+	songList.OnSelected = func(i widget.ListItemID) {
+		e := entries[i]
+		detailTitle.SetText(e.Song.Title)
+		info := fmt.Sprintf("%s\nBPM %s", e.Song.Artist, e.Song.BPM)
+		if e.Song.DLC != "" {
+			info += "\n" + e.Song.DLC
+		}
+		detailInfo.SetText(info)
+		var jacket fyne.CanvasObject = jacketPlaceholder
+		if img, ok := jackets.Load(e.Song.ID, false); ok {
+			jacketImage := canvas.NewImageFromImage(img)
+			jacketImage.FillMode = canvas.ImageFillContain
+			jacketImage.SetMinSize(fyne.NewSize(150, 150))
+			jacket = jacketImage
+		}
+		detailJacket.Objects = []fyne.CanvasObject{jacket}
+		detailJacket.Refresh()
+		patterns = client.SongPatterns(&cache, e.Song.ID, bests)
+		patternList.Refresh()
+	}
+
+	search := func() {
+		q := client.LibraryQuery{
+			Text:       searchEntry.Text,
+			DLCs:       dlcFilter(dlcSelect.Selected),
+			Sort:       sortSelect.Selected,
+			Descending: descendingCheck.Checked,
+		}
+		// Unreadable numbers are left out of the query
+		q.MinBPM, _ = strconv.Atoi(strings.TrimSpace(minBPMEntry.Text))
+		q.MaxBPM, _ = strconv.Atoi(strings.TrimSpace(maxBPMEntry.Text))
+		q.MinLevel, _ = strconv.Atoi(strings.TrimSpace(minLevelEntry.Text))
+		q.MaxLevel, _ = strconv.Atoi(strings.TrimSpace(maxLevelEntry.Text))
+		entries = client.SearchLibrary(&cache, q)
+		songList.UnselectAll()
+		songList.Refresh()
+	}
+	searchEntry.OnChanged = func(string) { search() }
+	for _, e := range []*widget.Entry{minBPMEntry, maxBPMEntry, minLevelEntry, maxLevelEntry} {
+		e.OnChanged = func(string) { search() }
+	}
+	dlcSelect.OnChanged = func(string) { search() }
+	sortSelect.OnChanged = func(string) { search() }
+	descendingCheck.OnChanged = func(bool) { search() }
+
+	refresh := func() {
+		bests = personalArchives()
+		selected := dlcSelect.Selected
+		dlcSelect.SetOptions(dlcOptions())
+		dlcSelect.SetSelected(selected)
+		search()
+	}
+
+	filters := container.NewGridWithColumns(7, dlcSelect, minBPMEntry, maxBPMEntry, minLevelEntry, maxLevelEntry, sortSelect, descendingCheck)
+	detail := container.NewBorder(
+		container.NewHBox(detailJacket, container.NewVBox(detailTitle, detailInfo)), nil, nil, nil, patternList)
+	split := container.NewHSplit(songList, detail)
+	split.SetOffset(0.5)
+	return container.NewBorder(container.NewVBox(searchEntry, filters), nil, nil, nil, split), refresh
+}
+
+// newDesignerTab builds the tab of the patterns and personal bests per chart designer,
+// weakest designer first. The returned function recomputes the statistics.
+func newDesignerTab() (fyne.CanvasObject, func()) {
+	lineSelect := widget.NewSelect([]string{allOption, "4", "6"}, nil)
+	difficultySelect := widget.NewSelect([]string{allOption, "EASY", "HARD", "OVER", "PLUS"}, nil)
+	lineSelect.SetSelected(allOption)
+	difficultySelect.SetSelected(allOption)
+
+	var stats []client.DesignerStats
+	list := widget.NewList(
+		func() int { return len(stats) },
+		func() fyne.CanvasObject {
+			return container.NewVBox(widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), widget.NewLabel(""))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			d := stats[i]
+			name := d.Designer
+			if name == "" {
+				name = "(미상)"
+			}
+			title := fmt.Sprintf("%s  패턴 %d개, 플레이 %d개", name, d.Patterns, d.Played)
+			if d.Played > 0 {
+				title += fmt.Sprintf("  Judge %.4f%% (%+.4f)  Patch %.2f", d.AverageJudge, d.JudgeGap, d.AveragePatch)
+			}
+			rows := o.(*fyne.Container).Objects
+			rows[0].(*widget.Label).SetText(title)
+			rows[1].(*widget.Label).SetText(d.Distribution())
+		})
+	refresh := func() {
+		stats = client.DesignerStatistics(&cache, personalArchives(), patternFilter(lineSelect, difficultySelect))
+		list.Refresh()
+	}
+	lineSelect.OnChanged = func(string) { refresh() }
+	difficultySelect.OnChanged = func(string) { refresh() }
+
+	help := widget.NewLabel("괄호 안은 같은 레벨 평균 Judge와의 차이입니다")
+	top := container.NewHBox(lineSelect, difficultySelect, help)
+	return container.NewBorder(top, nil, nil, nil, list), refresh
+}
+
+// newProgressTab builds the tab of the progress charts, overall or of a played pattern.
+// The returned function reloads the records.
+func newProgressTab() (fyne.CanvasObject, func()) {
+	var records []client.Archive
+	var keys []client.PatternKey
+	var charts []client.Chart
+	patternSelect := widget.NewSelect(nil, nil)
+	chartImage := canvas.NewImageFromImage(nil)
+	chartImage.FillMode = canvas.ImageFillOriginal
+
+	// drawing counts the draws, so a slow draw does not replace a later selection
+	var drawing int
+	draw := func(string) {
+		drawing++
+		generation, selected, snapshot := drawing, patternSelect.SelectedIndex(), records
+		var key client.PatternKey
+		if selected > 0 {
+			key = keys[selected-1]
+		}
+		go func() {
+			var drawn []client.Chart
+			if selected > 0 {
+				drawn = client.PatternProgress(snapshot, key, &cache)
+			} else {
+				drawn = client.OverallProgress(snapshot, &cache, config.RatingFormula())
+			}
+			img := client.RenderCharts(drawn, renderFonts())
+			fyne.Do(func() {
+				if generation != drawing {
+					return
+				}
+				charts = drawn
+				chartImage.Image = img
+				chartImage.Refresh()
+			})
+		}()
+	}
+	patternSelect.OnChanged = draw
+
+	refresh := func() {
+		var err error
+		records, err = client.ProgressArchives(archives, history, decoderName)
+		if err != nil {
+			logMessage(fmt.Sprintf("기록 조회 실패: %v", err))
+		}
+		keys = nil
+		seen := make(map[client.PatternKey]bool)
+		for _, a := range records {
+			if key := client.ArchiveKey(a); !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		names := make(map[client.PatternKey]string)
+		for _, key := range keys {
+			names[key] = key.String()
+			if song, ok := cache.FindSong(key.SongID); ok {
+				names[key] = fmt.Sprintf("%s %dL %s", song.Title, key.Line, key.Difficulty)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return names[keys[i]] < names[keys[j]] })
+		options := []string{allOption}
+		for _, key := range keys {
+			options = append(options, names[key])
+		}
+		patternSelect.SetOptions(options)
+		// Selecting the first option draws the charts, even when it was already selected
+		patternSelect.ClearSelected()
+		patternSelect.SetSelectedIndex(0)
+	}
+
+	saveButton := widget.NewButton("저장", func() {
+		save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				logMessage(fmt.Sprintf("차트 저장 실패: %v", err))
+				return
+			}
+			if writer == nil {
+				return
+			}
+			format := client.ChartFormatPNG
+			if strings.EqualFold(writer.URI().Extension(), "."+client.ChartFormatSVG) {
+				format = client.ChartFormatSVG
+			}
+			shown := charts
+			go func() {
+				defer writer.Close()
+				if err := client.WriteCharts(writer, format, shown, renderFonts()); err != nil {
+					logMessage(fmt.Sprintf("차트 저장 실패: %v", err))
+					return
+				}
+				logMessage(fmt.Sprintf("차트 저장 완료: %s", writer.URI().Path()))
+			}()
+		}, mainWindow)
+		save.SetFilter(storage.NewExtensionFileFilter([]string{"." + client.ChartFormatPNG, "." + client.ChartFormatSVG}))
+		save.SetFileName(fmt.Sprintf("platina-progress-%s.png", time.Now().Format("20060102")))
+		save.Show()
+	})
+	top := container.NewBorder(nil, nil, nil, saveButton, patternSelect)
+	return container.NewBorder(top, nil, nil, nil, container.NewVScroll(chartImage)), refresh
+}
+
+// renderFonts loads the bundled fonts for generated images once, or the Go fonts when they are missing.
+var renderFonts = sync.OnceValue(func() *client.Fonts {
+	fonts, err := client.LoadFonts(client.RegularFontPath, client.BoldFontPath)
+	if err != nil {
+		logMessage(fmt.Sprintf("폰트 로드 실패, 기본 폰트 사용: %v", err))
+		return client.FallbackFonts()
+	}
+	return fonts
+})
+
+// Sources of the export dialog.
+const (
+	exportArchive = "아카이브"
+	exportHistory = "분석 기록"
+)
+
+// showExportDialog asks for the records and format to export, then for the file to write.
+func showExportDialog() {
+	sourceSelect := widget.NewSelect([]string{exportArchive, exportHistory}, nil)
+	sourceSelect.SetSelected(exportArchive)
+	formatSelect := widget.NewSelect(client.ExportFormats, nil)
+	formatSelect.SetSelected(client.FormatCSV)
+	form := []*widget.FormItem{
+		widget.NewFormItem("기록", sourceSelect),
+		widget.NewFormItem("형식", formatSelect),
+	}
+	dialog.ShowForm("Export", "저장", "취소", form, func(ok bool) {
+		if !ok {
+			return
+		}
+		source, format := sourceSelect.Selected, formatSelect.Selected
+		save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				logMessage(fmt.Sprintf("Export failed: %v", err))
+				return
+			}
+			if writer == nil {
+				return
+			}
+			go exportRecords(writer, source, format)
+		}, mainWindow)
+		save.SetFileName(fmt.Sprintf("platina-archive-%s.%s", time.Now().Format("20060102"), format))
+		save.Show()
+	}, mainWindow)
+}
+
+func exportRecords(writer fyne.URIWriteCloser, source string, format string) {
+	defer writer.Close()
+	var err error
+	switch source {
+	case exportArchive:
+		// The records as fetched from the server; local plays are exported from the history
+		err = client.ExportArchives(writer, format, archives, &cache)
+	case exportHistory:
+		if history == nil {
+			err = fmt.Errorf("no history database")
+			break
+		}
+		err = client.ExportPlays(writer, format, history, client.HistoryQuery{}, &cache)
+	}
+	if err != nil {
+		logMessage(fmt.Sprintf("Export failed: %v", err))
+		return
+	}
+	logMessage(fmt.Sprintf("내보내기 완료: %s", writer.URI().Path()))
+}
+
+// showImportDialog asks for a CSV or JSON file of play records, shows how it differs
+// from the archive and uploads the new and improved records once confirmed.
+func showImportDialog() {
+	if b64APIKey == "" {
+		logMessage("로그인 후 가져올 수 있습니다")
+		return
+	}
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			logMessage(fmt.Sprintf("Import failed: %v", err))
+			return
+		}
+		if reader == nil {
+			return
+		}
+		go planImport(reader)
+	}, mainWindow)
+}
+
+func planImport(reader fyne.URIReadCloser) {
+	table, err := client.ReadImportTable(reader)
+	reader.Close()
+	if err != nil {
+		logMessage(fmt.Sprintf("Import failed: %v", err))
+		return
+	}
+	server := client.NewArchiveServer(b64APIKey)
+	existing, err := server.FetchArchive()
+	if err != nil {
+		logMessage(fmt.Sprintf("Import failed: %v", err))
+		return
+	}
+	plan := client.PlanImport(table, client.GuessImportMapping(table.Header), &cache, existing, decoderName)
+	counts := plan.Counts()
+	summary := fmt.Sprintf("새 기록 %d개, 갱신 %d개, 충돌 %d개, 변경 없음 %d개, 오류 %d개",
+		counts[client.ImportNew], counts[client.ImportImproved], counts[client.ImportConflict], counts[client.ImportUnchanged], counts[client.ImportInvalid])
+	logMessage(fmt.Sprintf("가져오기 확인: %s", summary))
+
+	diff := plan.Diff()
+	fyne.Do(func() {
+		list := widget.NewList(
+			func() int { return len(diff) },
+			func() fyne.CanvasObject { return widget.NewLabel("") },
+			func(i widget.ListItemID, o fyne.CanvasObject) { o.(*widget.Label).SetText(diff[i]) })
+		content := container.NewBorder(widget.NewLabel(summary), nil, nil, nil, list)
+		d := dialog.NewCustomConfirm("Import", "업로드", "취소", content, func(confirm bool) {
+			if !confirm {
+				logMessage("가져오기를 취소했습니다")
+				return
+			}
+			go func() {
+				pushed, failed := plan.Upload(server)
+				for _, f := range failed {
+					logMessage(fmt.Sprintf("업로드 실패 (%s): %v", client.ArchiveKey(f.Archive), f.Err))
+				}
+				logMessage(fmt.Sprintf("가져오기 완료: %d개 업로드, %d개 실패", len(pushed), len(failed)))
+				loadArchive()
+			}()
+		}, mainWindow)
+		d.Resize(fyne.NewSize(700, 500))
+		d.Show()
+	})
+}
+
+// recordPlay stores the analysed play and its screenshot in the local history.
+// The play only counts towards the bests once it is confirmed. The bool is false when it was not stored.
+func recordPlay(img image.Image, report client.AnalysisReport) (client.PlayRecord, bool) {
+	if history == nil {
+		return client.PlayRecord{}, false
+	}
+	playedAt := time.Now()
+	screenshotPath, err := history.SaveScreenshot(img, playedAt)
+	if err != nil {
+		logMessage(fmt.Sprintf("스크린샷 저장 실패: %v", err))
+	}
+	play, err := history.Add(client.NewPlayRecord(report, playedAt, screenshotPath))
+	if err != nil {
+		logMessage(fmt.Sprintf("플레이 기록 저장 실패: %v", err))
+		return client.PlayRecord{}, false
+	}
+	return play, true
+}
+
+// confirmPlay adds a play the user accepted, or that is uploaded, to the local bests,
+// and keeps its jacket.
+func confirmPlay(report *client.AnalysisReport, play client.PlayRecord, recorded bool) {
+	if err := jackets.SaveReportJacket(*report, true); err != nil {
+		logMessage(fmt.Sprintf("자켓 저장 실패: %v", err))
+	}
+	if !recorded {
+		return
+	}
+	if _, err := history.Confirm(play.ID); err != nil {
+		logMessage(fmt.Sprintf("플레이 기록 확인 실패: %v", err))
+	}
+}
+
+func writeDebugBundle(recorder *client.DebugRecorder) {
+	path := client.DebugBundlePath()
+	if err := recorder.WriteBundle(path); err != nil {
+		logMessage(fmt.Sprintf("Failed to write debug bundle: %v", err))
+		return
+	}
+	logMessage(fmt.Sprintf("디버그 파일 저장됨: %s", path))
+}
+
+// highlightConfidence marks the label of a field that was read with low confidence
+// or failed a consistency check.
+func highlightConfidence(label *widget.Label, report *client.AnalysisReport, field string) {
+	if report.IsLowConfidence(field) || report.HasWarning(field) {
+		label.Importance = widget.DangerImportance
+	} else {
+		label.Importance = widget.MediumImportance
+	}
+	label.Refresh()
+}
+
+// uploadReport confirms the play of the report and uploads it when it improves the personal best.
+// Records with low-confidence fields or failed consistency checks are only confirmed and sent after the user accepts them.
+func uploadReport(report *client.AnalysisReport, improvement client.Improvement, play client.PlayRecord, recorded bool) {
+	if !report.HasRecord() {
+		return
+	}
+	if len(report.DisabledChecks) > 0 {
+		logMessage(fmt.Sprintf("설정이 없어 건너뛴 검사: %s", strings.Join(report.DisabledChecks, ", ")))
+	}
+	lowFields := report.LowConfidenceFields()
+	clean := len(lowFields) == 0 && !report.HasWarnings()
+	if !improvement.NewRecord() {
+		// Not worth asking about; a play that needs checking stays out of the bests
+		if clean {
+			confirmPlay(report, play, recorded)
+		}
+		logMessage("최고 기록을 갱신하지 않아 업로드하지 않습니다")
+		return
+	}
+	if clean {
+		submitPlay(report, improvement, play, recorded)
+		return
+	}
+
+	var reasons []string
+	if len(lowFields) > 0 {
+		logMessage(fmt.Sprintf("인식 신뢰도가 낮은 항목: %s", strings.Join(lowFields, ", ")))
+		reasons = append(reasons, fmt.Sprintf("다음 항목의 인식 신뢰도가 낮습니다: %s", strings.Join(lowFields, ", ")))
+	}
+	for _, w := range report.Warnings {
+		logMessage(fmt.Sprintf("인식 값 불일치: %s", w.Message))
+		reasons = append(reasons, w.Message)
+	}
+	fyne.Do(func() {
+		dialog.ShowConfirm("인식 결과 확인",
+			fmt.Sprintf("%s\n그래도 기록하고 업로드할까요?", strings.Join(reasons, "\n")),
+			func(confirm bool) {
+				if !confirm {
+					logMessage("업로드를 취소했습니다")
+					return
+				}
+				go submitPlay(report, improvement, play, recorded)
+			}, mainWindow)
+	})
+}
+
+// submitPlay confirms an improving play and uploads it with its own values,
+// unless it is below the previous best in another value. Goals are checked once it counts.
+func submitPlay(report *client.AnalysisReport, improvement client.Improvement, play client.PlayRecord, recorded bool) {
+	confirmPlay(report, play, recorded)
+	archive, ok := improvement.UploadArchive(*report, decoderName)
+	if !ok {
+		logMessage("이전 최고 기록보다 낮은 값이 있어 업로드하지 않습니다 (동기화에서 합쳐서 올릴 수 있습니다)")
+	} else {
+		sendArchive(archive)
+	}
+	checkGoals()
+}
+
+func sendArchive(archive client.Archive) {
+	if b64APIKey == "" {
+		logMessage("로그인 후 업로드할 수 있습니다")
+		return
+	}
+	if _, err := client.UpdateArchive(b64APIKey, archive); err != nil {
+		logMessage(fmt.Sprintf("Upload failed: %v", err))
+		return
+	}
+	logMessage("기록 업로드 성공")
+	loadArchive()
+}